@@ -6,8 +6,13 @@ import (
 	"github.com/edemond/abstract/ast"
 	"github.com/edemond/abstract/chord"
 	"github.com/edemond/abstract/drivers"
+	"github.com/edemond/abstract/msg"
+	"github.com/edemond/abstract/parser"
 	"github.com/edemond/abstract/types"
+	"github.com/edemond/midi"
 	"fmt"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -19,6 +24,7 @@ type environment struct {
 	bindings      map[string]types.Value       // Name-value bindings for this scope.
 	parameterized map[string]ast.Parameterized // Exprs we can't fully evaluate yet because of formal parameters.
 	defPart       *types.SimplePart            // The default expression for this scope.
+	origins       map[string]string            // For imported names, the file they're defined in.
 }
 
 // Analyzer is the context object for semantic analysis of an Abstract program.
@@ -30,7 +36,10 @@ type Analyzer struct {
 	instruments map[string]*types.Instrument
 	bpm         int
 	ppq         int
-	spaces      int // Spaces to indent the trace.
+	spaces      int                     // Spaces to indent the trace.
+	errors      []error                 // Errors so far. After an error in a statement, analysis goes on to the next one.
+	files       []string                // The file being analyzed, then the file it's importing, and so on.
+	imports     map[string]*environment // What each file imported so far defines, by absolute path.
 }
 
 func (a *Analyzer) indent() {
@@ -46,6 +55,7 @@ func NewAnalyzer() *Analyzer {
 	return &Analyzer{
 		environments: make([]*environment, 0),
 		instruments:  make(map[string]*types.Instrument),
+		imports:      make(map[string]*environment),
 		bpm:          120,
 		ppq:          64,
 		spaces:       0,
@@ -57,7 +67,25 @@ func NewAnalyzer() *Analyzer {
 func (a *Analyzer) Analyze(stmt *ast.PlayStatement) (types.Part, error) {
 	a.pushScope()    // Root scope to kick things off.
 	a.bindBuiltIns() // Bind built-ins in the root scope.
-	return a.analyzePlay(stmt)
+	part, err := a.analyzePlay(stmt)
+	if err != nil {
+		a.fail(stmt.Line, err)
+	}
+	if len(a.errors) > 0 {
+		return nil, analysisErrors(a.errors)
+	}
+	return part, nil
+}
+
+// AnalyzeFile analyzes a piece parsed from the given file. Imports in it are found
+// relative to the file.
+func (a *Analyzer) AnalyzeFile(filename string, stmt *ast.PlayStatement) (types.Part, error) {
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	a.files = []string{path}
+	return a.Analyze(stmt)
 }
 
 // Create Abstract's built-in scales, dynamics, and more.
@@ -91,6 +119,23 @@ func (a *Analyzer) bindBuiltIns() {
 	a.bind("f", types.NewDynamics(96-1))
 	a.bind("ff", types.NewDynamics(112-1))
 	a.bind("fff", types.NewDynamics(128-1))
+
+	// Articulation, as the gate (percent of its length a note sounds for.)
+	staccato, _ := types.NewGate(50)
+	a.bind("staccato", staccato)
+	tenuto, _ := types.NewGate(100)
+	a.bind("tenuto", tenuto)
+	legato, _ := types.NewGate(110)
+	a.bind("legato", legato)
+
+	// Voicings, as shapes that work for chords of any size.
+	a.bind("close", types.CloseVoicing)
+	a.bind("open", types.OpenVoicing)
+	a.bind("drop2", types.Drop2Voicing)
+	a.bind("drop3", types.Drop3Voicing)
+	a.bind("shell", types.ShellVoicing)
+	a.bind("spread", types.SpreadVoicing)
+	a.bind("rootless", types.RootlessVoicing)
 }
 
 // OpenInstruments opens all the instruments, using the given driver, that we found in the program text.
@@ -99,8 +144,16 @@ func (a *Analyzer) OpenInstruments(driver drivers.Driver) ([]*types.Instrument,
 	a.trace("Opening instruments...")
 	a.indent()
 	defer a.unindent()
+	// Open them in name order so instrument IDs are the same from run to run.
+	names := make([]string, 0, len(a.instruments))
+	for name := range a.instruments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
 	insts := make([]*types.Instrument, 0)
-	for name, inst := range a.instruments {
+	for _, name := range names {
+		inst := a.instruments[name]
 		a.trace("Opening instrument '%v'...", name)
 		id, err := driver.OpenInstrument(name)
 		if err != nil {
@@ -137,7 +190,48 @@ func (a *Analyzer) trace(s string, args ...interface{}) {
 
 // Format an error with the current line number.
 func (a *Analyzer) errorf(line int, err string, args ...interface{}) error {
-	return fmt.Errorf("line %v: %v", line, fmt.Sprintf(err, args...))
+	return &analysisError{line: line, msg: fmt.Sprintf(err, args...)}
+}
+
+// fail records an error in the statement at the given line, so analysis can go on to the next one.
+// An error from a parameterized expression comes up once per call, but is only recorded once.
+func (a *Analyzer) fail(line int, err error) {
+	err = atLine(line, err)
+	for _, e := range a.errors {
+		if e.Error() == err.Error() {
+			return
+		}
+	}
+	a.errors = append(a.errors, err)
+}
+
+// An error in the piece, at a line of the source.
+type analysisError struct {
+	line int
+	msg  string
+}
+
+func (e *analysisError) Error() string {
+	return fmt.Sprintf("line %v: %v", e.line, e.msg)
+}
+
+// atLine puts the line in an error that doesn't have one yet.
+func atLine(line int, err error) error {
+	if _, ok := err.(*analysisError); ok {
+		return err
+	}
+	return &analysisError{line: line, msg: err.Error()}
+}
+
+// analysisErrors is every error found in a piece, one per line.
+type analysisErrors []error
+
+func (e analysisErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
 }
 
 // pushScope pushes a new scope onto the stack.
@@ -154,6 +248,7 @@ func (a *Analyzer) pushScope() {
 		bindings:      make(map[string]types.Value),
 		parameterized: make(map[string]ast.Parameterized),
 		defPart:       defPart,
+		origins:       make(map[string]string),
 	}
 	a.environments = append(a.environments, env)
 }
@@ -242,6 +337,140 @@ func (a *Analyzer) analyzeLet(stmt *ast.LetStatement) error {
 	return nil
 }
 
+// analyzeImport analyzes an import statement: it analyzes the lets (and defaults, and imports)
+// at the top level of the other file, and binds everything the file defines in the current scope.
+// The rest of the file (e.g. what it plays) is ignored.
+func (a *Analyzer) analyzeImport(stmt *ast.ImportStatement) error {
+	a.trace("import statement")
+	a.indent()
+	defer a.unindent()
+
+	path := stmt.Path
+	if !filepath.IsAbs(path) && len(a.files) > 0 {
+		path = filepath.Join(filepath.Dir(a.files[len(a.files)-1]), path)
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return a.errorf(stmt.Line, "can't import %q: %v", stmt.Path, err)
+	}
+	for i, f := range a.files {
+		if f == path {
+			cycle := []string{}
+			for _, c := range append(a.files[i:], path) {
+				cycle = append(cycle, filepath.Base(c))
+			}
+			return a.errorf(stmt.Line, "import cycle: %v", strings.Join(cycle, " -> "))
+		}
+	}
+
+	// A file is only analyzed the first time it's imported, so what it defines (e.g. an
+	// instrument) is the same wherever it's imported from.
+	imported, ok := a.imports[path]
+	if !ok {
+		imported, err = a.importFile(stmt, path)
+		if err != nil {
+			return err
+		}
+		a.imports[path] = imported
+	}
+
+	// Bind what the file defines here, in order so any error is always the same one.
+	names := []string{}
+	for name := range imported.bindings {
+		names = append(names, name)
+	}
+	for name := range imported.parameterized {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	env := a.currentEnv()
+	for _, name := range names {
+		origin, ok := imported.origins[name]
+		if !ok {
+			origin = path
+		}
+		if a.contains(name) {
+			if a.origin(name) == origin {
+				continue // The same definition, imported again by way of another file.
+			}
+			return a.errorf(stmt.Line, "'%v' from %v already defined", name, stmt.Path)
+		}
+		if p, ok := imported.parameterized[name]; ok {
+			a.addParameterized(name, p)
+		} else {
+			a.bind(name, imported.bindings[name])
+		}
+		env.origins[name] = origin
+	}
+	return nil
+}
+
+// importFile parses and analyzes a file being imported, and returns the environment with
+// everything it defines.
+func (a *Analyzer) importFile(stmt *ast.ImportStatement, path string) (*environment, error) {
+	p, err := parser.FromFile(path)
+	if err != nil {
+		return nil, a.errorf(stmt.Line, "can't import %q: %v", stmt.Path, err)
+	}
+	root, err := p.Parse()
+	if err != nil {
+		return nil, a.errorf(stmt.Line, "in %v: %v", stmt.Path, err)
+	}
+	block, ok := root.Expr.(*ast.BlockExpr)
+	if !ok {
+		panic("Internal error: the root of a file isn't a block expression")
+	}
+
+	// The file is analyzed on its own, seeing only the built-ins, like any other piece. Its errors
+	// are collected on their own so we can say which file they're in.
+	a.files = append(a.files, path)
+	environments := a.environments
+	a.environments = make([]*environment, 0)
+	a.pushScope()
+	a.bindBuiltIns()
+	a.pushScope()
+	errs := a.errors
+	a.errors = nil
+	for _, st := range block.Statements {
+		switch s := st.(type) {
+		case *ast.LetStatement:
+			if err := a.analyzeLet(s); err != nil {
+				a.fail(s.Line, err)
+			}
+		case *ast.DefaultStatement:
+			if err := a.analyzeDefault(s); err != nil {
+				a.fail(s.Line, err)
+			}
+		case *ast.ImportStatement:
+			if err := a.analyzeImport(s); err != nil {
+				a.fail(s.Line, err)
+			}
+		}
+	}
+	imported := a.currentEnv()
+	fileErrs := a.errors
+	a.errors = errs
+	a.environments = environments
+	a.files = a.files[:len(a.files)-1]
+	if len(fileErrs) > 0 {
+		return nil, a.errorf(stmt.Line, "in %v: %v", stmt.Path, analysisErrors(fileErrs))
+	}
+	return imported, nil
+}
+
+// origin gets the file an imported name is defined in, or "" if it wasn't imported.
+func (a *Analyzer) origin(name string) string {
+	for i := a.depth() - 1; i >= 0; i-- {
+		env := a.environments[i]
+		_, bound := env.bindings[name]
+		_, param := env.parameterized[name]
+		if bound || param {
+			return env.origins[name]
+		}
+	}
+	return ""
+}
+
 // analyzeDefault analyzes a default statement, updating the default part in the current environment.
 func (a *Analyzer) analyzeDefault(stmt *ast.DefaultStatement) error {
 	a.trace("default statement")
@@ -257,12 +486,21 @@ func (a *Analyzer) analyzeDefault(stmt *ast.DefaultStatement) error {
 	}
 
 	env := a.currentEnv()
+	if part.Rhythm.Accent.HasValue() {
+		env.defPart.Rhythm.Accent = part.Rhythm.Accent
+	}
+	if part.Harmony.Arp.HasValue() {
+		env.defPart.Harmony.Arp = part.Harmony.Arp
+	}
 	if part.Harmony.Chord.HasValue() {
 		env.defPart.Harmony.Chord = part.Harmony.Chord
 	}
 	if part.Rhythm.Dynamics.HasValue() {
 		env.defPart.Rhythm.Dynamics = part.Rhythm.Dynamics
 	}
+	if part.Rhythm.Gate.HasValue() {
+		env.defPart.Rhythm.Gate = part.Rhythm.Gate
+	}
 	if part.Rhythm.Humanize.HasValue() {
 		env.defPart.Rhythm.Humanize = part.Rhythm.Humanize
 	}
@@ -275,6 +513,12 @@ func (a *Analyzer) analyzeDefault(stmt *ast.DefaultStatement) error {
 	if part.Rhythm.Meter.HasValue() {
 		env.defPart.Rhythm.Meter = part.Rhythm.Meter
 	}
+	if part.Priority.HasValue() {
+		env.defPart.Priority = part.Priority
+	}
+	if part.Rhythm.Swing.HasValue() {
+		env.defPart.Rhythm.Swing = part.Rhythm.Swing
+	}
 	if part.Harmony.Octave.HasValue() {
 		env.defPart.Harmony.Octave = part.Harmony.Octave
 	}
@@ -316,8 +560,13 @@ func (a *Analyzer) analyzePlay(stmt *ast.PlayStatement) (types.Part, error) {
 		return v, nil
 	case *types.Seq: // TODO: Rename to SeqPart
 		return v, nil
+	case *types.TempoPart:
+		return v, nil
 	case types.MessagePart:
 		return v, nil
+	case types.MIDIMessage:
+		// A loose message is sent on its own, without sounding a note.
+		return a.messagePart([]midi.Message{v.Message()}, a.currentEnv().defPart.Instrument), nil
 	default:
 		// If an expr is just a loose value of any other kind, pack it into a SimplePart.
 		simple := types.NewSimplePart()
@@ -356,6 +605,12 @@ func (a *Analyzer) analyzeRestLiteral(name string) (types.Rest, error) {
 	return types.NewRest(name), nil
 }
 
+// Analyze a tie literal expression, -.
+func (a *Analyzer) analyzeTieLiteral(name string) (types.Tie, error) {
+	a.trace("tie literal expression.")
+	return types.NewTie(), nil
+}
+
 // analyzeIdentExpr looks up an identifier in the environment and returns
 // its corresponding value, or an error if the identifier is not bound.
 // Handles built-in identifiers like octave and pitch literals.
@@ -394,6 +649,15 @@ func (a *Analyzer) analyzeIdentExpr(expr ast.IdentExpr) (types.Value, error) {
 		return rest, nil
 	}
 
+	if types.IsTie(name) {
+		tie, err := a.analyzeTieLiteral(name)
+		if err != nil {
+			return nil, err
+		}
+		a.trace("'%v' evaluates to tie %v", expr, tie)
+		return tie, nil
+	}
+
 	// Look up the value in the environment.
 	for i := a.depth() - 1; i >= 0; i-- {
 		env := a.environments[i]
@@ -413,7 +677,6 @@ func (a *Analyzer) analyzeIdentExpr(expr ast.IdentExpr) (types.Value, error) {
 	if err == nil {
 		return chord, nil
 	}
-	fmt.Println(err)
 	// TODO: Wait, what the hell to do with this error? They might not have been trying to write a chord.
 
 	return nil, fmt.Errorf("'%v' not defined", name)
@@ -496,32 +759,166 @@ func (a *Analyzer) analyzeProb(expr *ast.ParamExpr) (*types.Prob, error) {
 	return prob, nil
 }
 
-// TODO: This could return a seq instead of a Velocity. Or do we not need it
-// because seqs implicitly run Bjorklund?!
-/*
-// analyzeBjork (heh) analyzes a Bjorklund-based velocity expression and returns a ("Euclidean") Velocity.
-func (a *Analyzer) analyzeBjork(expr *ast.ParamExpr) (*types.Velocity, error) {
+// analyzeBjork (heh) analyzes a Bjorklund-based rhythm expression and returns a ("Euclidean") Bjork.
+func (a *Analyzer) analyzeBjork(expr *ast.ParamExpr) (*types.Bjork, error) {
 	a.trace("Bjorklund expression.")
+	a.indent()
+	defer a.unindent()
 	assertName(expr.Name, "bjork")
-	if len(expr.Params) != 2 {
-		return nil, a.errorf(expr.Line, "bjork requires bjork(number pulses, number steps)")
+	if len(expr.Params) != 2 && len(expr.Params) != 3 {
+		return nil, a.errorf(expr.Line, "bjork requires bjork(pulses, steps) or bjork(pulses, steps, rotation)")
 	}
 
-	steps, err := a.analyzeNumberOrIdent(expr.Params[0])
+	pulses, err := a.analyzeNumberOrIdent(expr.Params[0])
+	if err != nil {
+		return nil, err
+	}
+	steps, err := a.analyzeNumberOrIdent(expr.Params[1])
 	if err != nil {
 		return nil, err
 	}
-	pulses, err := a.analyzeNumberOrIdent(expr.Params[1])
+	rotation := 0
+	if len(expr.Params) == 3 {
+		r, err := a.analyzeNumberOrIdent(expr.Params[2])
+		if err != nil {
+			return nil, err
+		}
+		rotation = int(r.Value)
+	}
+
+	if steps.Value == 0 {
+		return nil, a.errorf(expr.Line, "bjork needs at least 1 step (got %v)", steps.Value)
+	}
+	if pulses.Value > steps.Value {
+		return nil, a.errorf(expr.Line, "bjork can't have more pulses than steps (got %v pulses, %v steps)", pulses.Value, steps.Value)
+	}
+
+	return types.NewBjork(int(pulses.Value), int(steps.Value), rotation), nil
+}
+
+// analyzeArp analyzes an arpeggio: arp(pattern, rate, octaves) with a named pattern, e.g.
+// arp(updown, 16, 2), a string of chord indexes, e.g. arp("1 3 2 4", 8), or just the indexes,
+// e.g. arp(1, 3, 2, 4). The rate defaults to 16 notes to a bar and the octaves to 1.
+func (a *Analyzer) analyzeArp(expr *ast.ParamExpr) (*types.Arp, error) {
+	a.trace("arpeggio expression.")
+	a.indent()
+	defer a.unindent()
+	assertName(expr.Name, "arp")
+	if len(expr.Params) == 0 {
+		return nil, a.errorf(expr.Line, "arp requires arp(pattern), arp(pattern, rate) or arp(pattern, rate, octaves)")
+	}
+
+	// The given parameters, which have to be numbers.
+	numbers := func(params []ast.Expression) ([]int, error) {
+		nums := make([]int, len(params))
+		for i, p := range params {
+			n, err := a.analyzeNumberOrIdent(p)
+			if err != nil {
+				return nil, err
+			}
+			nums[i] = int(n.Value)
+		}
+		return nums, nil
+	}
+
+	switch e := expr.Params[0].(type) {
+	case ast.IdentExpr:
+		pattern, err := types.LookUpArpPattern(string(e))
+		if err != nil {
+			// It could be a let that's a number, as the first index of a custom pattern.
+			if _, numErr := a.analyzeNumberOrIdent(e); numErr != nil {
+				return nil, a.errorf(expr.Line, "%v", err)
+			}
+			break
+		}
+		if len(expr.Params) > 3 {
+			return nil, a.errorf(expr.Line, "arp requires arp(pattern), arp(pattern, rate) or arp(pattern, rate, octaves)")
+		}
+		args, err := numbers(expr.Params[1:])
+		if err != nil {
+			return nil, err
+		}
+		rate, octaves := 16, 1
+		if len(args) > 0 {
+			rate = args[0]
+		}
+		if len(args) > 1 {
+			octaves = args[1]
+		}
+		arp, err := types.NewArp(pattern, rate, octaves)
+		if err != nil {
+			return nil, a.errorf(expr.Line, "%v", err)
+		}
+		return arp, nil
+	case ast.StringExpr:
+		if len(expr.Params) > 2 {
+			return nil, a.errorf(expr.Line, "arp with a string of indexes requires arp(\"1 2 3\") or arp(\"1 2 3\", rate)")
+		}
+		var indexes []int
+		for _, field := range strings.Fields(string(e)) {
+			i, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, a.errorf(expr.Line, "arp indexes must be numbers (got %v)", field)
+			}
+			indexes = append(indexes, i)
+		}
+		rate := 16
+		if len(expr.Params) == 2 {
+			args, err := numbers(expr.Params[1:])
+			if err != nil {
+				return nil, err
+			}
+			rate = args[0]
+		}
+		arp, err := types.NewCustomArp(indexes, rate)
+		if err != nil {
+			return nil, a.errorf(expr.Line, "%v", err)
+		}
+		return arp, nil
+	}
+
+	indexes, err := numbers(expr.Params)
 	if err != nil {
 		return nil, err
 	}
+	arp, err := types.NewCustomArp(indexes, 16)
+	if err != nil {
+		return nil, a.errorf(expr.Line, "%v", err)
+	}
+	return arp, nil
+}
+
+// analyzeMelodyExpr analyzes a melody literal (e.g. mel"1 3 5 _") and returns a Melody.
+func (a *Analyzer) analyzeMelodyExpr(expr *ast.MelodyExpr) (*types.Melody, error) {
+	a.trace("melody expression.")
+	a.indent()
+	defer a.unindent()
+	mel, err := types.NewMelody(expr.Notes)
+	if err != nil {
+		return nil, a.errorf(expr.Line, "%v", err)
+	}
+	return mel, nil
+}
 
-	pattern := machine.Bjorklund(int(steps.Value), int(pulses.Value))
-	r := types.NewVelocity()
-	r.AddPartFromInts(pattern)
-	return r, nil
+// analyzeRhythm analyzes a bit pattern rhythm expression (e.g. rhythm(0x8080, 0x88)) and returns Bits.
+func (a *Analyzer) analyzeRhythm(expr *ast.ParamExpr) (*types.Bits, error) {
+	a.trace("rhythm expression.")
+	a.indent()
+	defer a.unindent()
+	assertName(expr.Name, "rhythm")
+	if len(expr.Params) < 1 {
+		return nil, a.errorf(expr.Line, "rhythm requires rhythm(bits, ...), e.g. rhythm(0x8080)")
+	}
+	bits := types.NewBits()
+	for _, param := range expr.Params {
+		n, err := a.analyzeNumberOrIdent(param)
+		if err != nil {
+			return nil, err
+		}
+		bits.AddBar(n.Value, n.Digits)
+	}
+	return bits, nil
 }
-*/
 
 // analyzeScale analyzes a scale expression and returns a Scale.
 func (a *Analyzer) analyzeScale(expr *ast.ParamExpr) (*types.Scale, error) {
@@ -652,14 +1049,29 @@ func (a *Analyzer) analyzePitch(expr *ast.ParamExpr) (types.Pitch, error) {
 	return types.NewPitch(pitch.Value), nil
 }
 
-// analyzePitch analyzes a voicing expression and returns a Voicing.
+// analyzeVoicing analyzes a voicing expression and returns a Voicing: a bitmap, e.g.
+// voicing(0x0705), a named voicing, e.g. voicing(drop2), or voicing(smooth) with an optional
+// range, e.g. voicing(smooth, range(C3, C5)).
 func (a *Analyzer) analyzeVoicing(expr *ast.ParamExpr) (types.Voicing, error) {
 	a.trace("voicing expression.")
 	a.indent()
 	defer a.unindent()
 	assertName(expr.Name, "voicing")
+	if len(expr.Params) < 1 || len(expr.Params) > 2 {
+		return types.NoVoicing(), a.errorf(expr.Line, "voicing requires voicing(number) or voicing(smooth, range(low, high))")
+	}
+	if ident, ok := expr.Params[0].(ast.IdentExpr); ok && ident == "smooth" {
+		return a.analyzeSmoothVoicing(expr)
+	}
 	if len(expr.Params) != 1 {
-		return types.NoVoicing(), a.errorf(expr.Line, "voicing requires voicing(number)")
+		return types.NoVoicing(), a.errorf(expr.Line, "voicing requires voicing(number) or voicing(smooth, range(low, high))")
+	}
+	if ident, ok := expr.Params[0].(ast.IdentExpr); ok {
+		if value, err := a.analyzeIdentExpr(ident); err == nil {
+			if voicing, ok := value.(types.Voicing); ok {
+				return voicing, nil
+			}
+		}
 	}
 	voicing, err := a.analyzeNumberOrIdent(expr.Params[0])
 	if err != nil {
@@ -668,6 +1080,47 @@ func (a *Analyzer) analyzeVoicing(expr *ast.ParamExpr) (types.Voicing, error) {
 	return types.NewVoicing(voicing.Value), nil
 }
 
+// analyzeSmoothVoicing analyzes voicing(smooth) or voicing(smooth, range(low, high)). Without
+// a range, it voices chords in the two octaves from the default octave up.
+func (a *Analyzer) analyzeSmoothVoicing(expr *ast.ParamExpr) (types.Voicing, error) {
+	low := types.Pitch(0).At(types.DefaultOctave())
+	high := low + 24
+	if len(expr.Params) == 2 {
+		rng, ok := expr.Params[1].(*ast.ParamExpr)
+		if !ok || rng.Name != "range" || len(rng.Params) != 2 {
+			return types.NoVoicing(), a.errorf(expr.Line, "smooth voicing requires voicing(smooth, range(low, high))")
+		}
+		var err error
+		if low, err = a.analyzeNoteName(rng.Params[0]); err != nil {
+			return types.NoVoicing(), a.errorf(expr.Line, "%v", err)
+		}
+		if high, err = a.analyzeNoteName(rng.Params[1]); err != nil {
+			return types.NoVoicing(), a.errorf(expr.Line, "%v", err)
+		}
+	}
+	voicing, err := types.NewSmoothVoicing(low, high)
+	if err != nil {
+		return types.NoVoicing(), a.errorf(expr.Line, "%v", err)
+	}
+	return voicing, nil
+}
+
+// analyzeNoteName analyzes a note given by name, e.g. C3, or by number. Names are read as they
+// are, not looked up, since C5 would otherwise be a power chord.
+func (a *Analyzer) analyzeNoteName(expr ast.Expression) (types.Note, error) {
+	switch e := expr.(type) {
+	case ast.IdentExpr:
+		return types.LookUpNote(string(e))
+	case *ast.NumberExpr:
+		return types.NewNote(e.Value)
+	case *ast.ParamExpr:
+		if e.Name == "note" {
+			return a.analyzeNote(e)
+		}
+	}
+	return types.NoNote(), fmt.Errorf("expected a note, like C3")
+}
+
 // analyzeNumberExpr analyzes a numeric parameter in the context of an expression like arp(32).
 func (a *Analyzer) analyzeNumberExpr(p ast.Expression) (*types.Number, error) {
 	number, ok := p.(*ast.NumberExpr)
@@ -693,8 +1146,8 @@ func (a *Analyzer) analyzeInstrument(expr *ast.ParamExpr) (*types.Instrument, er
 	a.indent()
 	defer a.unindent()
 	assertName(expr.Name, "instrument")
-	if len(expr.Params) != 3 {
-		return nil, a.errorf(expr.Line, "instrument requires instrument(instrument name, channel, voices)")
+	if len(expr.Params) != 3 && len(expr.Params) != 4 {
+		return nil, a.errorf(expr.Line, "instrument requires instrument(instrument name, channel, voices) or instrument(instrument name, channel, voices, stealing)")
 	}
 	deviceName, err := a.analyzeStringOrIdent(expr.Params[0])
 	if err != nil {
@@ -713,16 +1166,36 @@ func (a *Analyzer) analyzeInstrument(expr *ast.ParamExpr) (*types.Instrument, er
 	if err != nil {
 		return nil, err
 	}
+	steal := msg.StealOldest
+	if len(expr.Params) == 4 {
+		steal, err = a.analyzeSteal(expr.Params[3])
+		if err != nil {
+			return nil, a.errorf(expr.Line, "%v", err)
+		}
+	}
 	// Collect the instrument definition here.
 	_, ok := a.instruments[string(deviceName)] // TODO: string conversion hack
 	if ok {
 		return nil, a.errorf(expr.Line, "Instrument '%v' already created", deviceName) // TODO: Where?
 	}
-	inst := types.NewInstrument(string(deviceName), byte(channel.Value), int(voices.Value)) // TODO: string conversion hack
-	a.instruments[string(deviceName)] = inst                                                // TODO: string conversion hack
+	inst := types.NewInstrument(string(deviceName), byte(channel.Value), int(voices.Value), steal) // TODO: string conversion hack
+	a.instruments[string(deviceName)] = inst                                                       // TODO: string conversion hack
 	return inst, nil
 }
 
+// analyzeSteal analyzes which note an instrument cuts off when all of its voices are in use,
+// given by name, e.g. quietest or "quietest".
+func (a *Analyzer) analyzeSteal(expr ast.Expression) (msg.Steal, error) {
+	switch e := expr.(type) {
+	case ast.IdentExpr:
+		return msg.LookUpSteal(string(e))
+	case ast.StringExpr:
+		return msg.LookUpSteal(string(e))
+	default:
+		return msg.StealOldest, fmt.Errorf("voice stealing must be oldest, quietest, lowest or none")
+	}
+}
+
 // analyzeNote analyzes a note expression and returns a Note.
 func (a *Analyzer) analyzeNote(expr *ast.ParamExpr) (types.Note, error) {
 	a.trace("note.")
@@ -750,7 +1223,7 @@ func (a *Analyzer) analyzeHumanize(expr *ast.ParamExpr) (*types.Humanize, error)
 	defer a.unindent()
 	assertName(expr.Name, "human")
 	if len(expr.Params) != 1 {
-		return nil, a.errorf(expr.Line, "humanize requires humanize(time)")
+		return nil, a.errorf(expr.Line, "humanize requires human(ms)")
 	}
 	num, err := a.analyzeNumberOrIdent(expr.Params[0])
 	if err != nil {
@@ -763,8 +1236,122 @@ func (a *Analyzer) analyzeHumanize(expr *ast.ParamExpr) (*types.Humanize, error)
 	return humanize, nil
 }
 
+// analyzeAccent analyzes an accent(amount, ...) expression and returns an Accent.
+func (a *Analyzer) analyzeAccent(expr *ast.ParamExpr) (*types.Accent, error) {
+	a.trace("accent.")
+	a.indent()
+	defer a.unindent()
+	assertName(expr.Name, "accent")
+	if len(expr.Params) < 1 {
+		return nil, a.errorf(expr.Line, "accent requires accent(amount) or accent(downbeat, half, beat, ...)")
+	}
+	amounts := make([]int, len(expr.Params))
+	for i, param := range expr.Params {
+		num, err := a.analyzeNumberOrIdent(param)
+		if err != nil {
+			return types.NoAccent(), err
+		}
+		amounts[i] = int(num.Value)
+	}
+	accent, err := types.NewAccent(amounts...)
+	if err != nil {
+		return types.NoAccent(), a.errorf(expr.Line, "%v", err)
+	}
+	return accent, nil
+}
+
+// analyzeSwing analyzes a swing(percent) or swing(percent, level) expression and returns a Swing.
+func (a *Analyzer) analyzeSwing(expr *ast.ParamExpr) (*types.Swing, error) {
+	a.trace("swing.")
+	a.indent()
+	defer a.unindent()
+	assertName(expr.Name, "swing")
+	ln := len(expr.Params)
+	if ln != 1 && ln != 2 {
+		return nil, a.errorf(expr.Line, "swing requires swing(percent) or swing(percent, level)")
+	}
+	percent, err := a.analyzeNumberOrIdent(expr.Params[0])
+	if err != nil {
+		return types.NoSwing(), err
+	}
+	level := uint64(8)
+	if ln == 2 {
+		num, err := a.analyzeNumberOrIdent(expr.Params[1])
+		if err != nil {
+			return types.NoSwing(), err
+		}
+		level = num.Value
+	}
+	swing, err := types.NewSwing(int(percent.Value), int(level))
+	if err != nil {
+		return types.NoSwing(), a.errorf(expr.Line, "%v", err)
+	}
+	return swing, nil
+}
+
+// analyzeTempo analyzes an accel(from, to) or rit(from, to) expression, and returns a part
+// that ramps the tempo over the part after it.
+func (a *Analyzer) analyzeTempo(expr *ast.ParamExpr) (*types.TempoPart, error) {
+	a.trace("%v.", expr.Name)
+	a.indent()
+	defer a.unindent()
+	if len(expr.Params) != 2 {
+		return nil, a.errorf(expr.Line, "%v requires %v(from, to)", expr.Name, expr.Name)
+	}
+	from, err := a.analyzeNumberOrIdent(expr.Params[0])
+	if err != nil {
+		return nil, err
+	}
+	to, err := a.analyzeNumberOrIdent(expr.Params[1])
+	if err != nil {
+		return nil, err
+	}
+	tempo, err := types.NewTempoPart(expr.Name, int(from.Value), int(to.Value))
+	if err != nil {
+		return nil, a.errorf(expr.Line, "%v", err)
+	}
+	return tempo, nil
+}
+
+// analyzeGate analyzes a gate(percent) expression and returns a Gate.
+func (a *Analyzer) analyzeGate(expr *ast.ParamExpr) (*types.Gate, error) {
+	a.trace("gate.")
+	a.indent()
+	defer a.unindent()
+	assertName(expr.Name, "gate")
+	if len(expr.Params) != 1 {
+		return nil, a.errorf(expr.Line, "gate requires gate(percent)")
+	}
+	num, err := a.analyzeNumberOrIdent(expr.Params[0])
+	if err != nil {
+		return types.NoGate(), err
+	}
+	gate, err := types.NewGate(int(num.Value))
+	if err != nil {
+		return types.NoGate(), a.errorf(expr.Line, "%v", err)
+	}
+	return gate, nil
+}
+
+// analyzePriority analyzes a priority(level) expression and returns a Priority.
+func (a *Analyzer) analyzePriority(expr *ast.ParamExpr) (*types.Priority, error) {
+	a.trace("priority.")
+	a.indent()
+	defer a.unindent()
+	assertName(expr.Name, "priority")
+	if len(expr.Params) != 1 {
+		return nil, a.errorf(expr.Line, "priority requires priority(level)")
+	}
+	num, err := a.analyzeNumberOrIdent(expr.Params[0])
+	if err != nil {
+		return types.NoPriority(), err
+	}
+	return types.NewPriority(int(num.Value)), nil
+}
+
 // analyzeSeqExpr analyzes a sequence expression (like [a b c]) and returns a Seq.
-func (a *Analyzer) analyzeSeqExpr(expr *ast.SeqExpr) (*types.Seq, error) {
+// The parent is the part the sequence was found in, which it takes its length from.
+func (a *Analyzer) analyzeSeqExpr(expr *ast.SeqExpr, parent types.Part) (*types.Seq, error) {
 	a.trace("sequence expression.")
 	a.indent()
 	defer a.unindent()
@@ -772,6 +1359,7 @@ func (a *Analyzer) analyzeSeqExpr(expr *ast.SeqExpr) (*types.Seq, error) {
 	part.SetParent(parent)
 
 	parts := []types.Part{}
+	tieFrom := -1 // Index of the simple part a tie would hold, if there is one.
 	for _, e := range expr.ValueExprs {
 
 		var val types.Value
@@ -786,8 +1374,11 @@ func (a *Analyzer) analyzeSeqExpr(expr *ast.SeqExpr) (*types.Seq, error) {
 			val, err = a.analyzeParamExpr(ex)
 		case *ast.MeterExpr:
 			val, err = a.analyzeMeterExpr(ex)
+		case *ast.MelodyExpr:
+			val, err = a.analyzeMelodyExpr(ex)
 		case *ast.SeqExpr:
-			val, err = a.analyzeSeqExpr(ex)
+			// A nested seq has the same parent; it gets scaled down to its slot below.
+			val, err = a.analyzeSeqExpr(ex, parent)
 		case ast.StringExpr:
 			return nil, fmt.Errorf("loose string in sequence")
 		case *ast.NumberExpr:
@@ -800,26 +1391,58 @@ func (a *Analyzer) analyzeSeqExpr(expr *ast.SeqExpr) (*types.Seq, error) {
 			return nil, err
 		}
 
+		if _, ok := val.(types.Tie); !ok {
+			tieFrom = -1
+		}
+
 		switch v := val.(type) {
 		// Simple, compound, and message parts go right in.
 		case *types.SimplePart:
 			v.SetScale(len(expr.ValueExprs))
+			tieFrom = len(parts)
 			parts = append(parts, v)
 		case *types.CompoundPart:
 			v.SetScale(len(expr.ValueExprs))
 			parts = append(parts, v)
+		case *types.Seq:
+			v.SetScale(len(expr.ValueExprs))
+			parts = append(parts, v)
 		case *types.BlockPart:
 			return nil, fmt.Errorf("Sequences may not contain block parts.")
+		case *types.TempoPart:
+			return nil, fmt.Errorf("tempo changes go in blocks, not sequences (got %v)", v)
 		case types.MessagePart:
 			parts = append(parts, v) // TODO: Can this even happen syntactically?
+		case types.MIDIMessage:
+			// A loose message takes up its slot, and is sent when the slot starts.
+			parts = append(parts, a.messagePart([]midi.Message{v.Message()}, a.currentEnv().defPart.Instrument))
+		case types.Tie:
+			// Hold the notes of the part being tied through this slot, which is otherwise a rest.
+			if tieFrom < 0 {
+				return nil, fmt.Errorf("a tie (-) in a sequence has to follow a note, chord, or another tie")
+			}
+			// Copy the tied part, in case it's bound to a name and used elsewhere.
+			tied := parts[tieFrom].(*types.SimplePart).Copy()
+			tied.Ties += 1
+			parts[tieFrom] = tied
+
+			rest := types.NewSimplePart()
+			a.assign(rest, types.NewRest("_"))
+			a.fillOutDefaults(rest)
+			rest.SetScale(len(expr.ValueExprs))
+			parts = append(parts, rest)
 		default:
 			// Everything else gets upgraded to a simple part, inheriting its properties
 			// first from the parent simple part in which it was found, then any other
 			// properties from the default part.
 			simple := types.NewSimplePart()
 			a.assign(simple, v)
+			if p, ok := parent.(*types.SimplePart); ok {
+				fillOutFrom(simple, p)
+			}
 			a.fillOutDefaults(simple)
 			simple.SetScale(len(expr.ValueExprs))
+			tieFrom = len(parts)
 			parts = append(parts, simple)
 		}
 	}
@@ -836,16 +1459,50 @@ func (a *Analyzer) analyzeCall(expr *ast.ParamExpr, p ast.Parameterized) (types.
 	defer a.unindent()
 
 	params := p.Parameters()
-	args := expr.Params
-
-	// We should have arguments supplied for all the formal parameters.
-	if len(args) != len(params) {
-		return nil, a.errorf(expr.Line, "wrong number of arguments to '%v' (got %v, expected %v)", expr.Name, len(args), len(params))
+	defaults := p.Defaults()
+
+	// Match up the arguments with the formal parameters: positional ones first, in order,
+	// then named ones.
+	args := make([]ast.Expression, len(params))
+	named := false
+	for i, arg := range expr.Params {
+		n, ok := arg.(*ast.NamedArgExpr)
+		if !ok {
+			if named {
+				return nil, a.errorf(expr.Line, "positional argument after named argument in call to '%v'", expr.Name)
+			}
+			if i >= len(params) {
+				return nil, a.errorf(expr.Line, "wrong number of arguments to '%v' (got %v, expected %v)", expr.Name, len(expr.Params), len(params))
+			}
+			args[i] = arg
+			continue
+		}
+		named = true
+		found := false
+		for j, param := range params {
+			if string(param) == n.Name {
+				if args[j] != nil {
+					return nil, a.errorf(n.Line, "argument '%v' to '%v' given more than once", n.Name, expr.Name)
+				}
+				args[j] = n.Expr
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, a.errorf(n.Line, "'%v' has no parameter '%v'", expr.Name, n.Name)
+		}
 	}
 
-	// Then, analyze each argument...
+	// Then, analyze each argument here, in the caller's scope...
 	bindings := make(map[ast.IdentExpr]types.Value)
 	for i := 0; i < len(params); i++ {
+		if args[i] == nil {
+			if defaults[i] == nil {
+				return nil, a.errorf(expr.Line, "missing argument '%v' to '%v'", params[i], expr.Name)
+			}
+			continue
+		}
 		val, err := a.analyzeExpr(args[i])
 		if err != nil {
 			return nil, err
@@ -860,6 +1517,19 @@ func (a *Analyzer) analyzeCall(expr *ast.ParamExpr, p ast.Parameterized) (types.
 		a.trace("Binding formal parameter '%v' to '%v'.", string(name), value)
 		a.bind(string(name), value)
 	}
+
+	// Defaults are evaluated in the new scope, in order, so they can refer to the parameters before them.
+	for i := 0; i < len(params); i++ {
+		if args[i] != nil {
+			continue
+		}
+		val, err := a.analyzeExpr(defaults[i])
+		if err != nil {
+			return nil, err
+		}
+		a.trace("Binding formal parameter '%v' to default '%v'.", string(params[i]), val)
+		a.bind(string(params[i]), val)
+	}
 	return a.analyzeExpr(p)
 }
 
@@ -881,15 +1551,29 @@ func (a *Analyzer) analyzeParamExpr(expr *ast.ParamExpr) (types.Value, error) {
 		}
 	}
 
-	// Otherwise, it's a built-in.
+	// Otherwise, it's a built-in. Those only take their arguments in order.
+	for _, arg := range expr.Params {
+		if n, ok := arg.(*ast.NamedArgExpr); ok {
+			return nil, a.errorf(n.Line, "named argument '%v' can only be given to a let with parameters", n.Name)
+		}
+	}
 	switch expr.Name {
+	case "accel", "rit":
+		return a.analyzeTempo(expr)
+	case "accent":
+		return a.analyzeAccent(expr)
+	case "arp":
+		return a.analyzeArp(expr)
+	case "bjork":
+		return a.analyzeBjork(expr)
 	case "chord":
 		return a.analyzeChord(expr)
 	case "cc":
-		panic("cc not implemented yet")
-		//return a.analyzeCC(expr)
+		return a.analyzeCC(expr)
 	case "dynamics":
 		return a.analyzeDynamics(expr)
+	case "gate":
+		return a.analyzeGate(expr)
 	case "human":
 		return a.analyzeHumanize(expr)
 	case "instrument":
@@ -899,14 +1583,19 @@ func (a *Analyzer) analyzeParamExpr(expr *ast.ParamExpr) (types.Value, error) {
 	case "note":
 		return a.analyzeNote(expr)
 	case "pc":
-		panic("pc not implemented yet")
-		//return a.analyzePC(expr)
+		return a.analyzePC(expr)
 	case "pitch":
 		return a.analyzePitch(expr)
+	case "priority":
+		return a.analyzePriority(expr)
 	case "prob":
 		return a.analyzeProb(expr)
+	case "rhythm":
+		return a.analyzeRhythm(expr)
 	case "scale":
 		return a.analyzeScale(expr)
+	case "swing":
+		return a.analyzeSwing(expr)
 	case "voicing":
 		return a.analyzeVoicing(expr)
 	default:
@@ -923,7 +1612,15 @@ func (a *Analyzer) assignAllFrom(to, from *types.SimplePart) error {
 	}
 
 	msg := "cannot combine simple parts: %v"
-	err := a.assign(to, from.Harmony.Chord)
+	err := a.assign(to, from.Rhythm.Accent)
+	if err != nil {
+		return fmt.Errorf(msg, err)
+	}
+	err = a.assign(to, from.Harmony.Arp)
+	if err != nil {
+		return fmt.Errorf(msg, err)
+	}
+	err = a.assign(to, from.Harmony.Chord)
 	if err != nil {
 		return fmt.Errorf(msg, err)
 	}
@@ -931,6 +1628,10 @@ func (a *Analyzer) assignAllFrom(to, from *types.SimplePart) error {
 	if err != nil {
 		return fmt.Errorf(msg, err)
 	}
+	err = a.assign(to, from.Rhythm.Gate)
+	if err != nil {
+		return fmt.Errorf(msg, err)
+	}
 	err = a.assign(to, from.Rhythm.Humanize)
 	if err != nil {
 		return fmt.Errorf(msg, err)
@@ -947,6 +1648,14 @@ func (a *Analyzer) assignAllFrom(to, from *types.SimplePart) error {
 	if err != nil {
 		return fmt.Errorf(msg, err)
 	}
+	err = a.assign(to, from.Priority)
+	if err != nil {
+		return fmt.Errorf(msg, err)
+	}
+	err = a.assign(to, from.Rhythm.Swing)
+	if err != nil {
+		return fmt.Errorf(msg, err)
+	}
 	err = a.assign(to, from.Harmony.Octave)
 	if err != nil {
 		return fmt.Errorf(msg, err)
@@ -963,6 +1672,7 @@ func (a *Analyzer) assignAllFrom(to, from *types.SimplePart) error {
 	if err != nil {
 		return fmt.Errorf(msg, err)
 	}
+	to.Messages = append(to.Messages, from.Messages...)
 	return nil
 }
 
@@ -974,6 +1684,26 @@ func (a *Analyzer) assign(part *types.SimplePart, value types.Value) error {
 	}
 	// Switch on type of value and figure out where we can put it in the part.
 	switch v := value.(type) {
+	case *types.Arp:
+		if part.Harmony.Arp.HasValue() {
+			return fmt.Errorf("part already has arp %v", part.Harmony.Arp)
+		}
+		part.Harmony.Arp = v
+	case *types.Bjork:
+		if part.Interpretation.HasValue() {
+			return fmt.Errorf("part already has interpretation %v", part.Interpretation)
+		}
+		part.Interpretation = v
+	case *types.Melody:
+		if part.Interpretation.HasValue() {
+			return fmt.Errorf("part already has interpretation %v", part.Interpretation)
+		}
+		part.Interpretation = v
+	case *types.Bits:
+		if part.Interpretation.HasValue() {
+			return fmt.Errorf("part already has interpretation %v", part.Interpretation)
+		}
+		part.Interpretation = v
 	case *types.Block:
 		if part.Interpretation.HasValue() {
 			return fmt.Errorf("part already has interpretation %v", part.Interpretation)
@@ -984,11 +1714,21 @@ func (a *Analyzer) assign(part *types.SimplePart, value types.Value) error {
 			return fmt.Errorf("part already has chord %v", part.Harmony.Chord)
 		}
 		part.Harmony.Chord = v
+	case *types.Accent:
+		if part.Rhythm.Accent.HasValue() {
+			return fmt.Errorf("part already has accent %v", part.Rhythm.Accent)
+		}
+		part.Rhythm.Accent = v
 	case *types.Dynamics:
 		if part.Rhythm.Dynamics.HasValue() {
 			return fmt.Errorf("part already has dynamics %v", part.Rhythm.Dynamics)
 		}
 		part.Rhythm.Dynamics = v
+	case *types.Gate:
+		if part.Rhythm.Gate.HasValue() {
+			return fmt.Errorf("part already has gate %v", part.Rhythm.Gate)
+		}
+		part.Rhythm.Gate = v
 	case *types.Humanize:
 		if part.Rhythm.Humanize.HasValue() {
 			return fmt.Errorf("part already has humanize %v", part.Rhythm.Humanize)
@@ -1004,6 +1744,14 @@ func (a *Analyzer) assign(part *types.SimplePart, value types.Value) error {
 			return fmt.Errorf("part already has meter %v", part.Rhythm.Meter)
 		}
 		part.Rhythm.Meter = v
+	case *types.Swing:
+		if part.Rhythm.Swing.HasValue() {
+			return fmt.Errorf("part already has swing %v", part.Rhythm.Swing)
+		}
+		part.Rhythm.Swing = v
+	case types.MIDIMessage:
+		// A part can send any number of messages.
+		part.Messages = append(part.Messages, v.Message())
 	case types.Note:
 		if part.Harmony.Octave.HasValue() {
 			return fmt.Errorf("part already has octave %v (tried to add note)", part.Harmony.Octave)
@@ -1023,6 +1771,11 @@ func (a *Analyzer) assign(part *types.SimplePart, value types.Value) error {
 			return fmt.Errorf("part already has pitch %v", part.Harmony.Pitch)
 		}
 		part.Harmony.Pitch = v
+	case *types.Priority:
+		if part.Priority.HasValue() {
+			return fmt.Errorf("part already has priority %v", part.Priority)
+		}
+		part.Priority = v
 	case *types.Prob:
 		if part.Interpretation.HasValue() {
 			return fmt.Errorf("part already has interpretation %v", part.Interpretation)
@@ -1050,6 +1803,8 @@ func (a *Analyzer) assign(part *types.SimplePart, value types.Value) error {
 		return fmt.Errorf("loose number in simple part")
 	case types.String:
 		return fmt.Errorf("loose string in simple part")
+	case types.Tie:
+		return fmt.Errorf("a tie (-) can only appear in a sequence, after what it ties")
 	default:
 		panic(fmt.Sprintf("Internal error: unhandled value type in simple expression: %v", value))
 	}
@@ -1062,14 +1817,27 @@ func (a *Analyzer) assign(part *types.SimplePart, value types.Value) error {
 // Take a simple part and fill out any missing values from the current environment's default expression.
 func (a *Analyzer) fillOutDefaults(part *types.SimplePart) error {
 	a.trace("Filling out defaults.")
-	env := a.currentEnv()
-	def := env.defPart
+	fillOutFrom(part, a.currentEnv().defPart)
+	return nil
+}
+
+// Fill out any values missing from a simple part with the values of another.
+func fillOutFrom(part *types.SimplePart, def *types.SimplePart) {
+	if !part.Rhythm.Accent.HasValue() {
+		part.Rhythm.Accent = def.Rhythm.Accent
+	}
+	if !part.Harmony.Arp.HasValue() {
+		part.Harmony.Arp = def.Harmony.Arp
+	}
 	if !part.Harmony.Chord.HasValue() {
 		part.Harmony.Chord = def.Harmony.Chord
 	}
 	if !part.Rhythm.Dynamics.HasValue() {
 		part.Rhythm.Dynamics = def.Rhythm.Dynamics
 	}
+	if !part.Rhythm.Gate.HasValue() {
+		part.Rhythm.Gate = def.Rhythm.Gate
+	}
 	if !part.Rhythm.Humanize.HasValue() {
 		part.Rhythm.Humanize = def.Rhythm.Humanize
 	}
@@ -1082,6 +1850,12 @@ func (a *Analyzer) fillOutDefaults(part *types.SimplePart) error {
 	if !part.Rhythm.Meter.HasValue() {
 		part.Rhythm.Meter = def.Rhythm.Meter
 	}
+	if !part.Priority.HasValue() {
+		part.Priority = def.Priority
+	}
+	if !part.Rhythm.Swing.HasValue() {
+		part.Rhythm.Swing = def.Rhythm.Swing
+	}
 	if !part.Harmony.Octave.HasValue() {
 		part.Harmony.Octave = def.Harmony.Octave
 	}
@@ -1094,7 +1868,6 @@ func (a *Analyzer) fillOutDefaults(part *types.SimplePart) error {
 	if !part.Harmony.Voicing.HasValue() {
 		part.Harmony.Voicing = def.Harmony.Voicing
 	}
-	return nil
 }
 
 func (a *Analyzer) analyzeSimpleExpr(expr *ast.SimpleExpr) (types.Part, error) {
@@ -1107,6 +1880,8 @@ func (a *Analyzer) analyzeSimpleExpr(expr *ast.SimpleExpr) (types.Part, error) {
 
 	part := types.NewSimplePart()
 	var seq *ast.SeqExpr
+	var tempo *types.TempoPart // A tempo change to make as the part starts.
+	onlyMessages := true       // Whether the expression just sends messages, without sounding anything.
 
 	for _, valExpr := range expr.ValueExprs {
 		var val types.Value
@@ -1122,6 +1897,8 @@ func (a *Analyzer) analyzeSimpleExpr(expr *ast.SimpleExpr) (types.Part, error) {
 			val, err = a.analyzeParamExpr(ex)
 		case *ast.MeterExpr:
 			val, err = a.analyzeMeterExpr(ex)
+		case *ast.MelodyExpr:
+			val, err = a.analyzeMelodyExpr(ex)
 		case *ast.SeqExpr:
 			// Keep this around for later.
 			// Once the rest of the simple part is analyzed, only then can we analyze the
@@ -1147,24 +1924,34 @@ func (a *Analyzer) analyzeSimpleExpr(expr *ast.SimpleExpr) (types.Part, error) {
 			if err != nil {
 				return nil, err
 			}
+			onlyMessages = false
 		case *types.CompoundPart:
 			a.trace("Found a compound part reference in a simple expression.")
 			if len(expr.ValueExprs) > 1 {
 				fmt.Println("Warning: Expression references a compound part; these are currently ignored!")
 			}
-			return v, nil
+			return a.withTempo(tempo, v), nil
 		case *types.BlockPart:
 			a.trace("Found a block part reference in a simple expression.")
 			if len(expr.ValueExprs) > 1 {
 				fmt.Println("Warning: Expression references a block part; loose values currently ignored!")
 			}
-			return v, nil
+			return a.withTempo(tempo, v), nil
 		case *types.Seq:
 			a.trace("Found a sequence part reference in a simple expression.")
 			if len(expr.ValueExprs) > 1 {
 				fmt.Println("Warning: Expression references a sequence part; loose values currently ignored!")
 			}
-			return v, nil
+			return a.withTempo(tempo, v), nil
+		case *types.MIDIMessagePart:
+			a.trace("Found a MIDI message part reference in a simple expression; sending its messages from this part.")
+			part.Messages = append(part.Messages, v.Messages...)
+		case *types.TempoPart:
+			a.trace("Found a tempo change in a simple expression; making it as this part starts.")
+			if tempo != nil {
+				return nil, a.errorf(expr.Line, "more than one tempo change in one part")
+			}
+			tempo = v
 		case types.MessagePart:
 			// TODO: Again, I don't even think this can happen syntactically now,
 			// but let's guard against it.
@@ -1179,6 +1966,9 @@ func (a *Analyzer) analyzeSimpleExpr(expr *ast.SimpleExpr) (types.Part, error) {
 			if err != nil {
 				return nil, err
 			}
+			if _, ok := v.(types.MIDIMessage); !ok {
+				onlyMessages = false
+			}
 		}
 	}
 
@@ -1195,11 +1985,38 @@ func (a *Analyzer) analyzeSimpleExpr(expr *ast.SimpleExpr) (types.Part, error) {
 		if err != nil {
 			return nil, err
 		}
+		if len(part.Messages) > 0 {
+			// The messages go out as the seq starts.
+			block := types.NewBlockPart()
+			block.Add(a.messagePart(part.Messages, part.Instrument))
+			block.Add(seqPart)
+			return a.withTempo(tempo, block), nil
+		}
 		// A simple expr containing a seq expr produces a seq part.
-		return seqPart, nil
+		return a.withTempo(tempo, seqPart), nil
 	}
 
-	return part, nil
+	// Nothing but messages (e.g. a line with just a pc in it) shouldn't sound the default note.
+	if onlyMessages {
+		if len(part.Messages) == 0 && tempo != nil {
+			return tempo, nil // Just a tempo change, e.g. a line with an accel in it.
+		}
+		return a.withTempo(tempo, a.messagePart(part.Messages, part.Instrument)), nil
+	}
+
+	return a.withTempo(tempo, part), nil
+}
+
+// withTempo puts a tempo change at the start of a part, if there is one, so that a
+// ramp goes over the part.
+func (a *Analyzer) withTempo(tempo *types.TempoPart, p types.Part) types.Part {
+	if tempo == nil {
+		return p
+	}
+	block := types.NewBlockPart()
+	block.Add(tempo)
+	block.Add(p)
+	return block
 }
 
 func (a *Analyzer) analyzeCompoundExpr(expr *ast.CompoundExpr) (*types.CompoundPart, error) {
@@ -1220,10 +2037,80 @@ func (a *Analyzer) analyzeCompoundExpr(expr *ast.CompoundExpr) (*types.CompoundP
 	return part, nil
 }
 
-// analyzePC analyzes a PC statement and returns a part that sends a MIDI program change.
-func (a *Analyzer) analyzePC(expr *ast.ParamExpr) (types.ProgramChange, error) {
+// analyzePC analyzes a pc expression and returns a MIDI program change, which is sent
+// at the start of the part it's in.
+func (a *Analyzer) analyzePC(expr *ast.ParamExpr) (*types.ProgramChange, error) {
 	a.trace("MIDI PC expression")
-	panic("yeah not yet implemented sorry")
+	a.indent()
+	defer a.unindent()
+	assertName(expr.Name, "pc")
+	if len(expr.Params) != 2 {
+		return nil, a.errorf(expr.Line, "pc requires pc(channel, program)")
+	}
+	channel, err := a.analyzeNumberOrIdent(expr.Params[0])
+	if err != nil {
+		return nil, err
+	}
+	if channel.Value < 1 || channel.Value > 16 {
+		return nil, a.errorf(expr.Line, "pc MIDI channel must be from 1-16")
+	}
+	program, err := a.analyzeNumberOrIdent(expr.Params[1])
+	if err != nil {
+		return nil, err
+	}
+	if program.Value > 127 {
+		return nil, a.errorf(expr.Line, "pc program must be from 0-127")
+	}
+	return &types.ProgramChange{
+		Channel: uint8(channel.Value),
+		Program: uint8(program.Value),
+	}, nil
+}
+
+// analyzeCC analyzes a cc expression and returns a MIDI controller change, which is sent
+// at the start of the part it's in.
+func (a *Analyzer) analyzeCC(expr *ast.ParamExpr) (*types.ControllerChange, error) {
+	a.trace("MIDI CC expression")
+	a.indent()
+	defer a.unindent()
+	assertName(expr.Name, "cc")
+	if len(expr.Params) != 3 {
+		return nil, a.errorf(expr.Line, "cc requires cc(channel, controller, value)")
+	}
+	channel, err := a.analyzeNumberOrIdent(expr.Params[0])
+	if err != nil {
+		return nil, err
+	}
+	if channel.Value < 1 || channel.Value > 16 {
+		return nil, a.errorf(expr.Line, "cc MIDI channel must be from 1-16")
+	}
+	controller, err := a.analyzeNumberOrIdent(expr.Params[1])
+	if err != nil {
+		return nil, err
+	}
+	if controller.Value > 127 {
+		return nil, a.errorf(expr.Line, "cc controller must be from 0-127")
+	}
+	value, err := a.analyzeNumberOrIdent(expr.Params[2])
+	if err != nil {
+		return nil, err
+	}
+	if value.Value > 127 {
+		return nil, a.errorf(expr.Line, "cc value must be from 0-127")
+	}
+	return &types.ControllerChange{
+		Channel:    uint8(channel.Value),
+		Controller: uint8(controller.Value),
+		Value:      uint8(value.Value),
+	}, nil
+}
+
+// messagePart makes a part that sends MIDI messages to an instrument, and takes up no time.
+func (a *Analyzer) messagePart(msgs []midi.Message, inst *types.Instrument) *types.MIDIMessagePart {
+	return &types.MIDIMessagePart{
+		Messages:   msgs,
+		Instrument: inst,
+	}
 }
 
 func (a *Analyzer) analyzeBlockExpr(expr *ast.BlockExpr) (types.Part, error) {
@@ -1235,17 +2122,25 @@ func (a *Analyzer) analyzeBlockExpr(expr *ast.BlockExpr) (types.Part, error) {
 	// Go over its statements, building up bindings and analyzing subexpressions.
 	for _, stmt := range expr.Statements {
 		switch s := stmt.(type) {
-		// BPM and PPQ statements can only appeare in the root scope.
-		// TODO: Is how we return BPM and PPQ satisfactory? We could set it multiple times?
+		// The first BPM sets the tempo the piece starts at. Past that, or in another block,
+		// it changes the tempo from there to the end of the block.
+		// PPQ statements can only appear in the root scope.
 		case *ast.BPMStatement:
-			if a.depth() > 1 {
-				return nil, a.errorf(expr.Line, "bpm can only be set in the top scope")
+			if s.BPM <= 0 {
+				a.fail(s.Line, a.errorf(s.Line, "bpm must be above 0"))
+				continue
+			}
+			if a.depth() > 1 || part.NumParts() > 0 {
+				a.trace("Changing BPM to %v.", s.BPM)
+				part.Add(types.NewBPMPart(s.BPM))
+				continue
 			}
 			a.trace("Setting BPM to %v.", s.BPM)
 			a.bpm = s.BPM
 		case *ast.PPQStatement:
 			if a.depth() > 1 {
-				return nil, a.errorf(expr.Line, "ppq can only be set in the top scope")
+				a.fail(s.Line, a.errorf(s.Line, "ppq can only be set in the top scope"))
+				continue
 			}
 			a.trace("Setting PPQ to %v.", s.PPQ)
 			a.ppq = s.PPQ
@@ -1261,16 +2156,21 @@ func (a *Analyzer) analyzeBlockExpr(expr *ast.BlockExpr) (types.Part, error) {
 
 		case *ast.LetStatement:
 			if err := a.analyzeLet(s); err != nil {
-				return nil, fmt.Errorf("line %v: %v", s.Line, err) // TODO: uh what to do with this line business here
+				a.fail(s.Line, err)
+			}
+		case *ast.ImportStatement:
+			if err := a.analyzeImport(s); err != nil {
+				a.fail(s.Line, err)
 			}
 		case *ast.DefaultStatement:
 			if err := a.analyzeDefault(s); err != nil {
-				return nil, err
+				a.fail(s.Line, err)
 			}
 		case *ast.PlayStatement:
 			p, err := a.analyzePlay(s)
 			if err != nil {
-				return nil, err
+				a.fail(s.Line, err)
+				continue
 			}
 			part.Add(p)
 		default:
@@ -1312,6 +2212,10 @@ func (a *Analyzer) analyzeExpr(expr ast.Expression) (types.Value, error) {
 		return a.analyzeStringExpr(e)
 	case *ast.MeterExpr:
 		return a.analyzeMeterExpr(e)
+	case *ast.MelodyExpr:
+		return a.analyzeMelodyExpr(e)
+	case *ast.NamedArgExpr:
+		return nil, a.errorf(e.Line, "named argument '%v' can only be given to a let with parameters", e.Name)
 	default:
 		panic("Internal error: unhandled expression type")
 	}
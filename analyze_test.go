@@ -2,8 +2,14 @@ package main
 
 import (
 	"github.com/edemond/abstract/ast"
+	"github.com/edemond/abstract/msg"
 	"github.com/edemond/abstract/parser"
 	"github.com/edemond/abstract/types"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -104,3 +110,689 @@ func TestSimplePartsCanCombine(t *testing.T) {
 		t.Fatalf("expected 5/4 time, got %v", simple.Rhythm.Meter)
 	}
 }
+
+func TestMessagesGoInSimplePart(t *testing.T) {
+	a := NewAnalyzer()
+
+	text := `pc(2, 5) cc(2, 74, 30) C
+        `
+	parsed := testParse(t, text)
+	part, err := a.Analyze(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	simple, ok := part.(*types.SimplePart)
+	if !ok {
+		t.Fatalf("Expected a simple part, got %v", part)
+	}
+	if len(simple.Messages) != 2 {
+		t.Fatalf("expected 2 messages, got %v", len(simple.Messages))
+	}
+	pc := simple.Messages[0]
+	if pc.Command != 0xC || pc.Channel != 2 || pc.Data1 != 5 {
+		t.Fatalf("expected pc(2, 5), got %v", pc)
+	}
+	cc := simple.Messages[1]
+	if cc.Command != 0xB || cc.Channel != 2 || cc.Data1 != 74 || cc.Data2 != 30 {
+		t.Fatalf("expected cc(2, 74, 30), got %v", cc)
+	}
+}
+
+func TestLooseMessageDoesNotSound(t *testing.T) {
+	a := NewAnalyzer()
+
+	text := `pc(1, 5)
+        `
+	parsed := testParse(t, text)
+	part, err := a.Analyze(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs, ok := part.(*types.MIDIMessagePart)
+	if !ok {
+		t.Fatalf("Expected a MIDI message part, got %v", part)
+	}
+	if msgs.Length(96) != 0 {
+		t.Fatalf("expected a message part to take no time, got length %v", msgs.Length(96))
+	}
+}
+
+func TestBadPC(t *testing.T) {
+	a := NewAnalyzer()
+
+	text := `pc(17, 5)
+        `
+	parsed := testParse(t, text)
+	_, err := a.Analyze(parsed)
+	if err == nil {
+		t.Fatalf("expected an error for a pc on channel 17")
+	}
+}
+
+func TestTieHoldsNote(t *testing.T) {
+	a := NewAnalyzer()
+
+	text := `[C - E]
+        `
+	parsed := testParse(t, text)
+	part, err := a.Analyze(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq, ok := part.(*types.Seq)
+	if !ok {
+		t.Fatalf("Expected a seq part, got %v", part)
+	}
+	// Three slots of a 4/4 bar at 96 ppq: C is held through the second one.
+	length := firstNoteLength(t, seq)
+	if length <= 128 || length > 256 {
+		t.Fatalf("expected the tied note to last into the second slot (128-256 steps), got %v", length)
+	}
+}
+
+// Play the first step of a part at 96 ppq and return the length of the note it starts.
+func firstNoteLength(t *testing.T, part types.Part) uint64 {
+	buf, err := msg.NewBuffer(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Play(buf, rand.New(rand.NewSource(1)), 96, 0)
+	if buf.NextLength() != 1 {
+		t.Fatalf("expected one note at the start, got %v", buf.NextLength())
+	}
+	return buf.Next()[0].Length
+}
+
+func TestTieOutsideSeq(t *testing.T) {
+	a := NewAnalyzer()
+
+	text := `C -
+        `
+	parsed := testParse(t, text)
+	_, err := a.Analyze(parsed)
+	if err == nil {
+		t.Fatalf("expected an error for a tie outside a sequence")
+	}
+}
+
+func TestSeqInheritsFromParent(t *testing.T) {
+	a := NewAnalyzer()
+
+	text := `staccato [C D]
+        `
+	parsed := testParse(t, text)
+	part, err := a.Analyze(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Half a bar at 96 ppq, cut to half by staccato.
+	if length := firstNoteLength(t, part); length != 96 {
+		t.Fatalf("expected the seq's notes to be staccato (96 steps), got %v", length)
+	}
+}
+
+func TestDefaultGate(t *testing.T) {
+	a := NewAnalyzer()
+
+	text := `default staccato
+        C
+        `
+	parsed := testParse(t, text)
+	part, err := a.Analyze(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	simple, ok := part.(*types.SimplePart)
+	if !ok {
+		t.Fatalf("Expected a simple part, got %v", part)
+	}
+	if simple.Rhythm.Gate.Percent != 50 {
+		t.Fatalf("expected the default gate to be staccato, got %v", simple.Rhythm.Gate)
+	}
+}
+
+func TestBjorkComposes(t *testing.T) {
+	a := NewAnalyzer()
+
+	text := `bjork(3, 8, 1) E dynamics(100)
+        `
+	parsed := testParse(t, text)
+	part, err := a.Analyze(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	simple, ok := part.(*types.SimplePart)
+	if !ok {
+		t.Fatalf("Expected a simple part, got %v", part)
+	}
+	if _, ok := simple.Interpretation.(*types.Bjork); !ok {
+		t.Fatalf("expected a bjork interpretation, got %v", simple.Interpretation)
+	}
+	if simple.Rhythm.Dynamics.Center != 100 {
+		t.Fatalf("expected dynamics 100, got %v", simple.Rhythm.Dynamics)
+	}
+}
+
+func TestBjorkTooManyPulses(t *testing.T) {
+	a := NewAnalyzer()
+
+	text := `bjork(9, 8) C
+        `
+	parsed := testParse(t, text)
+	_, err := a.Analyze(parsed)
+	if err == nil {
+		t.Fatalf("expected an error for more pulses than steps")
+	}
+}
+
+func TestRhythmComposes(t *testing.T) {
+	a := NewAnalyzer()
+
+	text := `rhythm(0x8080, 0x88) C dynamics(90)
+        `
+	parsed := testParse(t, text)
+	part, err := a.Analyze(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	simple, ok := part.(*types.SimplePart)
+	if !ok {
+		t.Fatalf("Expected a simple part, got %v", part)
+	}
+	if _, ok := simple.Interpretation.(*types.Bits); !ok {
+		t.Fatalf("expected a rhythm interpretation, got %v", simple.Interpretation)
+	}
+}
+
+func TestEveryErrorReported(t *testing.T) {
+	a := NewAnalyzer()
+
+	text := `let x = foo
+        C
+        pc(17, 1)
+        `
+	parsed := testParse(t, text)
+	_, err := a.Analyze(parsed)
+	errs, ok := err.(analysisErrors)
+	if !ok {
+		t.Fatalf("expected analysis errors, got %v", err)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errs)
+	}
+	if !strings.HasPrefix(errs[0].Error(), "line 1:") || !strings.HasPrefix(errs[1].Error(), "line 3:") {
+		t.Fatalf("expected errors on lines 1 and 3, got %v", errs)
+	}
+}
+
+func TestAccent(t *testing.T) {
+	tests := []struct {
+		text    string
+		amounts string
+		err     string
+	}{
+		{"default accent(20)\nC", "accent(20, 15, 10, 5)", ""},
+		{"C accent(24, 8, 16)", "accent(24, 8, 16)", ""},
+		{"C accent(200)", "", "accent amounts must be 0-127"},
+		{"C accent(1, 2, 3, 4, 5, 6, 7)", "", "accent takes 1-6 amounts"},
+		{"C accent(10) accent(20)", "", "part already has accent"},
+	}
+	for _, test := range tests {
+		part, err := NewAnalyzer().Analyze(testParse(t, test.text+"\n"))
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("%q: expected error %q, got %v", test.text, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q: %v", test.text, err)
+		}
+		simple, ok := part.(*types.SimplePart)
+		if !ok {
+			t.Fatalf("%q: expected a simple part, got %v", test.text, part)
+		}
+		if simple.Rhythm.Accent.String() != test.amounts {
+			t.Fatalf("%q: expected %v, got %v", test.text, test.amounts, simple.Rhythm.Accent)
+		}
+	}
+}
+
+func TestSwing(t *testing.T) {
+	tests := []struct {
+		text  string
+		swing string
+		err   string
+	}{
+		{"default swing(66)\nC", "swing(66, 8)", ""},
+		{"C swing(58, 16)", "swing(58, 16)", ""},
+		{"C swing(40)", "", "swing must be from 50-75 percent"},
+		{"C swing(60, 12)", "", "swing can be on quarters"},
+		{"C swing(60) swing(66)", "", "part already has swing"},
+	}
+	for _, test := range tests {
+		part, err := NewAnalyzer().Analyze(testParse(t, test.text+"\n"))
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("%q: expected error %q, got %v", test.text, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q: %v", test.text, err)
+		}
+		simple, ok := part.(*types.SimplePart)
+		if !ok {
+			t.Fatalf("%q: expected a simple part, got %v", test.text, part)
+		}
+		if simple.Rhythm.Swing.String() != test.swing {
+			t.Fatalf("%q: expected %v, got %v", test.text, test.swing, simple.Rhythm.Swing)
+		}
+	}
+}
+
+func TestTempo(t *testing.T) {
+	tests := []struct {
+		text string
+		bpm  map[uint64]float64 // Step -> tempo, at the default 64 PPQ.
+		err  string
+	}{
+		{"bpm 100\nC\nbpm 80\nC", map[uint64]float64{0: 100, 256: 80}, ""},
+		{"C\nrit(120, 60) C", map[uint64]float64{0: 120, 256: 120, 384: 90}, ""},
+		{"C\naccel(60, 120)\nC", map[uint64]float64{256: 60, 384: 90}, ""},
+		{"let bridge = {\n\tbpm 90\n\tC\n}\nC\nbridge\nC", map[uint64]float64{0: 120, 256: 90, 512: 120}, ""},
+		{"accel(120, 100) C", nil, "accel has to speed up"},
+		{"rit(100) C", nil, "rit requires rit(from, to)"},
+		{"accel(100, 120) rit(120, 100) C", nil, "more than one tempo change"},
+		{"[accel(100, 140) C]", nil, "tempo changes go in blocks"},
+		{"[C rit(120, 90) D]", nil, "tempo changes go in blocks"},
+	}
+	for _, test := range tests {
+		a := NewAnalyzer()
+		part, err := a.Analyze(testParse(t, test.text+"\n"))
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("%q: expected error %q, got %v", test.text, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q: %v", test.text, err)
+		}
+		tempo := types.NewTempoMap(part, a.bpm, a.ppq)
+		for step, bpm := range test.bpm {
+			if got := tempo.BPM(step); got != bpm {
+				t.Errorf("%q: expected step %v to be at %v bpm, got %v", test.text, step, bpm, got)
+			}
+		}
+	}
+}
+
+func TestInstrumentSteal(t *testing.T) {
+	tests := []struct {
+		text  string
+		steal msg.Steal
+		err   string
+	}{
+		{"instrument(\"synth\", 1, 4) C", msg.StealOldest, ""},
+		{"instrument(\"synth\", 1, 4, quietest) C", msg.StealQuietest, ""},
+		{"instrument(\"synth\", 1, 4, \"lowest\") C", msg.StealLowest, ""},
+		{"instrument(\"synth\", 1, 4, none) C", msg.StealNone, ""},
+		{"instrument(\"synth\", 1, 4, loudest) C", 0, "voice stealing must be oldest, quietest, lowest or none"},
+	}
+	for _, test := range tests {
+		part, err := NewAnalyzer().Analyze(testParse(t, test.text+"\n"))
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("%q: expected error %q, got %v", test.text, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q: %v", test.text, err)
+		}
+		simple, ok := part.(*types.SimplePart)
+		if !ok {
+			t.Fatalf("%q: expected a simple part, got %v", test.text, part)
+		}
+		if simple.Instrument.Voices.Steal != test.steal {
+			t.Fatalf("%q: expected to steal the %v note, got %v", test.text, test.steal, simple.Instrument.Voices.Steal)
+		}
+	}
+}
+
+func TestPriority(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+		err      string
+	}{
+		{"priority(2) C", "priority(2)", ""},
+		{"default priority(3)\nC", "priority(3)", ""},
+		{"priority(1) priority(2) C", "", "part already has priority"},
+		{"priority(1, 2) C", "", "priority requires priority(level)"},
+	}
+	for _, test := range tests {
+		part, err := NewAnalyzer().Analyze(testParse(t, test.text+"\n"))
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("%q: expected error %q, got %v", test.text, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q: %v", test.text, err)
+		}
+		simple, ok := part.(*types.SimplePart)
+		if !ok {
+			t.Fatalf("%q: expected a simple part, got %v", test.text, part)
+		}
+		if got := simple.Priority.String(); got != test.expected {
+			t.Fatalf("%q: expected %v, got %v", test.text, test.expected, got)
+		}
+	}
+}
+
+// writeTunes writes out files (name -> text) to a temporary directory and returns its path.
+func writeTunes(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, text := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func analyzeTune(t *testing.T, filename string) (types.Part, error) {
+	p, err := parser.FromFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewAnalyzer().AnalyzeFile(filename, parsed)
+}
+
+func TestImportBindsLets(t *testing.T) {
+	dir := writeTunes(t, map[string]string{
+		"drums/kit.abs": "let kick = C\nlet snare = D\nlet beat = [kick snare]\nC\n",
+		"song.abs":      "import \"drums/kit.abs\"\nbeat\n",
+	})
+	_, err := analyzeTune(t, filepath.Join(dir, "song.abs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImportIsRelativeToImportingFile(t *testing.T) {
+	dir := writeTunes(t, map[string]string{
+		"drums/kit.abs":   "import \"notes.abs\"\nlet beat = [kick snare]\n",
+		"drums/notes.abs": "let kick = C\nlet snare = D\n",
+		"song.abs":        "import \"drums/kit.abs\"\n[beat kick]\n",
+	})
+	_, err := analyzeTune(t, filepath.Join(dir, "song.abs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImportSameFileTwice(t *testing.T) {
+	dir := writeTunes(t, map[string]string{
+		"kick.abs": "let kick = C\n",
+		"one.abs":  "import \"kick.abs\"\nlet one = kick\n",
+		"two.abs":  "import \"kick.abs\"\nlet two = kick\n",
+		"song.abs": "import \"one.abs\"\nimport \"two.abs\"\n[one two kick]\n",
+	})
+	_, err := analyzeTune(t, filepath.Join(dir, "song.abs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImportInstrumentTwice(t *testing.T) {
+	dir := writeTunes(t, map[string]string{
+		"kit.abs":  "let kit = instrument(\"kit\", 10, 8)\nlet kick = note(36)\n",
+		"one.abs":  "import \"kit.abs\"\nlet one = kit kick\n",
+		"two.abs":  "import \"kit.abs\"\nlet two = kit kick\n",
+		"song.abs": "import \"one.abs\"\nimport \"two.abs\"\nimport \"kit.abs\"\nimport \"kit.abs\"\n[one two kit kick]\n",
+	})
+	_, err := analyzeTune(t, filepath.Join(dir, "song.abs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImportConflict(t *testing.T) {
+	dir := writeTunes(t, map[string]string{
+		"kit.abs":  "let kick = C\n",
+		"song.abs": "let kick = D\nimport \"kit.abs\"\nkick\n",
+	})
+	_, err := analyzeTune(t, filepath.Join(dir, "song.abs"))
+	if err == nil || !strings.Contains(err.Error(), "line 2: 'kick'") {
+		t.Fatalf("expected 'kick' to conflict on line 2, got %v", err)
+	}
+}
+
+func TestImportCycle(t *testing.T) {
+	dir := writeTunes(t, map[string]string{
+		"a.abs":    "import \"b.abs\"\nlet a = C\n",
+		"b.abs":    "import \"a.abs\"\nlet b = D\n",
+		"song.abs": "import \"a.abs\"\na\n",
+	})
+	_, err := analyzeTune(t, filepath.Join(dir, "song.abs"))
+	if err == nil || !strings.Contains(err.Error(), "import cycle: a.abs -> b.abs -> a.abs") {
+		t.Fatalf("expected an import cycle, got %v", err)
+	}
+}
+
+func TestImportMissingFile(t *testing.T) {
+	dir := writeTunes(t, map[string]string{
+		"song.abs": "C\nimport \"nope.abs\"\n",
+	})
+	_, err := analyzeTune(t, filepath.Join(dir, "song.abs"))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Fatalf("expected an error on line 2, got %v", err)
+	}
+}
+
+func TestImportErrorsSayWhere(t *testing.T) {
+	dir := writeTunes(t, map[string]string{
+		"kit.abs":  "let kick = C\nlet snare = foo\n",
+		"song.abs": "import \"kit.abs\"\nkick\n",
+	})
+	_, err := analyzeTune(t, filepath.Join(dir, "song.abs"))
+	if err == nil || !strings.Contains(err.Error(), "line 1: in kit.abs: line 2:") {
+		t.Fatalf("expected the error in kit.abs, got %v", err)
+	}
+}
+
+// callDynamics analyzes a call to hit (defined in the text) and returns the dynamics of the part.
+func callDynamics(t *testing.T, text string) (int, error) {
+	parsed := testParse(t, "let hit(n, d = dynamics(40), e = d) = n e\n"+text+"\n")
+	part, err := NewAnalyzer().Analyze(parsed)
+	if err != nil {
+		return 0, err
+	}
+	simple, ok := part.(*types.SimplePart)
+	if !ok {
+		t.Fatalf("expected a simple part, got %v", part)
+	}
+	return int(simple.Rhythm.Dynamics.Center), nil
+}
+
+func TestCallArguments(t *testing.T) {
+	tests := []struct {
+		call     string
+		dynamics int
+	}{
+		{"hit(C)", 40},
+		{"hit(C, dynamics(90))", 90},
+		{"hit(C, d = dynamics(90))", 90},
+		{"hit(d = dynamics(90), n = C)", 90},
+		{"hit(C, e = dynamics(20))", 20},
+	}
+	for _, test := range tests {
+		dynamics, err := callDynamics(t, test.call)
+		if err != nil {
+			t.Fatalf("%v: %v", test.call, err)
+		}
+		if dynamics != test.dynamics {
+			t.Fatalf("%v: expected dynamics %v, got %v", test.call, test.dynamics, dynamics)
+		}
+	}
+}
+
+func TestBadCallArguments(t *testing.T) {
+	tests := []struct {
+		call string
+		err  string
+	}{
+		{"hit(e = dynamics(20))", "missing argument 'n' to 'hit'"},
+		{"hit(C, x = dynamics(20))", "'hit' has no parameter 'x'"},
+		{"hit(C, n = D)", "argument 'n' to 'hit' given more than once"},
+		{"hit(d = dynamics(90), C)", "positional argument after named argument"},
+		{"hit(C, D, E, F)", "wrong number of arguments to 'hit' (got 4, expected 3)"},
+		{"dynamics(d = 3)", "named argument 'd'"},
+	}
+	for _, test := range tests {
+		_, err := callDynamics(t, test.call)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("%v: expected error %q, got %v", test.call, test.err, err)
+		}
+	}
+}
+
+func TestSmoothVoicing(t *testing.T) {
+	tests := []struct {
+		text      string
+		low, high types.Note
+		err       string
+	}{
+		{"voicing(smooth) Cmaj", 48, 72, ""},
+		{"voicing(smooth, range(C3, C5)) Cmaj", 36, 60, ""},
+		{"voicing(smooth, range(Bb2, note(60))) Cmaj", 34, 60, ""},
+		{"voicing(smooth, range(C3, A3)) Cmaj", 0, 0, "has to span at least an octave"},
+		{"voicing(smooth, range(C3)) Cmaj", 0, 0, "voicing(smooth, range(low, high))"},
+		{"voicing(smooth, range(H3, C5)) Cmaj", 0, 0, "not a note name"},
+	}
+	for _, test := range tests {
+		part, err := NewAnalyzer().Analyze(testParse(t, test.text+"\n"))
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("%q: expected error %q, got %v", test.text, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q: %v", test.text, err)
+		}
+		simple, ok := part.(*types.SimplePart)
+		if !ok {
+			t.Fatalf("%q: expected a simple part, got %v", test.text, part)
+		}
+		smooth, ok := simple.Harmony.Voicing.(*types.SmoothVoicing)
+		if !ok {
+			t.Fatalf("%q: expected a smooth voicing, got %v", test.text, simple.Harmony.Voicing)
+		}
+		if smooth.Low != test.low || smooth.High != test.high {
+			t.Fatalf("%q: expected a range of %v to %v, got %v to %v", test.text, test.low, test.high, smooth.Low, smooth.High)
+		}
+	}
+}
+
+func TestNamedVoicing(t *testing.T) {
+	for _, text := range []string{"drop2 Cmaj7", "voicing(drop2) Cmaj7", "let v = drop2\nv Cmaj7"} {
+		part, err := NewAnalyzer().Analyze(testParse(t, text+"\n"))
+		if err != nil {
+			t.Fatalf("%q: %v", text, err)
+		}
+		simple, ok := part.(*types.SimplePart)
+		if !ok {
+			t.Fatalf("%q: expected a simple part, got %v", text, part)
+		}
+		if simple.Harmony.Voicing != types.Drop2Voicing {
+			t.Fatalf("%q: expected the drop2 voicing, got %v", text, simple.Harmony.Voicing)
+		}
+	}
+}
+
+func TestArp(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+		err      string
+	}{
+		{"arp(up) Cmaj", "arp(up, 16, 1)", ""},
+		{"arp(updown, 8, 2) Cmaj", "arp(updown, 8, 2)", ""},
+		{"arp(1, 3, 2, 4) Cmaj", "arp(\"1 3 2 4\", 16)", ""},
+		{"arp(\"1 0 3\", 8) Cmaj", "arp(\"1 0 3\", 8)", ""},
+		{"arp(sideways) Cmaj", "", "arp pattern must be up, down, updown, random, played or chord indexes"},
+		{"arp(up, 16, 0) Cmaj", "", "at least 1 octave"},
+		{"arp(\"1 x\") Cmaj", "", "arp indexes must be numbers"},
+		{"arp(up) bjork(3, 8) Cmaj", "arp(up, 16, 1)", ""},
+		{"arp(up) arp(down) Cmaj", "", "part already has arp"},
+	}
+	for _, test := range tests {
+		part, err := NewAnalyzer().Analyze(testParse(t, test.text+"\n"))
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("%q: expected error %q, got %v", test.text, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q: %v", test.text, err)
+		}
+		simple, ok := part.(*types.SimplePart)
+		if !ok {
+			t.Fatalf("%q: expected a simple part, got %v", test.text, part)
+		}
+		if got := simple.Harmony.Arp.String(); got != test.expected {
+			t.Fatalf("%q: expected %v, got %v", test.text, test.expected, got)
+		}
+	}
+}
+
+func TestMelody(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+		err      string
+	}{
+		{"C mel\"1 3 5 - 4 3 2 _\"", "mel\"1 3 5 - 4 3 2 _\"", ""},
+		{"D dorian mel\"[1 2] 3 b7' -\"", "mel\"[1 2] 3 b7' -\"", ""},
+		{"mel\"1 x\"", "", "melody notes must be scale degrees"},
+		{"mel\"0 1\"", "", "melody scale degrees start at 1"},
+		{"mel\"- 1\"", "", "a tie (-) in a melody has to follow a note"},
+		{"mel\"1 [2 3\"", "", "unclosed [ in melody"},
+		{"mel\"1 2] 3\"", "", "unexpected ] in melody"},
+		{"mel\"1 []\"", "", "empty [] in melody"},
+		{"mel\"1\" bjork(3, 8)", "", "part already has interpretation"},
+	}
+	for _, test := range tests {
+		part, err := NewAnalyzer().Analyze(testParse(t, test.text+"\n"))
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("%q: expected error %q, got %v", test.text, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q: %v", test.text, err)
+		}
+		simple, ok := part.(*types.SimplePart)
+		if !ok {
+			t.Fatalf("%q: expected a simple part, got %v", test.text, part)
+		}
+		if got := simple.Interpretation.String(); got != test.expected {
+			t.Fatalf("%q: expected %v, got %v", test.text, test.expected, got)
+		}
+	}
+}
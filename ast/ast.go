@@ -34,8 +34,13 @@ func (let *LetStatement) String() string {
 	paramExpr, ok := let.Expr.(Parameterized)
 	if ok && paramExpr.HasParameters() {
 		params := []string{}
-		for _, p := range paramExpr.Parameters() {
-			params = append(params, string(p))
+		defaults := paramExpr.Defaults()
+		for i, p := range paramExpr.Parameters() {
+			if defaults[i] != nil {
+				params = append(params, fmt.Sprintf("%v = %v", p, defaults[i]))
+			} else {
+				params = append(params, string(p))
+			}
 		}
 		return fmt.Sprintf("let %v(%v) = %v", let.Name, strings.Join(params, ", "), let.Expr)
 	}
@@ -70,6 +75,16 @@ func (p *PlayStatement) String() string {
 	return p.Expr.String()
 }
 
+// Binds the top-level names of another file, e.g. import "drums/boss.abs".
+type ImportStatement struct {
+	Path string // As written, relative to the importing file.
+	Line int
+}
+
+func (i *ImportStatement) String() string {
+	return fmt.Sprintf("import %q", i.Path)
+}
+
 // Sets the BPM.
 type BPMStatement struct {
 	BPM  int
@@ -130,6 +145,18 @@ func (p *ParamExpr) String() string {
 	return fmt.Sprintf("%v(%v)", p.Name, strings.Join(params, ", "))
 }
 
+// An argument given by name in a call, e.g. s = clap in rock(s = clap). Also a parameter with
+// a default in a let, e.g. s = snare in let rock(k, s = snare) = ...
+type NamedArgExpr struct {
+	Name string
+	Expr Expression
+	Line int
+}
+
+func (n *NamedArgExpr) String() string {
+	return fmt.Sprintf("%v = %v", n.Name, n.Expr)
+}
+
 // Syntax sugar for meter (e.g. 6/8 for meter(6,8)).
 type MeterExpr struct {
 	// TODO: It'd be interesting to allow any expression here, that way you could do:
@@ -144,29 +171,43 @@ func (m *MeterExpr) String() string {
 	return fmt.Sprintf("%v/%v", m.Beats, m.Value)
 }
 
+// A melody literal, e.g. mel"1 3 5 _ 4 3 2 .", in scale degrees.
+type MelodyExpr struct {
+	Notes string
+	Line  int
+}
+
+func (m *MelodyExpr) String() string {
+	return fmt.Sprintf("mel%q", m.Notes)
+}
+
 // Any kind of expression that can be parameterized.
 type Parameterized interface {
 	Expression
 	Parameters() []IdentExpr
+	Defaults() []Expression // One for each parameter; nil if it has no default.
 	HasParameters() bool
-	AddParameter(IdentExpr)
+	AddParameter(IdentExpr, Expression)
 }
 
 type SimpleExpr struct {
 	ValueExprs []Expression
 	params     []IdentExpr
+	defaults   []Expression
 	Line       int
 }
 
 type CompoundExpr struct {
 	SimpleExprs []*SimpleExpr
 	params      []IdentExpr
+	defaults    []Expression
 	Line        int
 }
 
 type BlockExpr struct {
 	Statements []Statement
 	params     []IdentExpr
+	defaults   []Expression
 	Line       int
 }
 
@@ -183,14 +224,17 @@ func (b *BlockExpr) String() string {
 	return fmt.Sprintf("{\n%v\n}", strings.Join(stmts, "\n"))
 }
 
-func (e *SimpleExpr) AddParameter(param IdentExpr) {
+func (e *SimpleExpr) AddParameter(param IdentExpr, def Expression) {
 	e.params = append(e.params, param)
+	e.defaults = append(e.defaults, def)
 }
-func (e *CompoundExpr) AddParameter(param IdentExpr) {
+func (e *CompoundExpr) AddParameter(param IdentExpr, def Expression) {
 	e.params = append(e.params, param)
+	e.defaults = append(e.defaults, def)
 }
-func (e *BlockExpr) AddParameter(param IdentExpr) {
+func (e *BlockExpr) AddParameter(param IdentExpr, def Expression) {
 	e.params = append(e.params, param)
+	e.defaults = append(e.defaults, def)
 }
 
 func (e *SimpleExpr) HasParameters() bool {
@@ -213,6 +257,16 @@ func (e *BlockExpr) Parameters() []IdentExpr {
 	return e.params
 }
 
+func (e *SimpleExpr) Defaults() []Expression {
+	return e.defaults
+}
+func (e *CompoundExpr) Defaults() []Expression {
+	return e.defaults
+}
+func (e *BlockExpr) Defaults() []Expression {
+	return e.defaults
+}
+
 func (e *SimpleExpr) String() string {
 	exprs := []string{}
 	for _, e := range e.ValueExprs {
@@ -242,6 +296,7 @@ func (s *DefaultStatement) isStatement() {}
 func (s *PlayStatement) isStatement()    {}
 func (s *BPMStatement) isStatement()     {}
 func (s *PPQStatement) isStatement()     {}
+func (s *ImportStatement) isStatement()  {}
 
 func (e *SimpleExpr) isExpression()   {}
 func (e *CompoundExpr) isExpression() {}
@@ -249,6 +304,8 @@ func (e *BlockExpr) isExpression()    {}
 func (e *SeqExpr) isExpression()      {}
 func (e IdentExpr) isExpression()     {}
 func (e *ParamExpr) isExpression()    {}
+func (e *NamedArgExpr) isExpression() {}
 func (s StringExpr) isExpression()    {}
 func (n *NumberExpr) isExpression()   {}
 func (m *MeterExpr) isExpression()    {}
+func (m *MelodyExpr) isExpression()   {}
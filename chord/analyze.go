@@ -3,6 +3,7 @@ package chord
 import (
 	"github.com/edemond/abstract/types"
 	"fmt"
+	"sort"
 )
 
 const OCTAVE = 12 // for convenience
@@ -284,8 +285,9 @@ func analyzeAbsoluteChordExpr(expr *absoluteChordExpr) (types.Chord, error) {
 	for _, halfSteps := range chord {
 		intervals = append(intervals, halfSteps)
 	}
+	sort.Ints(intervals) // Root first, and the same order every time.
 
-	return types.NewAbsoluteChord(expr.pitch, intervals), nil
+	return withBass(types.NewAbsoluteChord(expr.pitch, intervals), expr.bass, len(intervals))
 }
 
 func analyzeRelativeChordExpr(expr *relativeChordExpr) (types.Chord, error) {
@@ -299,8 +301,9 @@ func analyzeRelativeChordExpr(expr *relativeChordExpr) (types.Chord, error) {
 	for _, halfSteps := range chord {
 		intervals = append(intervals, halfSteps+expr.accidental)
 	}
+	sort.Ints(intervals)
 
-	return types.NewRelativeChord(expr.rootScaleDegree, intervals), nil
+	return withBass(types.NewRelativeChord(expr.rootScaleDegree, intervals), expr.bass, len(intervals))
 }
 
 func analyzeDiatonicChordExpr(expr *diatonicChordExpr) (types.Chord, error) {
@@ -352,6 +355,21 @@ func analyzeDiatonicChordExpr(expr *diatonicChordExpr) (types.Chord, error) {
 			degrees = append(degrees, (degree + expr.rootScaleDegree - 1))
 		}
 	}
+	sort.Ints(degrees)
 
-	return types.NewDiatonicChord(degrees), nil
+	return withBass(types.NewDiatonicChord(degrees), expr.bass, len(degrees))
+}
+
+// Put the chord's bass on the bottom, if it's something other than the root. The chord has the given number of notes.
+func withBass(chord types.Chord, bass bassExpr, notes int) (types.Chord, error) {
+	if bass.pitch.HasValue() {
+		return types.NewSlashChord(chord, bass.pitch), nil
+	}
+	if bass.inversion == 0 {
+		return chord, nil
+	}
+	if bass.inversion >= notes {
+		return types.NoChord(), fmt.Errorf("a chord of %v notes only has %v inversions (got %v)", notes, notes-1, bass.inversion)
+	}
+	return types.NewInvertedChord(chord, bass.inversion), nil
 }
@@ -11,12 +11,14 @@ type chordExpr interface {
 type absoluteChordExpr struct {
 	pitch     types.Pitch
 	qualities []*qualityExpr
+	bass      bassExpr
 }
 
 type relativeChordExpr struct {
 	rootScaleDegree int
 	accidental      int // -1 for flat, 1 for sharp, 0 for natural. We don't support double sharps or flats here.
 	qualities       []*qualityExpr
+	bass            bassExpr
 }
 
 type diatonicChordExpr struct {
@@ -24,6 +26,14 @@ type diatonicChordExpr struct {
 	// Some qualities are OK in a diatonic context (sus4, power chord), but not others (major, minor)!
 	// The analyzer will enforce this.
 	qualities []*qualityExpr
+	bass      bassExpr
+}
+
+// What goes on the bottom of the chord, if not the root: one of its own notes (e.g. Cmaj/1, I6)
+// or a bass note of its own (e.g. F/Ab).
+type bassExpr struct {
+	inversion int         // Which of the chord's notes, counting the root as 0.
+	pitch     types.Pitch // NoPitch if the bass is one of the chord's own notes.
 }
 
 type rootExpr string // I, ii, @iii
@@ -284,3 +284,61 @@ func TestDiatonicMajorScaleSusChords(t *testing.T) {
 func TestDiatonicMajorScaleNoChords(t *testing.T) {
 	testDia(t, "@VIadd2no5", "C", MAJOR, []string{"A", "B", "C"})
 }
+
+// Test that a chord resolves to the given pitches, in order, with the bass first.
+func testBass(t *testing.T, symbol string, pitch string, expectedPitches []string) {
+	t.Helper()
+	chord, err := ParseAndAnalyze(symbol)
+	if err != nil {
+		t.Fatalf("'%v' didn't parse: %v", symbol, err)
+	}
+	actualPitches := chord.ResolveIn(getPitch(pitch), MAJOR)
+	if len(actualPitches) != len(expectedPitches) {
+		failWithExpected(t, expectedPitches, actualPitches)
+	}
+	for i, p := range expectedPitches {
+		if actualPitches[i] != getPitch(p) {
+			failWithExpected(t, expectedPitches, actualPitches)
+		}
+	}
+	if bass := chord.Bass(getPitch(pitch), MAJOR); bass != getPitch(expectedPitches[0]) {
+		t.Fatalf("Expected '%v' to have %v in the bass, got %v", symbol, expectedPitches[0], bass)
+	}
+}
+
+func TestInversions(t *testing.T) {
+	testBass(t, "Cmaj", "C", []string{"C", "E", "G"})
+	testBass(t, "Cmaj/1", "C", []string{"E", "G", "C"})
+	testBass(t, "Cmaj/2", "C", []string{"G", "C", "E"})
+	testBass(t, "Cmaj7/3", "C", []string{"B", "C", "E", "G"})
+	testBass(t, "IV/1", "C", []string{"A", "C", "F"})
+	testBass(t, "@V/2", "C", []string{"D", "G", "B"})
+}
+
+func TestFiguredBass(t *testing.T) {
+	testBass(t, "I6", "C", []string{"E", "G", "C"})
+	testBass(t, "I64", "C", []string{"G", "C", "E"})
+	testBass(t, "ii6", "C", []string{"F", "A", "D"})
+	testBass(t, "V65", "C", []string{"B", "D", "F", "G"})
+	testBass(t, "V43", "C", []string{"D", "F", "G", "B"})
+	testBass(t, "V42", "C", []string{"F", "G", "B", "D"})
+	testBass(t, "V2", "C", []string{"F", "G", "B", "D"})
+	testBass(t, "@V65", "C", []string{"B", "D", "F", "G"})
+	testBass(t, "@II6", "C", []string{"F", "A", "D"})
+}
+
+func TestSlashChords(t *testing.T) {
+	testBass(t, "F/Ab", "C", []string{"Ab", "F", "A", "C"})
+	testBass(t, "Cmaj/E", "C", []string{"E", "C", "G"})
+	testBass(t, "Cmaj7/D", "C", []string{"D", "C", "E", "G", "B"})
+	testBass(t, "V7/F", "C", []string{"F", "G", "B", "D"})
+}
+
+func TestBadInversions(t *testing.T) {
+	testBadAbs(t, "Cmaj/3")
+	testBadAbs(t, "Cmaj/0")
+	testBadAbs(t, "Cmaj/E/G")
+	testBadAbs(t, "Cmaj/")
+	testBadAbs(t, "I6/E")
+	testBadAbs(t, "C5/2")
+}
@@ -171,8 +171,22 @@ func (p *Parser) parseDiatonicChord() (*diatonicChordExpr, error) {
 	}
 	p.next()
 
-	// We now expect a list of qualities.
-	qualities, err := p.parseAdditionalQualities()
+	// We may have figured bass, e.g. @I6, then a list of qualities.
+	// Figures for a seventh chord, e.g. @V65, add the seventh from the scale.
+	qualities := []*qualityExpr{}
+	inversion, figured := figuredBass(p.tok, p.val)
+	if figured != nil {
+		if figured.quality != INVALID {
+			qualities = append(qualities, &qualityExpr{quality: ADD, interval: 7})
+		}
+		p.next()
+	}
+	additional, err := p.parseAdditionalQualities()
+	if err != nil {
+		return nil, err
+	}
+	qualities = append(qualities, additional...)
+	bass, err := p.parseBass(inversion)
 	if err != nil {
 		return nil, err
 	}
@@ -180,6 +194,7 @@ func (p *Parser) parseDiatonicChord() (*diatonicChordExpr, error) {
 	return &diatonicChordExpr{
 		rootScaleDegree: rootDegree,
 		qualities:       qualities,
+		bass:            bass,
 	}, nil
 }
 
@@ -211,40 +226,110 @@ func (p *Parser) parseRelativeChord() (*relativeChordExpr, error) {
 	// this by saying that diminished just affects the 5th and MAJ/MIN doesn't?
 	// actually I think that's correct, that's the only way.
 
+	qualities := []*qualityExpr{}
+	qualities = append(qualities, &qualityExpr{quality: quality, interval: 0, implied: true})
+
+	// Roman numerals can have figured bass, e.g. V65 for a seventh chord in first inversion.
+	inversion, figured := figuredBass(p.tok, p.val)
+	if figured != nil {
+		if figured.quality != INVALID {
+			qualities = append(qualities, figured)
+		}
+		p.next()
+	}
+
 	// Then we expect zero or more additional qualities.
 	// (This advances the parser for us, no need for p.next() after.)
 	additional, err := p.parseAdditionalQualities()
 	if err != nil {
 		return nil, err
 	}
-
-	qualities := []*qualityExpr{}
-	qualities = append(qualities, &qualityExpr{quality: quality, interval: 0, implied: true})
 	qualities = append(qualities, additional...)
 
+	bass, err := p.parseBass(inversion)
+	if err != nil {
+		return nil, err
+	}
+
 	return &relativeChordExpr{
 		rootScaleDegree: rootDegree,
 		accidental:      accidental,
 		qualities:       qualities,
+		bass:            bass,
 	}, nil
 }
 
-// Parse a list of qualities.
+// Parse a list of qualities, up to the end of the chord or the slash before its bass.
 func (p *Parser) parseAdditionalQualities() ([]*qualityExpr, error) {
 	p.trace("Parsing additional qualities.")
 	qualities := []*qualityExpr{}
-	for p.tok != EOF {
+	for p.tok != EOF && p.tok != SLASH {
 		p.trace("Parsing a quality, because token isn't EOF, it's:", p.tok)
 		q, err := p.parseQuality()
 		if err != nil {
 			return nil, err
 		}
 		qualities = append(qualities, q)
-		p.next()
 	}
 	return qualities, nil
 }
 
+// figuredBass reads figured bass after a Roman numeral, e.g. the 6 in I6, and gets the
+// inversion it stands for. Figures for seventh chords (65, 43, 42) also give the seventh as
+// a quality; the others give a quality of INVALID. It returns nil if there are no figures.
+func figuredBass(tok Token, val string) (int, *qualityExpr) {
+	if tok != NUMBER {
+		return 0, nil
+	}
+	switch val {
+	case "6":
+		return 1, &qualityExpr{quality: INVALID}
+	case "64":
+		return 2, &qualityExpr{quality: INVALID}
+	case "65":
+		return 1, &qualityExpr{quality: DOMINANT, interval: 7}
+	case "43":
+		return 2, &qualityExpr{quality: DOMINANT, interval: 7}
+	case "42", "2":
+		return 3, &qualityExpr{quality: DOMINANT, interval: 7}
+	}
+	return 0, nil
+}
+
+// Parse what goes on the bottom of the chord, after a slash: an inversion (e.g. Cmaj/1) or a
+// bass note (e.g. F/Ab). Without a slash, it's the given inversion (from figured bass, if any.)
+func (p *Parser) parseBass(inversion int) (bassExpr, error) {
+	bass := bassExpr{inversion: inversion, pitch: types.NoPitch()}
+	if p.tok != SLASH {
+		return bass, nil
+	}
+	if inversion != 0 {
+		return bass, fmt.Errorf("a chord with figured bass can't have a slash too")
+	}
+	p.next()
+	switch p.tok {
+	case NUMBER:
+		n, err := strconv.Atoi(p.val)
+		if err != nil || n < 1 {
+			return bass, fmt.Errorf("bad inversion: '%v'", p.val)
+		}
+		bass.inversion = n
+	case PITCH:
+		pitch, err := types.LookUpPitch(p.val)
+		if err != nil {
+			return bass, err
+		}
+		bass.pitch = pitch
+	default:
+		return bass, fmt.Errorf("expected an inversion or a bass note after the slash, got %v", p.tok)
+	}
+	p.next()
+	if p.tok != EOF {
+		return bass, fmt.Errorf("expected the end of the chord after its bass, got %v", p.tok)
+	}
+	return bass, nil
+}
+
 func (p *Parser) parseAbsoluteChord() (*absoluteChordExpr, error) {
 	p.trace("Parsing an absolute chord.")
 
@@ -255,12 +340,14 @@ func (p *Parser) parseAbsoluteChord() (*absoluteChordExpr, error) {
 	}
 	p.next()
 
-	// For now, we expect at least one quality.
-	quality, err := p.parseQuality()
-	if err != nil {
-		return nil, err
+	// For now, we expect at least one quality, except in a slash chord like F/Ab, which is major.
+	quality := &qualityExpr{quality: MAJ}
+	if p.tok != SLASH {
+		quality, err = p.parseQuality()
+		if err != nil {
+			return nil, err
+		}
 	}
-	p.next()
 
 	// Then we expect zero or more additional qualities.
 	// (This advances the parser for us, no need for p.next() after.)
@@ -273,10 +360,16 @@ func (p *Parser) parseAbsoluteChord() (*absoluteChordExpr, error) {
 	qualities = append(qualities, quality)
 	qualities = append(qualities, additional...)
 
+	bass, err := p.parseBass(0)
+	if err != nil {
+		return nil, err
+	}
+
 	p.trace("Parsed an absolute chord.")
 	return &absoluteChordExpr{
 		pitch:     rootPitch,
 		qualities: qualities,
+		bass:      bass,
 	}, nil
 }
 
@@ -307,22 +400,25 @@ func (p *Parser) parseQuality() (*qualityExpr, error) {
 
 	// Certain numbers here can be read as a chord quality (e.g. C7, D13, for dominant, E5 for a power chord)
 	if p.tok == NUMBER {
+		var q *qualityExpr
 		switch p.val {
 		case "5":
-			return &qualityExpr{quality: POWER, interval: 5}, nil
+			q = &qualityExpr{quality: POWER, interval: 5}
 		case "6":
-			return &qualityExpr{quality: MAJ, interval: 6}, nil
+			q = &qualityExpr{quality: MAJ, interval: 6}
 		case "7":
-			return &qualityExpr{quality: DOMINANT, interval: 7}, nil
+			q = &qualityExpr{quality: DOMINANT, interval: 7}
 		case "9":
-			return &qualityExpr{quality: DOMINANT, interval: 9}, nil
+			q = &qualityExpr{quality: DOMINANT, interval: 9}
 		case "11":
-			return &qualityExpr{quality: DOMINANT, interval: 11}, nil
+			q = &qualityExpr{quality: DOMINANT, interval: 11}
 		case "13":
-			return &qualityExpr{quality: DOMINANT, interval: 13}, nil
+			q = &qualityExpr{quality: DOMINANT, interval: 13}
 		default:
 			return nil, fmt.Errorf("%v is not a chord quality", p.val)
 		}
+		p.next()
+		return q, nil
 	}
 	var quality Token
 	switch p.tok {
@@ -343,6 +439,7 @@ func (p *Parser) parseQuality() (*qualityExpr, error) {
 				return nil, fmt.Errorf("bad number format: '%v'", p.val)
 			}
 			p.trace("quality has a number; got", p.tok, p.val)
+			p.next()
 			return &qualityExpr{quality: quality, interval: int(num)}, nil
 		} else {
 			p.trace("next token wasn't a number; got", p.tok, p.val)
@@ -352,5 +449,6 @@ func (p *Parser) parseQuality() (*qualityExpr, error) {
 			return &qualityExpr{quality: quality, interval: 0}, nil
 		}
 	}
+	p.next()
 	return &qualityExpr{quality: quality, interval: 0}, nil
 }
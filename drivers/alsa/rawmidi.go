@@ -1,5 +1,5 @@
 // rawmidi.go implements an Abstract driver for the ALSA "rawmidi" API.
-// It keeps time itself, using a Go time.Ticker.
+// It keeps time itself, sending each message when it's due with a drivers.Scheduler.
 package alsa
 
 import (
@@ -8,10 +8,9 @@ import (
 	"github.com/edemond/abstract/types"
 	"github.com/edemond/midi"
 	"fmt"
+	"math/rand"
 	"os"
 	"os/signal"
-	"sync"
-	"time"
 )
 
 var instrumentID int = 0
@@ -33,27 +32,6 @@ func NewRawMidiDriver() (drivers.Driver, error) {
 	}, nil
 }
 
-func timer(d time.Duration) *time.Ticker {
-	return time.NewTicker(d)
-}
-
-func swingTimer(d1 time.Duration, d2 time.Duration, cond *sync.Cond) {
-	ticker1 := time.NewTicker(d1)
-	ticker2 := time.NewTicker(d1 + d2)
-	for {
-		<-ticker1.C
-		cond.Broadcast()
-		<-ticker2.C
-		cond.Broadcast()
-	}
-}
-
-// Returns a time.Duration representing how long we should wait for each tick at the given BPM.
-func bpmToDuration(bpm int, ticksPerBeat int) time.Duration {
-	oneBeat := (time.Minute / time.Duration(bpm)) // duration of one beat
-	return oneBeat / time.Duration(ticksPerBeat)  // duration of one tick
-}
-
 func stopAll(insts map[int]midi.Device) {
 	// Send Controller Change 123 ("All notes off")
 	// TODO: Also send All Sound Off (120)?
@@ -71,8 +49,10 @@ func playNote(m *msg.Message, device midi.Device) {
 		device.NoteOff(note.Channel, note.Data1, note.Data2)
 	case 0x9:
 		device.NoteOn(note.Channel, note.Data1, note.Data2)
-	case 0xC:
+	case 0xB:
 		device.ControllerChange(note.Channel, note.Data1, note.Data2)
+	case 0xC:
+		device.ProgramChange(note.Channel, note.Data1)
 	}
 }
 
@@ -116,52 +96,16 @@ func (r *rawMidiDriver) Close() error {
 	return nil
 }
 
-func (r *rawMidiDriver) Play(part types.Part, bpm int, ppq int, loop bool, polyphony int) error {
-	// Use a buffer big enough to handle all the notes that might be playing at once.
-	buf, err := msg.NewBuffer(polyphony)
-	if err != nil {
-		return err
-	}
-
+func (r *rawMidiDriver) Play(part types.Part, tempo *types.TempoMap, ppq int, loop bool, polyphony int, rnd *rand.Rand) error {
 	// Handle SIGINT and SIGKILL so we can cut off any notes that are still ringing.
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, os.Interrupt, os.Kill)
-
-	ticker := timer(bpmToDuration(bpm, ppq))
+	defer signal.Stop(signals)
 	defer stopAll(r.openDevices)
 
-	var steps uint64
-	for {
-		length := part.Length(ppq)
-		for step := uint64(0); step < length; step++ {
-			part.Play(buf, ppq, step)
-			select {
-			case <-ticker.C:
-				if buf.Any() {
-					last := buf.Last()
-					for i := 0; i < buf.LastLength(); i++ {
-						m := last[i]
-						note := m.MidiMessage
-						device := r.openDevices[m.Instrument]
-						device.NoteOff(note.Channel, note.Data1, note.Data2)
-					}
-					next := buf.Next()
-					for i := 0; i < buf.NextLength(); i++ {
-						m := next[i]
-						playNote(m, r.openDevices[m.Instrument])
-					}
-				}
-				buf.Flip()
-			case <-signals:
-				return nil
-			}
+	return drivers.NewScheduler().Play(part, tempo, ppq, loop, polyphony, rnd, signals, func(m *msg.Message) {
+		if device, ok := r.openDevices[m.Instrument]; ok {
+			playNote(m, device)
 		}
-		if !loop {
-			return nil
-		}
-		fmt.Println("Looping.")
-		steps += part.Length(ppq)
-	}
-
-	return nil
+	})
 }
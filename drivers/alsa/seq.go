@@ -0,0 +1,300 @@
+// seq.go implements an Abstract driver for the ALSA sequencer API.
+// Each instrument is a virtual port (e.g. "abstract:piano") that softsynths or hardware
+// can be connected to with aconnect. Instead of sending notes as a Go timer ticks, it queues
+// them a little ahead of time on an ALSA queue, timestamped for when they should sound.
+package alsa
+
+// #cgo LDFLAGS: -lasound
+// #include <stdlib.h>
+// #include <alsa/asoundlib.h>
+/*
+// Most of the snd_seq_ev_* API is macros, which Go can't call, so events are made here.
+// command: High nibble of the status byte (e.g. 0x8 note off, 0x9 note on).
+// channel: MIDI channel 0-15.
+// direct: If not 0, the event goes out right away instead of being queued for sec + nsec.
+static int output_event(snd_seq_t* seq, int queue, int port,
+    unsigned int sec, unsigned int nsec, int direct,
+    unsigned char command, unsigned char channel,
+    unsigned char data1, unsigned char data2) {
+    snd_seq_event_t ev;
+    snd_seq_ev_clear(&ev);
+    snd_seq_ev_set_source(&ev, port);
+    snd_seq_ev_set_subs(&ev);
+    if (direct) {
+        snd_seq_ev_set_direct(&ev);
+    } else {
+        snd_seq_real_time_t rtime = { sec, nsec };
+        snd_seq_ev_schedule_real(&ev, queue, 0, &rtime);
+    }
+    switch (command) {
+    case 0x8:
+        snd_seq_ev_set_noteoff(&ev, channel, data1, data2);
+        break;
+    case 0x9:
+        snd_seq_ev_set_noteon(&ev, channel, data1, data2);
+        break;
+    case 0xB:
+        snd_seq_ev_set_controller(&ev, channel, data1, data2);
+        break;
+    case 0xC:
+        snd_seq_ev_set_pgmchange(&ev, channel, data1);
+        break;
+    default:
+        return 0; // Nothing else is sent.
+    }
+    return snd_seq_event_output(seq, &ev);
+}
+*/
+import "C"
+
+import (
+	"github.com/edemond/abstract/drivers"
+	"github.com/edemond/abstract/msg"
+	"github.com/edemond/abstract/types"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/signal"
+	"time"
+	"unsafe"
+)
+
+// How far ahead of the queue to queue events. Enough to ride out the Go scheduler and
+// garbage collector, not so much that the queue fills up.
+const lookahead = 200 * time.Millisecond
+
+type seqDriver struct {
+	seq    *C.snd_seq_t
+	client int
+	queue  C.int
+	ports  map[int]C.int // Instrument ID -> port
+}
+
+// seqError makes an error from an ALSA error code.
+func seqError(what string, result C.int) error {
+	return fmt.Errorf("%v: %v", what, C.GoString(C.snd_strerror(result)))
+}
+
+// Create and initialize the ALSA sequencer driver.
+func NewSeqDriver() (drivers.Driver, error) {
+	name := C.CString("abstract")
+	defer C.free(unsafe.Pointer(name))
+	device := C.CString("default")
+	defer C.free(unsafe.Pointer(device))
+
+	var seq *C.snd_seq_t
+	result := C.snd_seq_open(&seq, device, C.SND_SEQ_OPEN_OUTPUT, 0)
+	if result < 0 {
+		return nil, seqError("Couldn't open ALSA sequencer", result)
+	}
+	C.snd_seq_set_client_name(seq, name)
+
+	queue := C.snd_seq_alloc_named_queue(seq, name)
+	if queue < 0 {
+		C.snd_seq_close(seq)
+		return nil, seqError("Couldn't allocate ALSA sequencer queue", queue)
+	}
+
+	return &seqDriver{
+		seq:    seq,
+		client: int(C.snd_seq_client_id(seq)),
+		queue:  queue,
+		ports:  make(map[int]C.int),
+	}, nil
+}
+
+func (s *seqDriver) OpenInstrument(name string) (int, error) {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+
+	port := C.snd_seq_create_simple_port(
+		s.seq,
+		cname,
+		C.SND_SEQ_PORT_CAP_READ|C.SND_SEQ_PORT_CAP_SUBS_READ,
+		C.SND_SEQ_PORT_TYPE_MIDI_GENERIC|C.SND_SEQ_PORT_TYPE_APPLICATION,
+	)
+	if port < 0 {
+		return -1, seqError(fmt.Sprintf("Couldn't create ALSA sequencer port '%v'", name), port)
+	}
+
+	id := instrumentID
+	s.ports[id] = port
+	fmt.Printf("Created ALSA sequencer port 'abstract:%v' (%v:%v). Connect it with aconnect.\n", name, s.client, port)
+	instrumentID += 1
+	return id, nil
+}
+
+func (s *seqDriver) CloseInstrument(id int) error {
+	port, ok := s.ports[id]
+	if !ok {
+		panic(fmt.Sprintf("Internal error: Couldn't close instrument; no instrument with ID %v is open", id))
+	}
+	result := C.snd_seq_delete_simple_port(s.seq, port)
+	if result < 0 {
+		return seqError("Couldn't delete ALSA sequencer port", result)
+	}
+	delete(s.ports, id)
+	return nil
+}
+
+func (s *seqDriver) Close() error {
+	for id := range s.ports {
+		err := s.CloseInstrument(id)
+		if err != nil {
+			fmt.Printf("Error closing instrument %v: %v\n", id, err)
+		}
+	}
+	C.snd_seq_free_queue(s.seq, s.queue)
+	result := C.snd_seq_close(s.seq)
+	if result < 0 {
+		return seqError("Couldn't close ALSA sequencer", result)
+	}
+	s.seq = nil
+	return nil
+}
+
+// output queues a message to go out at the given time since the queue started.
+func (s *seqDriver) output(m *msg.Message, at time.Duration) error {
+	port, ok := s.ports[m.Instrument]
+	if !ok {
+		return nil // Parts without an instrument still send messages, but there's nowhere to send them.
+	}
+	if at < 0 {
+		at = 0
+	}
+	note := m.MidiMessage
+	result := C.output_event(
+		s.seq,
+		s.queue,
+		port,
+		C.uint(at/time.Second),
+		C.uint(at%time.Second),
+		0,
+		C.uchar(note.Command),
+		C.uchar((note.Channel-1)&0x0F),
+		C.uchar(note.Data1),
+		C.uchar(note.Data2),
+	)
+	if result < 0 {
+		return seqError("Couldn't queue ALSA sequencer event", result)
+	}
+	return nil
+}
+
+// noteOffs queues note offs for the notes that are done at a step.
+func (s *seqDriver) noteOffs(buf msg.Buffer, step uint64, due func(step uint64) time.Duration) error {
+	return drivers.NoteOffs(buf, step, due, func(off *msg.Message) error {
+		return s.output(off, due(step)+off.HumanizeTime)
+	})
+}
+
+// step queues the messages for a step.
+func (s *seqDriver) step(buf msg.Buffer, step uint64, due func(step uint64) time.Duration) error {
+	if !buf.Any() {
+		return nil
+	}
+	if err := s.noteOffs(buf, step, due); err != nil {
+		return err
+	}
+
+	next := buf.Next()
+	for i := 0; i < buf.NextLength(); i++ {
+		if err := s.output(next[i], due(step)+next[i].HumanizeTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stopAll drops everything still queued and sends "All notes off" (controller 123) on every
+// channel of every port, right away.
+func (s *seqDriver) stopAll() {
+	C.snd_seq_drop_output(s.seq)
+	for _, port := range s.ports {
+		for channel := 0; channel < 16; channel++ {
+			C.output_event(s.seq, s.queue, port, 0, 0, 1, 0xB, C.uchar(channel), 123, 0)
+		}
+	}
+	C.snd_seq_drain_output(s.seq)
+}
+
+func (s *seqDriver) Play(part types.Part, tempo *types.TempoMap, ppq int, loop bool, polyphony int, rnd *rand.Rand) error {
+	if err := drivers.CheckLoop(part, ppq, loop); err != nil {
+		return err
+	}
+
+	// Use a buffer big enough to handle all the notes that might be playing at once.
+	buf, err := msg.NewBuffer(polyphony)
+	if err != nil {
+		return err
+	}
+	defer drivers.ReportOverflows(buf)
+
+	// Handle SIGINT and SIGKILL so we can cut off any notes that are still ringing.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, os.Kill)
+	defer signal.Stop(signals)
+
+	result := C.snd_seq_control_queue(s.seq, s.queue, C.SND_SEQ_EVENT_START, 0, nil)
+	if result < 0 {
+		return seqError("Couldn't start ALSA sequencer queue", result)
+	}
+	C.snd_seq_drain_output(s.seq)
+	started := time.Now()
+	defer func() {
+		C.snd_seq_control_queue(s.seq, s.queue, C.SND_SEQ_EVENT_STOP, 0, nil)
+		C.snd_seq_drain_output(s.seq)
+	}()
+
+	due := tempo.Time
+	// wait waits until it's time to queue the given step, and reports whether we were interrupted.
+	wait := func(step uint64) bool {
+		d := time.Until(started.Add(due(step) - lookahead))
+		if d <= 0 {
+			return false
+		}
+		select {
+		case <-time.After(d):
+			return false
+		case <-signals:
+			s.stopAll()
+			return true
+		}
+	}
+
+	var steps uint64
+	for {
+		// A piece that takes no time (e.g. only pc and cc) still plays step 0, to send its messages.
+		length := part.Length(ppq)
+		for step := uint64(0); step < length || step == 0; step++ {
+			part.Play(buf, rnd, ppq, step)
+			if wait(steps + step) {
+				return nil
+			}
+			if err := s.step(buf, steps+step, due); err != nil {
+				return err
+			}
+			C.snd_seq_drain_output(s.seq)
+			buf.Flip()
+		}
+		steps += length
+		if !loop {
+			// Stop whatever's still sounding, then let the queue play out.
+			buf.Release()
+			if err := s.noteOffs(buf, steps, due); err != nil {
+				return err
+			}
+			C.snd_seq_drain_output(s.seq)
+			if wait(steps) {
+				return nil
+			}
+			select {
+			case <-time.After(lookahead):
+			case <-signals:
+				s.stopAll()
+			}
+			return nil
+		}
+		fmt.Println("Looping.")
+	}
+}
@@ -1,13 +1,19 @@
 package drivers
 
 import (
+	"github.com/edemond/abstract/msg"
 	"github.com/edemond/abstract/types"
+	"fmt"
+	"math/rand"
+	"time"
 )
 
 type Driver interface {
 	// Play the piece from the given root part.
+	// tempo: How fast each step goes, and when it falls (see types.NewTempoMap).
 	// polyphony: The maximum number of voices that might be playing at once.
-	Play(part types.Part, bpm int, ppq int, loop bool, polyphony int) error
+	// rnd: The source of all randomness during playback.
+	Play(part types.Part, tempo *types.TempoMap, ppq int, loop bool, polyphony int, rnd *rand.Rand) error
 
 	// Open an instrument for playback. Returns an instrument ID.
 	OpenInstrument(name string) (int, error)
@@ -18,3 +24,50 @@ type Driver interface {
 	// Close the driver.
 	Close() error
 }
+
+// CheckLoop makes sure a piece that's going to loop takes some time. One that doesn't
+// (e.g. a block of only pc() and cc()) would go around forever without waiting.
+func CheckLoop(part types.Part, ppq int, loop bool) error {
+	if loop && part.Length(ppq) == 0 {
+		return fmt.Errorf("can't loop a piece that takes no time")
+	}
+	return nil
+}
+
+// ReportOverflows warns about the notes that didn't fit in the buffer, if there were any.
+func ReportOverflows(buf msg.Buffer) {
+	if n := buf.Overflows(); n > 0 {
+		fmt.Printf("Warning: %v notes were dropped or cut short because the note buffer was full.\n", n)
+	}
+}
+
+// NoteOffs calls off with a note off for every note on that's done at this step, its
+// HumanizeTime saying how far from the step it goes out. That's with the earliest message of
+// the step, which could be humanized early, so a key struck again isn't cut off; but never
+// before its own note on, which could have been humanized late. due gets when a step falls.
+func NoteOffs(buf msg.Buffer, step uint64, due func(step uint64) time.Duration, off func(m *msg.Message) error) error {
+	var earliest time.Duration
+	next := buf.Next()
+	for i := 0; i < buf.NextLength(); i++ {
+		if i == 0 || next[i].HumanizeTime < earliest {
+			earliest = next[i].HumanizeTime
+		}
+	}
+
+	last := buf.Last()
+	for i := 0; i < buf.LastLength(); i++ {
+		if !last[i].IsNoteOn() {
+			continue // Only note ons need to be turned off.
+		}
+		m := *last[i]
+		m.MidiMessage.Command = 0x8
+		m.HumanizeTime = earliest
+		if on := due(m.Step) + last[i].HumanizeTime - due(step); m.HumanizeTime < on {
+			m.HumanizeTime = on
+		}
+		if err := off(&m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+// Package dump implements an Abstract driver that writes out every message of a piece as a
+// line of text instead of playing it, for debugging and for golden tests. The format is
+// stable: one message per line, in the order they'd be sent, e.g.
+//
+//	step=96 beat=1.500 inst="piano" ch=1 on note=60 vel=100
+//
+// Messages that humanize moves off their step end with how far, e.g. "human=-3.25ms".
+// Changes of tempo get a line of their own, rounded to a whole BPM, e.g.
+//
+//	step=512 beat=8.000 tempo bpm=96
+package dump
+
+import (
+	"github.com/edemond/abstract/drivers"
+	"github.com/edemond/abstract/msg"
+	"github.com/edemond/abstract/types"
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+)
+
+// Unique Instrument ID to be incremented each time we assign one.
+var instrumentID int
+
+type dumpDriver struct {
+	w           io.Writer
+	instruments map[int]string // Instrument ID -> name
+}
+
+// NewDumpDriver creates a driver that writes the piece to w when played.
+func NewDumpDriver(w io.Writer) drivers.Driver {
+	return &dumpDriver{
+		w:           w,
+		instruments: make(map[int]string),
+	}
+}
+
+func (d *dumpDriver) OpenInstrument(name string) (int, error) {
+	id := instrumentID
+	d.instruments[id] = name
+	instrumentID += 1
+	return id, nil
+}
+
+func (d *dumpDriver) CloseInstrument(id int) error {
+	_, ok := d.instruments[id]
+	if !ok {
+		panic(fmt.Sprintf("Internal error: Couldn't close instrument; no instrument with ID %v is open", id))
+	}
+	delete(d.instruments, id)
+	return nil
+}
+
+// Close closes the file being written to, if there is one.
+func (d *dumpDriver) Close() error {
+	if c, ok := d.w.(io.Closer); ok && d.w != os.Stdout {
+		return c.Close()
+	}
+	return nil
+}
+
+// Write the piece out once through. Looping makes no sense here.
+func (d *dumpDriver) Play(part types.Part, tempo *types.TempoMap, ppq int, loop bool, polyphony int, rnd *rand.Rand) error {
+	w := bufio.NewWriter(d.w)
+	bpm := math.Round(tempo.BPM(0))
+	fmt.Fprintf(w, "# bpm=%v ppq=%v\n", bpm, ppq)
+
+	// changes writes the tempo changes up to and including the given step, in with the messages.
+	length := part.Length(ppq)
+	var next uint64 // The next step to look for a tempo change on.
+	changes := func(step uint64) {
+		for ; next <= step && next < length; next++ {
+			if b := math.Round(tempo.BPM(next)); b != bpm {
+				fmt.Fprintf(w, "step=%v beat=%.3f tempo bpm=%v\n", next, float64(next)/float64(ppq), b)
+				bpm = b
+			}
+		}
+	}
+
+	err := drivers.Render(part, tempo, ppq, polyphony, rnd, func(step uint64, m *msg.Message) {
+		changes(step)
+		fmt.Fprintf(w, "step=%v beat=%.3f inst=%q ch=%v %v",
+			step,
+			float64(step)/float64(ppq),
+			d.name(m.Instrument),
+			m.MidiMessage.Channel,
+			event(m),
+		)
+		if m.HumanizeTime != 0 {
+			fmt.Fprintf(w, " human=%v", m.HumanizeTime)
+		}
+		fmt.Fprintln(w)
+	})
+	if err != nil {
+		return err
+	}
+
+	changes(length)
+	fmt.Fprintf(w, "# end step=%v\n", length)
+	return w.Flush()
+}
+
+// name gets the name of an instrument. Parts without an instrument still send messages.
+func (d *dumpDriver) name(id int) string {
+	name, ok := d.instruments[id]
+	if !ok {
+		return "(no instrument)"
+	}
+	return name
+}
+
+// event describes what a message does, e.g. "on note=60 vel=100" or "pc program=5".
+func event(m *msg.Message) string {
+	mm := m.MidiMessage
+	switch mm.Command {
+	case 0x8:
+		return fmt.Sprintf("off note=%v vel=%v", mm.Data1, mm.Data2)
+	case 0x9:
+		return fmt.Sprintf("on note=%v vel=%v", mm.Data1, mm.Data2)
+	case 0xB:
+		return fmt.Sprintf("cc controller=%v value=%v", mm.Data1, mm.Data2)
+	case 0xC:
+		return fmt.Sprintf("pc program=%v", mm.Data1)
+	}
+	return fmt.Sprintf("midi command=%x data1=%v data2=%v", mm.Command, mm.Data1, mm.Data2)
+}
@@ -0,0 +1,80 @@
+package dump
+
+import (
+	"github.com/edemond/abstract/types"
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDump(t *testing.T) {
+	var out bytes.Buffer
+	d := NewDumpDriver(&out)
+	id, err := d.OpenInstrument("piano")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	part := types.NewSimplePart()
+	part.Rhythm.Meter = &types.Meter{Beats: 4, Value: 4}
+	part.Rhythm.Dynamics = types.NewDynamics(100)
+	part.Rhythm.Gate = types.DefaultGate()
+	part.Instrument = &types.Instrument{ID: id, Channel: 1}
+	pitch, err := types.LookUpPitch("C")
+	if err != nil {
+		t.Fatalf("Error in test: %v", err)
+	}
+	part.Harmony.Pitch = pitch
+
+	err = d.Play(part, types.NewTempoMap(part, 120, 4), 4, false, 16, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# bpm=120 ppq=4
+step=0 beat=0.000 inst="piano" ch=1 on note=48 vel=100
+step=14 beat=3.500 inst="piano" ch=1 off note=48 vel=100
+# end step=16
+`
+	if out.String() != expected {
+		t.Fatalf("expected:\n%v\ngot:\n%v", expected, out.String())
+	}
+}
+
+func TestDumpHumanize(t *testing.T) {
+	var out bytes.Buffer
+	d := NewDumpDriver(&out)
+	id, err := d.OpenInstrument("piano")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	part := types.NewSimplePart()
+	part.Rhythm.Meter = &types.Meter{Beats: 4, Value: 4}
+	part.Rhythm.Humanize, err = types.NewHumanize(10)
+	if err != nil {
+		t.Fatalf("Error in test: %v", err)
+	}
+	part.Instrument = &types.Instrument{ID: id, Channel: 1}
+
+	err = d.Play(part, types.NewTempoMap(part, 120, 4), 4, false, 16, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(out.String(), "\n")
+	fields := strings.Fields(lines[1])
+	last := fields[len(fields)-1]
+	if !strings.HasPrefix(last, "human=") {
+		t.Fatalf("expected the note on to say how it's humanized, got %q", lines[1])
+	}
+	human, err := time.ParseDuration(strings.TrimPrefix(last, "human="))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if human < -10*time.Millisecond || human > 10*time.Millisecond {
+		t.Errorf("expected the note on to be humanized by at most 10ms, got %v", human)
+	}
+}
@@ -18,10 +18,13 @@ static jack_nframes_t frames_to_burn = 8000;
 int write_midi_event(void* port_buffer, int offset, 
     unsigned char command, unsigned char channel, 
     unsigned char note, unsigned char velocity) { 
+    // Program change and channel pressure only have one data byte.
+    size_t size = (command == 0xC || command == 0xD) ? 2 : 3;
+
     jack_midi_data_t* event = jack_midi_event_reserve(
         port_buffer,
         offset, // into the buffer
-        3 // bytes to reserve
+        size // bytes to reserve
     );
 
     if (event == NULL) {
@@ -31,23 +34,15 @@ int write_midi_event(void* port_buffer, int offset,
 
     event[0] = (command << 4) | ((channel-1) & 0x0F); // note on, channel 1
     event[1] = note; // pitch
-    event[2] = velocity; // velocity
+    if (size > 2) {
+        event[2] = velocity; // velocity
+    }
 
     //printf("buffer: %p, event: %p, offset %d: %#x %#x %#x\n", port_buffer, event, offset, event[0], event[1], event[2]);
 
     return 0;
 }
 
-// Calculate the number of JACK frames for each Abstract step.
-jack_nframes_t get_frames_per_step(jack_nframes_t sample_rate, int bpm, int ppq) {
-    // TODO: This is not very precise. Could cause some drift, or some tempos
-    // to be slightly faster or slower. But we're not planning on synchronizing
-    // with anything just yet; we just want to be close for the time being.
-    double bps = bpm / 60.0;
-    jack_nframes_t samples_per_beat = sample_rate / bps; // TODO: Will eventually drift...
-    return samples_per_beat / ppq;
-}
-
 // Main JACK callback to send some audio. We pass a jack_context* as the arg.
 int process_callback(jack_nframes_t nframes, void* arg) {
     jack_context* context = (jack_context*)arg;
@@ -58,29 +53,21 @@ int process_callback(jack_nframes_t nframes, void* arg) {
     // in which MIDI notes aren't actually played if I write them before a certain
     // number of frames.
     if (start_frame >= frames_to_burn) {
+        if (context->start == 0) {
+            context->start = start_frame;
+        }
 
-        // Do all of the steps that fall in this period.
-        // A step happens on any multiple of context->frames_per_step that falls 
-        // in the range [context->frames, (context->frames + nframes)].
+        // Do all of the steps that fall in this period. Each step falls on the frame
+        // the tempo map says it does (StepFrame, in jack.go), counting from the start.
         for (;;) {
-            // Round from start_frame up to the next multiple of frames_per_step.
-            // (y - (y % x)) + x
-            jack_nframes_t next_step = 
-                (start_frame - (start_frame % context->frames_per_step)) 
-                + context->frames_per_step;
-
-            if (next_step < context->frames) {
-                printf("Internal error: We screwed up the math in the JACK process callback. Fix it!\n");
-                return -1;
-            } else if (next_step >= (context->frames + nframes)) {
+            jack_nframes_t next_step = context->start + StepFrame(context->steps);
+            if (next_step >= (context->frames + nframes)) {
                 break;
-            } else {
-                int next_step_offset = next_step - context->frames;
-                // This is the callback into Go (jack.go).
-                StepSong(context->steps, next_step_offset, nframes);
-                context->steps += 1;
-                start_frame += context->frames_per_step;
             }
+            int next_step_offset = next_step - context->frames;
+            // This is the callback into Go (jack.go).
+            StepSong(context->steps, next_step_offset, nframes);
+            context->steps += 1;
         }
     }
 
@@ -98,11 +85,9 @@ int xrun_callback() {
 
 // Start running the driver. This will block, and the process callback 
 // will be invoked, until a signal is caught.
-enum jack_driver_result run_jack_driver(jack_client_t* client, int bpm, int ppq) {
-    jack_nframes_t sample_rate = jack_get_sample_rate(client);
-
+enum jack_driver_result run_jack_driver(jack_client_t* client) {
     jack_context context;
-    context.frames_per_step = get_frames_per_step(sample_rate, bpm, ppq);
+    context.start = 0;
     context.frames = 0;
     context.steps = 0;
 
@@ -27,6 +27,8 @@ import (
 	"github.com/edemond/abstract/msg"
 	"github.com/edemond/abstract/types"
 	"fmt"
+	"math/rand"
+	"time"
 	"unsafe"
 )
 
@@ -44,12 +46,15 @@ type jackDriver struct {
 
 	// This is a bit ugly, but during playback, these fields are set so the callback
 	// can get at them:
-	buffers map[int]unsafe.Pointer // Instrument ID -> void* (output port buffer)
-	ppq     int
-	part    types.Part
-	buf     msg.Buffer // Main note buffer that the piece's Parts dump notes into.
-	length  uint64     // Total length of the piece in steps.
-	loop    bool       // Whether or not to loop.
+	buffers    map[int]unsafe.Pointer // Instrument ID -> void* (output port buffer)
+	ppq        int
+	sampleRate int // Frames per second, to turn times into frames.
+	tempo      *types.TempoMap
+	part       types.Part
+	rnd        *rand.Rand
+	buf        msg.Buffer // Main note buffer that the piece's Parts dump notes into.
+	length     uint64     // Total length of the piece in steps.
+	loop       bool       // Whether or not to loop.
 }
 
 // Unique Instrument ID to be incremented each time we assign one.
@@ -77,9 +82,9 @@ func NewJACKDriver() (drivers.Driver, error) {
 }
 
 // Get the humanized time offset, clamped to within the number of frames.
-func calculateHumanizedOffset(offset int, humanize int, nframes C.jack_nframes_t) int {
+func calculateHumanizedOffset(offset int, humanize time.Duration, nframes C.jack_nframes_t) int {
 	//fmt.Printf("humanizing: %v %v %v -> ", offset, humanize, nframes)
-	t := offset + humanize
+	t := offset + int(humanize*time.Duration(_driver.sampleRate)/time.Second)
 	if t < 0 {
 		return 0
 	} else if t >= int(nframes) {
@@ -118,21 +123,33 @@ func PrepareBuffers(nframes C.jack_nframes_t) {
 	}
 }
 
+// Gets the frame the given step falls on, counting from the first step.
+//
+//export StepFrame
+func StepFrame(step uint64) C.jack_nframes_t {
+	return C.jack_nframes_t(_driver.tempo.Time(step) * time.Duration(_driver.sampleRate) / time.Second)
+}
+
 // Returns 1 to keep playing, 0 for done.
+//
 //export StepSong
 func StepSong(step uint64, offset int, nframes C.jack_nframes_t) int {
 
 	// Signal that we're done if we're past length and we're not looping.
 	if _driver.donePlaying(step) {
+		if step == _driver.length {
+			// Right at the end: stop whatever's still sounding.
+			_driver.buf.Release()
+			writeNoteOffs(offset)
+		}
 		return 0
 	}
 
-	_driver.part.Play(_driver.buf, _driver.ppq, step) // Fill buf with notes to process.
+	_driver.part.Play(_driver.buf, _driver.rnd, _driver.ppq, step) // Fill buf with notes to process.
 
 	if _driver.buf.Any() {
 
 		noteOffOffset := offset
-		last := _driver.buf.Last()
 		next := _driver.buf.Next()
 
 		// JACK doesn't support writing events out of order, and we might have scrambled
@@ -150,19 +167,7 @@ func StepSong(step uint64, offset int, nframes C.jack_nframes_t) int {
 		// TODO: A human player would take a bit of extra time between lifting off the last
 		// note and hitting the next note! This could be an opportunity to inject more feel.
 
-		for i := 0; i < _driver.buf.LastLength(); i++ {
-			m := last[i]
-			buffer := _driver.buffers[m.Instrument]
-			note := m.MidiMessage
-			C.write_midi_event(
-				buffer,
-				C.int(noteOffOffset),
-				C.uchar(0x8),
-				C.uchar(note.Channel),
-				C.uchar(note.Data1),
-				C.uchar(note.Data2),
-			)
-		}
+		writeNoteOffs(noteOffOffset)
 
 		for i := 0; i < _driver.buf.NextLength(); i++ {
 			m := next[i]
@@ -181,11 +186,33 @@ func StepSong(step uint64, offset int, nframes C.jack_nframes_t) int {
 				fmt.Printf("Failed to write MIDI note!\n")
 			}
 		}
-		_driver.buf.Flip()
 	}
+	// Flip at every step, so the sounding notes count down.
+	_driver.buf.Flip()
 	return 1 // Keep looping.
 }
 
+// writeNoteOffs writes note offs for the notes that are done, at the given offset.
+func writeNoteOffs(offset int) {
+	last := _driver.buf.Last()
+	for i := 0; i < _driver.buf.LastLength(); i++ {
+		m := last[i]
+		buffer := _driver.buffers[m.Instrument]
+		note := m.MidiMessage
+		if note.Command != 0x9 {
+			continue // Only note ons need to be turned off.
+		}
+		C.write_midi_event(
+			buffer,
+			C.int(offset),
+			C.uchar(0x8),
+			C.uchar(note.Channel),
+			C.uchar(note.Data1),
+			C.uchar(note.Data2),
+		)
+	}
+}
+
 func (j *jackDriver) OpenInstrument(name string) (int, error) {
 	// TODO: This should also check that the name doesn't exceed the max length.
 	cname := C.CString(name)
@@ -237,22 +264,30 @@ func (j *jackDriver) Close() error {
 	return nil
 }
 
-func (j *jackDriver) Play(part types.Part, bpm int, ppq int, loop bool, polyphony int) error {
+func (j *jackDriver) Play(part types.Part, tempo *types.TempoMap, ppq int, loop bool, polyphony int, rnd *rand.Rand) error {
+	if err := drivers.CheckLoop(part, ppq, loop); err != nil {
+		return err
+	}
+
 	buf, err := msg.NewBuffer(polyphony)
 	if err != nil {
 		return err
 	}
+	defer drivers.ReportOverflows(buf)
 
 	// TODO: Would be nice not to have to rely on globals, but doesn't make sense to
 	// bounce this stuff off of C constantly, and we have to watch out for C storing
 	// Go pointers, even temporarily.
 	j.ppq = ppq
+	j.sampleRate = int(C.jack_get_sample_rate(j.client))
+	j.tempo = tempo
 	j.part = part
+	j.rnd = rnd
 	j.buf = buf
 	j.length = part.Length(ppq)
 	_driver = j
 
-	result := C.run_jack_driver(j.client, C.int(bpm), C.int(ppq))
+	result := C.run_jack_driver(j.client)
 	if int(result) != JACK_OK {
 		return fmt.Errorf("Error in JACK driver: %v", getErrorMessage(int(result)))
 	}
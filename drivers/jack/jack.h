@@ -5,7 +5,7 @@
 
 // JACK driver context for the process callback.
 typedef struct {
-    jack_nframes_t frames_per_step;
+    jack_nframes_t start; // Frame the first step fell on, or 0 before it.
     jack_nframes_t frames;
     uint64_t steps;
     jack_port_t* port;
@@ -23,7 +23,7 @@ enum jack_driver_result {
 };
 
 // Playback
-extern enum jack_driver_result run_jack_driver(jack_client_t* client, int bpm, int ppq);
+extern enum jack_driver_result run_jack_driver(jack_client_t* client);
 extern int write_midi_event(void* port_buffer, int offset, 
     unsigned char command, unsigned char channel, 
     unsigned char note, unsigned char velocity);
@@ -0,0 +1,55 @@
+package drivers
+
+import (
+	"github.com/edemond/abstract/msg"
+	"github.com/edemond/abstract/types"
+	"math/rand"
+)
+
+// Render plays the piece from the given root part once through, as fast as possible.
+// It's for drivers that write the piece out somewhere instead of keeping time themselves.
+// emit is called with every message in the order it should be sent, along with the step
+// it falls on: at each step, the note offs for the notes that are done, then the new
+// messages. Whatever is still sounding at the end is stopped after the last step.
+// Each message's HumanizeTime says how far from its step it should go out; note offs
+// go with the earliest note on of their step, but never before their own (see NoteOffs).
+func Render(part types.Part, tempo *types.TempoMap, ppq int, polyphony int, rnd *rand.Rand, emit func(step uint64, m *msg.Message)) error {
+	buf, err := msg.NewBuffer(polyphony)
+	if err != nil {
+		return err
+	}
+	defer ReportOverflows(buf)
+
+	noteOffs := func(step uint64) {
+		NoteOffs(buf, step, tempo.Time, func(m *msg.Message) error {
+			emit(step, m)
+			return nil
+		})
+	}
+
+	play := func(step uint64) {
+		part.Play(buf, rnd, ppq, step)
+		if buf.Any() {
+			noteOffs(step)
+			next := buf.Next()
+			for i := 0; i < buf.NextLength(); i++ {
+				emit(step, next[i])
+			}
+		}
+		buf.Flip()
+	}
+
+	length := part.Length(ppq)
+	for step := uint64(0); step < length; step++ {
+		play(step)
+	}
+	if length == 0 {
+		// A piece that takes no time (e.g. only pc and cc) still sends its messages, all at once.
+		play(0)
+	}
+
+	// Notes that are done right at the end are already in Last; stop the rest too.
+	buf.Release()
+	noteOffs(length)
+	return nil
+}
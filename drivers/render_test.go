@@ -0,0 +1,121 @@
+package drivers
+
+import (
+	"github.com/edemond/abstract/msg"
+	"github.com/edemond/abstract/types"
+	"github.com/edemond/midi"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// renderProb renders a bar of a 50% prob part with the given seed, one line per message.
+func renderProb(t *testing.T, seed int64) []string {
+	part := types.NewSimplePart()
+	part.Rhythm.Meter = &types.Meter{Beats: 4, Value: 4}
+	part.Interpretation = types.NewProb(0, 0, 50)
+	pitch, err := types.LookUpPitch("C")
+	if err != nil {
+		t.Fatalf("Error in test: %v", err)
+	}
+	part.Harmony.Pitch = pitch
+
+	out := []string{}
+	err = Render(part, types.NewTempoMap(part, 120, 4), 4, 16, rand.New(rand.NewSource(seed)), func(step uint64, m *msg.Message) {
+		out = append(out, fmt.Sprintf("%v %x %v", step, m.MidiMessage.Command, m.MidiMessage.Data1))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestRenderIsReproducibleWithSeed(t *testing.T) {
+	first := fmt.Sprint(renderProb(t, 42))
+	second := fmt.Sprint(renderProb(t, 42))
+	if first != second {
+		t.Fatalf("expected the same seed to render the same messages, got:\n%v\n%v", first, second)
+	}
+}
+
+// humanized is a part that plays two steps of notes, moved off their steps by the given times.
+type humanized map[uint64][]time.Duration
+
+func (h humanized) HasValue() bool {
+	return true
+}
+
+func (h humanized) String() string {
+	return "humanized"
+}
+
+func (h humanized) Length(ppq int) uint64 {
+	return 4
+}
+
+func (h humanized) Play(buf msg.Buffer, rnd *rand.Rand, ppq int, step uint64) {
+	for i, human := range h[step] {
+		m := &msg.Message{HumanizeTime: human, Length: 2}
+		m.MidiMessage.Command = 0x9
+		m.MidiMessage.Data1 = byte(60 + i)
+		buf.Add(m)
+	}
+}
+
+func TestRenderNoteOffsGoWithEarliestNoteOn(t *testing.T) {
+	part := humanized{
+		0: {-3 * time.Millisecond},
+		2: {4 * time.Millisecond, -1 * time.Millisecond},
+	}
+	out := []string{}
+	err := Render(part, types.NewTempoMap(part, 120, 4), 4, 16, rand.New(rand.NewSource(1)), func(step uint64, m *msg.Message) {
+		out = append(out, fmt.Sprintf("%v %x %v %v", step, m.MidiMessage.Command, m.MidiMessage.Data1, m.HumanizeTime))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "[0 9 60 -3ms 2 8 60 -1ms 2 9 60 4ms 2 9 61 -1ms 4 8 60 0s 4 8 61 0s]"
+	if fmt.Sprint(out) != expected {
+		t.Fatalf("expected %v, got %v", expected, out)
+	}
+}
+
+func TestRenderNoteOffsDontGoBeforeTheirNoteOns(t *testing.T) {
+	// At 600 BPM, a step is 25ms, so the note off at step 2 would go out at 35ms, before the
+	// note on humanized to 40ms.
+	part := humanized{
+		0: {40 * time.Millisecond},
+		2: {-15 * time.Millisecond},
+	}
+	out := []string{}
+	err := Render(part, types.NewTempoMap(part, 600, 4), 4, 16, rand.New(rand.NewSource(1)), func(step uint64, m *msg.Message) {
+		out = append(out, fmt.Sprintf("%v %x %v %v", step, m.MidiMessage.Command, m.MidiMessage.Data1, m.HumanizeTime))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "[0 9 60 40ms 2 8 60 -10ms 2 9 60 -15ms 4 8 60 0s]"
+	if fmt.Sprint(out) != expected {
+		t.Fatalf("expected %v, got %v", expected, out)
+	}
+}
+
+// programChange is a piece that takes no time: a program change on its own.
+func programChange() types.Part {
+	return &types.MIDIMessagePart{Messages: []midi.Message{{Command: 0xC, Channel: 1, Data1: 5}}}
+}
+
+func TestRenderSendsAPieceThatTakesNoTime(t *testing.T) {
+	part := programChange()
+	out := []string{}
+	err := Render(part, types.NewTempoMap(part, 120, 4), 4, 16, rand.New(rand.NewSource(1)), func(step uint64, m *msg.Message) {
+		out = append(out, fmt.Sprintf("%v %x %v", step, m.MidiMessage.Command, m.MidiMessage.Data1))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(out) != "[0 c 5]" {
+		t.Fatalf("expected the program change at step 0, got %v", out)
+	}
+}
@@ -0,0 +1,190 @@
+package drivers
+
+import (
+	"github.com/edemond/abstract/msg"
+	"github.com/edemond/abstract/types"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+)
+
+// How far ahead of time a Scheduler works out the steps to play, so that a step that's slow
+// to work out is done before it's due.
+const DefaultLookahead = 50 * time.Millisecond
+
+// How late a message can go out before a Scheduler counts it as late.
+const DefaultTolerance = 2 * time.Millisecond
+
+// Scheduler plays a piece in real time, for drivers that have to send each message at the
+// moment it should sound (e.g. rawmidi). Every step is due at a time counted from when
+// playback started, rather than a tick after the step before, so a slow step doesn't push
+// back everything after it. Steps are worked out up to Lookahead ahead of when they're due
+// and held in a queue until then, and messages that still go out late are counted, to be
+// reported once playback is over rather than from the middle of it.
+type Scheduler struct {
+	Lookahead time.Duration
+	Tolerance time.Duration
+
+	Late  int           // How many messages went out late.
+	Worst time.Duration // The latest a message went out.
+
+	// The clock; swapped out by the tests.
+	now   func() time.Time
+	after func(d time.Duration) <-chan time.Time
+}
+
+// timed is a message waiting in the queue, due at the given time since playback started.
+type timed struct {
+	at time.Duration
+	m  msg.Message
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		Lookahead: DefaultLookahead,
+		Tolerance: DefaultTolerance,
+		now:       time.Now,
+		after:     time.After,
+	}
+}
+
+// add puts a message in the queue, after anything due at the same time or earlier.
+// Nothing can go out before playback starts, however early it's humanized.
+func add(queue []timed, t timed) []timed {
+	if t.at < 0 {
+		t.at = 0
+	}
+	i := len(queue)
+	for i > 0 && queue[i-1].at > t.at {
+		i--
+	}
+	queue = append(queue, timed{})
+	copy(queue[i+1:], queue[i:])
+	queue[i] = t
+	return queue
+}
+
+// queueOffs queues note offs for the notes that are done at a step.
+func queueOffs(queue []timed, buf msg.Buffer, step uint64, due func(step uint64) time.Duration) []timed {
+	NoteOffs(buf, step, due, func(m *msg.Message) error {
+		queue = add(queue, timed{due(step) + m.HumanizeTime, *m})
+		return nil
+	})
+	return queue
+}
+
+// queueStep queues the messages for a step.
+func queueStep(queue []timed, buf msg.Buffer, step uint64, due func(step uint64) time.Duration) []timed {
+	if !buf.Any() {
+		return queue
+	}
+	queue = queueOffs(queue, buf, step, due)
+
+	next := buf.Next()
+	for i := 0; i < buf.NextLength(); i++ {
+		queue = add(queue, timed{due(step) + next[i].HumanizeTime, *next[i]})
+	}
+	return queue
+}
+
+// Play plays the piece from the given root part, calling send with each message when it's due.
+// It stops early, without stopping the notes still sounding, when something comes in on stop.
+func (s *Scheduler) Play(part types.Part, tempo *types.TempoMap, ppq int, loop bool, polyphony int, rnd *rand.Rand,
+	stop <-chan os.Signal, send func(m *msg.Message)) error {
+	if err := CheckLoop(part, ppq, loop); err != nil {
+		return err
+	}
+
+	// Use a buffer big enough to handle all the notes that might be playing at once.
+	buf, err := msg.NewBuffer(polyphony)
+	if err != nil {
+		return err
+	}
+	defer ReportOverflows(buf)
+
+	due := tempo.Time
+	start := s.now()
+	elapsed := func() time.Duration {
+		return s.now().Sub(start)
+	}
+	defer func() {
+		if s.Late > 0 {
+			fmt.Printf("Warning: %v messages went out late, by up to %v.\n", s.Late, s.Worst)
+		}
+	}()
+
+	queue := []timed{}
+	var steps, step uint64 // Steps worked out in all, and into the part.
+	length := part.Length(ppq)
+	done := false
+	if length == 0 {
+		// A piece that takes no time (e.g. only pc and cc) still sends its messages, all at once.
+		part.Play(buf, rnd, ppq, 0)
+		queue = queueStep(queue, buf, 0, due)
+		buf.Flip()
+	}
+	for {
+		// Work out the steps that are coming up.
+		for !done && due(steps)-s.Lookahead <= elapsed() {
+			if step == length {
+				if !loop {
+					// Stop whatever's still sounding.
+					buf.Release()
+					queue = queueOffs(queue, buf, steps, due)
+					done = true
+					break
+				}
+				fmt.Println("Looping.")
+				step = 0
+				length = part.Length(ppq)
+			}
+			part.Play(buf, rnd, ppq, step)
+			queue = queueStep(queue, buf, steps, due)
+			buf.Flip()
+			step++
+			steps++
+		}
+		if done && len(queue) == 0 {
+			return nil
+		}
+
+		// Wait for the next message to send or step to work out, whichever is first.
+		var wake time.Duration
+		switch {
+		case done:
+			wake = queue[0].at
+		case len(queue) > 0 && queue[0].at < due(steps)-s.Lookahead:
+			wake = queue[0].at
+		default:
+			wake = due(steps) - s.Lookahead
+		}
+		if d := wake - elapsed(); d > 0 {
+			select {
+			case <-s.after(d):
+			case <-stop:
+				return nil
+			}
+		} else {
+			select {
+			case <-stop:
+				return nil
+			default:
+			}
+		}
+
+		// Send everything that's due.
+		now := elapsed()
+		for len(queue) > 0 && queue[0].at <= now {
+			t := &queue[0]
+			if late := now - t.at; late > s.Tolerance {
+				s.Late++
+				if late > s.Worst {
+					s.Worst = late
+				}
+			}
+			send(&t.m)
+			queue = queue[1:]
+		}
+	}
+}
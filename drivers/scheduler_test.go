@@ -0,0 +1,198 @@
+package drivers
+
+import (
+	"github.com/edemond/abstract/msg"
+	"github.com/edemond/abstract/types"
+	"math/rand"
+	"os"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock that only moves when it's waited on, or when a test moves it.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) after(d time.Duration) <-chan time.Time {
+	c.t = c.t.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.t
+	return ch
+}
+
+// quarters is a bar of quarter notes on middle C, some of whose steps take a while to work out.
+type quarters struct {
+	clock *fakeClock
+	slow  map[uint64]time.Duration
+}
+
+func (q *quarters) HasValue() bool {
+	return true
+}
+
+func (q *quarters) String() string {
+	return "quarters"
+}
+
+func (q *quarters) Length(ppq int) uint64 {
+	return uint64(4 * ppq)
+}
+
+func (q *quarters) Play(buf msg.Buffer, rnd *rand.Rand, ppq int, step uint64) {
+	q.clock.t = q.clock.t.Add(q.slow[step])
+	if step%uint64(ppq) == 0 {
+		m := &msg.Message{Length: uint64(ppq)}
+		m.MidiMessage.Command = 0x9
+		m.MidiMessage.Channel = 1
+		m.MidiMessage.Data1 = 60
+		m.MidiMessage.Data2 = 100
+		buf.Add(m)
+	}
+}
+
+type sent struct {
+	at      time.Duration
+	command uint8
+}
+
+// schedule plays a bar of quarter notes at 60 BPM and 4 PPQ, so a step every 250ms, with the
+// given steps taking the given time to work out. It returns what was sent and when.
+func schedule(t *testing.T, slow map[uint64]time.Duration) (*Scheduler, []sent) {
+	clock := &fakeClock{time.Unix(0, 0)}
+	s := NewScheduler()
+	s.now = clock.now
+	s.after = clock.after
+
+	out := []sent{}
+	part := &quarters{clock, slow}
+	err := s.Play(part, types.NewTempoMap(part, 60, 4), 4, false, 16, rand.New(rand.NewSource(1)), nil, func(m *msg.Message) {
+		out = append(out, sent{clock.t.Sub(time.Unix(0, 0)), m.MidiMessage.Command})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) == 0 {
+		t.Fatal("expected some messages to be sent")
+	}
+	return s, out
+}
+
+// noteOnTimes gets when each note on was sent.
+func noteOnTimes(out []sent) []time.Duration {
+	times := []time.Duration{}
+	for _, s := range out {
+		if s.command == 0x9 {
+			times = append(times, s.at)
+		}
+	}
+	return times
+}
+
+func TestSchedulerSendsOnTime(t *testing.T) {
+	s, out := schedule(t, nil)
+	for _, m := range out {
+		if m.at%(250*time.Millisecond) != 0 {
+			t.Errorf("expected messages to go out on a step, got one at %v", m.at)
+		}
+	}
+	times := noteOnTimes(out)
+	expected := []time.Duration{0, time.Second, 2 * time.Second, 3 * time.Second}
+	if len(times) != len(expected) {
+		t.Fatalf("expected note ons at %v, got %v", expected, times)
+	}
+	for i := range expected {
+		if times[i] != expected[i] {
+			t.Fatalf("expected note ons at %v, got %v", expected, times)
+		}
+	}
+	if out[len(out)-1].command != 0x8 || out[len(out)-1].at != 4*time.Second {
+		t.Errorf("expected the last note to be stopped at the end of the bar, got %v", out[len(out)-1])
+	}
+	if s.Late != 0 {
+		t.Errorf("expected nothing to be late, got %v late messages", s.Late)
+	}
+}
+
+func TestSchedulerRidesOutSlowSteps(t *testing.T) {
+	// Slow, but not so slow that the lookahead can't cover it.
+	s, out := schedule(t, map[uint64]time.Duration{3: 40 * time.Millisecond, 4: 30 * time.Millisecond})
+	times := noteOnTimes(out)
+	if len(times) < 2 || times[1] != time.Second {
+		t.Errorf("expected the second note on to go out on time, at 1s, got %v", times)
+	}
+	if s.Late != 0 {
+		t.Errorf("expected nothing to be late, got %v late messages", s.Late)
+	}
+}
+
+func TestSchedulerReportsLateMessages(t *testing.T) {
+	// Longer than the lookahead, so the second beat goes out late.
+	s, out := schedule(t, map[uint64]time.Duration{4: 100 * time.Millisecond})
+	if s.Late == 0 {
+		t.Fatal("expected late messages to be counted")
+	}
+	if s.Worst != 100*time.Millisecond-DefaultLookahead {
+		t.Errorf("expected the worst to be %v late, got %v", 100*time.Millisecond-DefaultLookahead, s.Worst)
+	}
+	// Being late doesn't push back the steps after it.
+	times := noteOnTimes(out)
+	if len(times) < 3 || times[2] != 2*time.Second {
+		t.Errorf("expected the third note on to go out on time, at 2s, got %v", times)
+	}
+}
+
+func TestSchedulerStops(t *testing.T) {
+	clock := &fakeClock{time.Unix(0, 0)}
+	s := NewScheduler()
+	s.now = clock.now
+	s.after = func(d time.Duration) <-chan time.Time {
+		return nil // Never comes, so only stopping can end it.
+	}
+	stop := make(chan os.Signal, 1)
+	stop <- os.Interrupt
+	part := &quarters{clock, nil}
+	err := s.Play(part, types.NewTempoMap(part, 60, 4), 4, true, 16, rand.New(rand.NewSource(1)), stop, func(m *msg.Message) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSchedulerWontLoopNothing(t *testing.T) {
+	s := NewScheduler()
+	part := types.NewBlockPart() // Takes no time at all.
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Play(part, types.NewTempoMap(part, 60, 4), 4, true, 16, rand.New(rand.NewSource(1)), nil, func(m *msg.Message) {})
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("expected an error looping a piece with no length")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("looping a piece with no length never returned")
+	}
+}
+
+func TestSchedulerSendsAPieceThatTakesNoTime(t *testing.T) {
+	clock := &fakeClock{time.Unix(0, 0)}
+	s := NewScheduler()
+	s.now = clock.now
+	s.after = clock.after
+	part := programChange()
+	sent := []msg.Message{}
+	err := s.Play(part, types.NewTempoMap(part, 60, 4), 4, false, 16, rand.New(rand.NewSource(1)), nil, func(m *msg.Message) {
+		sent = append(sent, *m)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || sent[0].MidiMessage.Command != 0xC || sent[0].MidiMessage.Data1 != 5 {
+		t.Fatalf("expected the program change to be sent, got %v", sent)
+	}
+}
@@ -0,0 +1,238 @@
+// Package sf2 implements an Abstract driver that plays the piece with the samples in a
+// SoundFont 2 (.sf2) file and renders it to a WAV file, with no MIDI gear or softsynth needed.
+//
+// An instrument's name picks its preset instead of a MIDI device: "bank:preset" (e.g. "0:33"),
+// just a preset number in bank 0 (e.g. "33"), or the preset's name. As in General MIDI,
+// channel 10 plays the drum kits in bank 128, and a program change picks another preset
+// from the same bank.
+package sf2
+
+import (
+	"github.com/edemond/abstract/drivers"
+	"github.com/edemond/abstract/drivers/wav"
+	"github.com/edemond/abstract/msg"
+	"github.com/edemond/abstract/types"
+	"bufio"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const sampleRate = 44100
+
+// The bank General MIDI SoundFonts keep drum kits in.
+const drumBank = 128
+
+// Unique Instrument ID to be incremented each time we assign one.
+var instrumentID int
+
+// channelKey identifies a channel on an instrument, which has its own program.
+type channelKey struct {
+	instrument int
+	channel    uint8
+}
+
+// soundingKey identifies a sounding note so its note off can find it.
+type soundingKey struct {
+	instrument int
+	channel    uint8
+	note       uint8
+}
+
+type sf2Driver struct {
+	filename    string
+	font        *SoundFont
+	instruments map[int]*preset        // Instrument ID -> preset
+	programs    map[channelKey]*preset // Presets picked with program changes.
+}
+
+// NewSF2Driver creates a driver that plays the piece with the given SoundFont and writes
+// it to the given file.
+func NewSF2Driver(soundfont string, filename string) (drivers.Driver, error) {
+	if soundfont == "" {
+		return nil, fmt.Errorf("The sf2 driver needs a SoundFont (.sf2) file; give one with -sf.")
+	}
+	font, err := Load(soundfont)
+	if err != nil {
+		return nil, err
+	}
+	if len(font.presets) == 0 {
+		return nil, fmt.Errorf("%v has no presets.", soundfont)
+	}
+	return &sf2Driver{
+		filename:    filename,
+		font:        font,
+		instruments: make(map[int]*preset),
+		programs:    make(map[channelKey]*preset),
+	}, nil
+}
+
+// lookup finds the preset an instrument name refers to, or nil.
+func (d *sf2Driver) lookup(name string) *preset {
+	bank, number := 0, name
+	if i := strings.Index(name, ":"); i >= 0 {
+		b, err := strconv.Atoi(name[:i])
+		if err != nil {
+			return d.font.presetNamed(name)
+		}
+		bank, number = b, name[i+1:]
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return d.font.presetNamed(name)
+	}
+	return d.font.preset(bank, n)
+}
+
+func (d *sf2Driver) OpenInstrument(name string) (int, error) {
+	p := d.lookup(name)
+	if p == nil {
+		// Probably a MIDI device name from a piece written for outboard gear. Still play it.
+		p = d.font.presets[0]
+		fmt.Printf("No preset '%v' in the SoundFont, so using '%v' (%v:%v).\n", name, p.name, p.bank, p.preset)
+	}
+	id := instrumentID
+	d.instruments[id] = p
+	instrumentID += 1
+	return id, nil
+}
+
+func (d *sf2Driver) CloseInstrument(id int) error {
+	_, ok := d.instruments[id]
+	if !ok {
+		panic(fmt.Sprintf("Internal error: Couldn't close instrument; no instrument with ID %v is open", id))
+	}
+	delete(d.instruments, id)
+	return nil
+}
+
+func (d *sf2Driver) Close() error {
+	return nil
+}
+
+// preset gets the preset to play an instrument's channel with.
+func (d *sf2Driver) preset(id int, channel uint8) *preset {
+	if p, ok := d.programs[channelKey{id, channel}]; ok {
+		return p
+	}
+	p, ok := d.instruments[id]
+	if !ok {
+		p = d.font.presets[0] // Parts without an instrument still send messages.
+	}
+	if channel == 10 && p.bank != drumBank {
+		if kit := d.font.preset(drumBank, 0); kit != nil {
+			return kit
+		}
+	}
+	return p
+}
+
+// program handles a program change, picking a preset from the same bank.
+func (d *sf2Driver) program(id int, channel uint8, program uint8) {
+	bank := d.preset(id, channel).bank
+	p := d.font.preset(bank, int(program))
+	if p == nil {
+		p = d.font.preset(0, int(program))
+	}
+	if p == nil {
+		fmt.Printf("No preset %v:%v in the SoundFont; ignoring the program change.\n", bank, program)
+		return
+	}
+	d.programs[channelKey{id, channel}] = p
+}
+
+// Render the piece to the file. Looping makes no sense here, so the piece is rendered once.
+func (d *sf2Driver) Play(part types.Part, tempo *types.TempoMap, ppq int, loop bool, polyphony int, rnd *rand.Rand) error {
+	if ppq <= 0 {
+		return fmt.Errorf("Can't render a piece with a PPQ of %v.", ppq)
+	}
+
+	voices := []*voice{}
+	sounding := make(map[soundingKey][]*voice)
+	err := drivers.Render(part, tempo, ppq, polyphony, rnd, func(step uint64, m *msg.Message) {
+		// Humanize moves everything, note offs included, off the step.
+		at := int((tempo.Time(step) + m.HumanizeTime) * sampleRate / time.Second)
+		if at < 0 {
+			at = 0
+		}
+		mm := m.MidiMessage
+		key := soundingKey{m.Instrument, mm.Channel, mm.Data1}
+		switch mm.Command {
+		case 0x9:
+			p := d.preset(m.Instrument, mm.Channel)
+			for _, r := range d.font.regions(p, int(mm.Data1), int(mm.Data2)) {
+				v := &voice{
+					region: r,
+					key:    int(mm.Data1),
+					vel:    int(mm.Data2),
+					start:  at,
+				}
+				voices = append(voices, v)
+				sounding[key] = append(sounding[key], v)
+			}
+		case 0x8:
+			for _, v := range sounding[key] {
+				v.released = at
+				if v.released < v.start {
+					v.released = v.start
+				}
+			}
+			delete(sounding, key)
+		case 0xC:
+			d.program(m.Instrument, mm.Channel, mm.Data1)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	samples := d.mix(voices, int(tempo.Time(part.Length(ppq))*sampleRate/time.Second))
+
+	f, err := os.Create(d.filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	err = wav.Write(w, samples, sampleRate)
+	if err != nil {
+		return err
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %v.\n", d.filename)
+	return nil
+}
+
+// mix plays all the voices together. The result is at least length samples long, and
+// longer if notes ring on past the end. If it would clip, it's turned down to fit.
+func (d *sf2Driver) mix(voices []*voice, length int) []float64 {
+	for _, v := range voices {
+		end := v.released + int(newEnvelope(&v.region.gens).tail()*sampleRate) + 1
+		if end > length {
+			length = end
+		}
+	}
+	out := make([]float64, length)
+	for _, v := range voices {
+		v.render(out, sampleRate, d.font.data)
+	}
+
+	peak := 0.0
+	for _, s := range out {
+		peak = math.Max(peak, math.Abs(s))
+	}
+	if peak > 1 {
+		for i := range out {
+			out[i] /= peak
+		}
+	}
+	return out
+}
@@ -0,0 +1,221 @@
+package sf2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// chunk makes a RIFF chunk.
+func chunk(id string, body []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(id)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(body)))
+	buf.Write(body)
+	if len(body)%2 == 1 {
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
+
+// list makes a RIFF LIST (or the RIFF itself) of the given type.
+func list(id string, kind string, chunks ...[]byte) []byte {
+	body := []byte(kind)
+	for _, c := range chunks {
+		body = append(body, c...)
+	}
+	return chunk(id, body)
+}
+
+// record packs fields into a little-endian record, padding names out to 20 bytes.
+func record(fields ...interface{}) []byte {
+	var buf bytes.Buffer
+	for _, f := range fields {
+		if s, ok := f.(string); ok {
+			name := make([]byte, 20)
+			copy(name, s)
+			buf.Write(name)
+			continue
+		}
+		binary.Write(&buf, binary.LittleEndian, f)
+	}
+	return buf.Bytes()
+}
+
+func join(records ...[]byte) []byte {
+	return bytes.Join(records, nil)
+}
+
+// testFont makes a SoundFont with one instrument playing one looped, 100 sample square-ish
+// wave over keys 0-64, and two presets using it: "Piano" (0:0) and "Kit" (128:0), which is
+// quieter by 6dB.
+func testFont() []byte {
+	smpl := make([]int16, 100)
+	for i := range smpl {
+		smpl[i] = 16384
+	}
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, smpl)
+
+	gen := func(oper uint16, amount int16) []byte { return record(oper, amount) }
+	return list("RIFF", "sfbk",
+		list("LIST", "INFO", chunk("ifil", record(uint16(2), uint16(1)))),
+		list("LIST", "sdta", chunk("smpl", data.Bytes())),
+		list("LIST", "pdta",
+			chunk("phdr", join(
+				record("Piano", uint16(0), uint16(0), uint16(0), uint32(0), uint32(0), uint32(0)),
+				record("Kit", uint16(0), uint16(128), uint16(1), uint32(0), uint32(0), uint32(0)),
+				record("EOP", uint16(0), uint16(0), uint16(2), uint32(0), uint32(0), uint32(0)),
+			)),
+			chunk("pbag", join(record(uint16(0), uint16(0)), record(uint16(1), uint16(0)), record(uint16(3), uint16(0)))),
+			chunk("pmod", make([]byte, 10)),
+			chunk("pgen", join(
+				gen(genInstrument, 0),
+				gen(genInitialAttenuation, 60), gen(genInstrument, 0),
+				gen(0, 0),
+			)),
+			chunk("inst", join(record("Square", uint16(0)), record("EOI", uint16(2)))),
+			chunk("ibag", join(record(uint16(0), uint16(0)), record(uint16(1), uint16(0)), record(uint16(4), uint16(0)))),
+			chunk("imod", make([]byte, 10)),
+			chunk("igen", join(
+				gen(genReleaseVolEnv, -1200),
+				record(uint16(genKeyRange), uint8(0), uint8(64)), gen(genSampleModes, 1), gen(genSampleID, 0),
+				gen(0, 0),
+			)),
+			chunk("shdr", join(
+				record("Square", uint32(0), uint32(100), uint32(10), uint32(90), uint32(44100), uint8(60), int8(0), uint16(0), uint16(1)),
+				record("EOS", uint32(0), uint32(0), uint32(0), uint32(0), uint32(0), uint8(0), int8(0), uint16(0), uint16(0)),
+			)),
+		),
+	)
+}
+
+func testDriver(t *testing.T) *sf2Driver {
+	font, err := parse(testFont())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &sf2Driver{
+		font:        font,
+		instruments: make(map[int]*preset),
+		programs:    make(map[channelKey]*preset),
+	}
+}
+
+func TestParse(t *testing.T) {
+	font, err := parse(testFont())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(font.presets) != 2 || len(font.instruments) != 1 || len(font.samples) != 1 {
+		t.Fatalf("expected 2 presets, 1 instrument and 1 sample, got %v, %v and %v", len(font.presets), len(font.instruments), len(font.samples))
+	}
+	kit := font.presets[1]
+	if kit.name != "Kit" || kit.bank != 128 || kit.preset != 0 {
+		t.Fatalf("expected Kit at 128:0, got %v at %v:%v", kit.name, kit.bank, kit.preset)
+	}
+	if len(font.data) != 100 {
+		t.Fatalf("expected 100 samples of data, got %v", len(font.data))
+	}
+}
+
+func TestNotASoundFont(t *testing.T) {
+	if _, err := parse([]byte("RIFF\x04\x00\x00\x00WAVE")); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestRegions(t *testing.T) {
+	d := testDriver(t)
+	piano, kit := d.font.presets[0], d.font.presets[1]
+	if r := d.font.regions(piano, 70, 100); len(r) != 0 {
+		t.Fatalf("expected nothing above the key range, got %v regions", len(r))
+	}
+	r := d.font.regions(piano, 60, 100)
+	if len(r) != 1 {
+		t.Fatalf("expected 1 region, got %v", len(r))
+	}
+	if r[0].gens[genReleaseVolEnv] != -1200 {
+		t.Fatalf("expected the release from the global zone, got %v", r[0].gens[genReleaseVolEnv])
+	}
+	if r[0].gens[genScaleTuning] != 100 {
+		t.Fatalf("expected the default scale tuning, got %v", r[0].gens[genScaleTuning])
+	}
+	r = d.font.regions(kit, 60, 100)
+	if len(r) != 1 || r[0].gens[genInitialAttenuation] != 60 {
+		t.Fatalf("expected the preset's attenuation added on, got %v", r)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	d := testDriver(t)
+	cases := map[string]*preset{
+		"0:0":                    d.font.presets[0],
+		"0":                      d.font.presets[0],
+		"128:0":                  d.font.presets[1],
+		"kit":                    d.font.presets[1],
+		"0:1":                    nil,
+		"UM-2 MIDI 2 (hw:1,0,1)": nil,
+	}
+	for name, expected := range cases {
+		if p := d.lookup(name); p != expected {
+			t.Fatalf("expected '%v' to find %v, got %v", name, expected, p)
+		}
+	}
+}
+
+func TestChannel10PlaysDrums(t *testing.T) {
+	d := testDriver(t)
+	id, _ := d.OpenInstrument("Piano")
+	if p := d.preset(id, 1); p.name != "Piano" {
+		t.Fatalf("expected Piano, got %v", p.name)
+	}
+	if p := d.preset(id, 10); p.name != "Kit" {
+		t.Fatalf("expected channel 10 to play the kit, got %v", p.name)
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	env := envelope{attack: 0.1, hold: 0.1, decay: 1, sustain: 200, release: 1}
+	if cb := env.held(0.15); cb != 0 {
+		t.Fatalf("expected no attenuation during the hold, got %v", cb)
+	}
+	if cb := env.held(0.3); math.Abs(cb-100) > 1e-9 {
+		t.Fatalf("expected 10dB down a tenth of the way through the decay, got %vcB", cb)
+	}
+	if cb := env.held(5); cb != 200 {
+		t.Fatalf("expected to sustain at 20dB down, got %vcB", cb)
+	}
+	if _, done := env.level(4.5, 4); done {
+		t.Fatal("expected the note to still be releasing")
+	}
+	if _, done := env.level(5, 4); !done {
+		t.Fatal("expected the release to be done")
+	}
+}
+
+func TestVoicePitchAndLoop(t *testing.T) {
+	d := testDriver(t)
+	r := d.font.regions(d.font.presets[0], 60, 127)[0]
+	// Without the loop, an octave up plays through the 100 samples in 50.
+	r.gens[genSampleModes] = 0
+	r.gens[genReleaseVolEnv] = 0
+	v := &voice{region: r, key: 60 + 12, vel: 127, start: 0, released: 1000}
+	out := make([]float64, 1000)
+	v.render(out, sampleRate, d.font.data)
+	if out[10] == 0 || out[48] == 0 {
+		t.Fatalf("expected the sample to sound, got %v and %v", out[10], out[48])
+	}
+	if out[50] != 0 {
+		t.Fatalf("expected the sample to run out after 50 samples, got %v", out[50])
+	}
+
+	// With the loop, it keeps going until it's released.
+	r.gens[genSampleModes] = 1
+	out = make([]float64, 1000)
+	v.render(out, sampleRate, d.font.data)
+	if out[500] == 0 {
+		t.Fatal("expected the loop to keep the note going")
+	}
+}
@@ -0,0 +1,355 @@
+package sf2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Generator operators, from the SoundFont 2.01 spec (section 8.1.2). Only the ones the
+// renderer uses are here.
+const (
+	genStartAddrsOffset       = 0
+	genEndAddrsOffset         = 1
+	genStartloopAddrsOffset   = 2
+	genEndloopAddrsOffset     = 3
+	genStartAddrsCoarseOffset = 4
+	genEndAddrsCoarseOffset   = 12
+	genAttackVolEnv           = 34
+	genHoldVolEnv             = 35
+	genDecayVolEnv            = 36
+	genSustainVolEnv          = 37
+	genReleaseVolEnv          = 38
+	genInstrument             = 41
+	genKeyRange               = 43
+	genVelRange               = 44
+	genStartloopCoarseOffset  = 45
+	genInitialAttenuation     = 48
+	genEndloopCoarseOffset    = 50
+	genCoarseTune             = 51
+	genFineTune               = 52
+	genSampleID               = 53
+	genSampleModes            = 54
+	genScaleTuning            = 56
+	genOverridingRootKey      = 58
+	numGenerators             = 61
+)
+
+// Values of generators that aren't given, where they aren't 0.
+var defaultGenerators = map[int]int{
+	genAttackVolEnv:      -12000,
+	genHoldVolEnv:        -12000,
+	genDecayVolEnv:       -12000,
+	genReleaseVolEnv:     -12000,
+	genKeyRange:          127 << 8,
+	genVelRange:          127 << 8,
+	genScaleTuning:       100,
+	genOverridingRootKey: -1,
+}
+
+// zone is a preset or instrument zone: a set of generators. Ranges are stored as in the
+// file, low byte first.
+type zone struct {
+	gens [numGenerators]int
+	set  [numGenerators]bool
+}
+
+func (z *zone) inRange(gen int, value int) bool {
+	if !z.set[gen] {
+		return true
+	}
+	lo, hi := z.gens[gen]&0xFF, (z.gens[gen]>>8)&0xFF
+	return value >= lo && value <= hi
+}
+
+type instrument struct {
+	name   string
+	global *zone
+	zones  []*zone
+}
+
+type preset struct {
+	name   string
+	preset int
+	bank   int
+	global *zone
+	zones  []*zone
+}
+
+type sampleHeader struct {
+	name            string
+	start           int
+	end             int
+	startLoop       int
+	endLoop         int
+	sampleRate      int
+	originalPitch   int
+	pitchCorrection int
+}
+
+// SoundFont is a SoundFont 2 file, loaded for playing.
+type SoundFont struct {
+	data        []int16 // All the sample data.
+	samples     []*sampleHeader
+	instruments []*instrument
+	presets     []*preset
+}
+
+// region is what to play for one note: a sample, and the generators that apply to it
+// (the instrument's, with the preset's added on).
+type region struct {
+	sample *sampleHeader
+	gens   [numGenerators]int
+}
+
+// Load reads a SoundFont 2 file.
+func Load(filename string) (*SoundFont, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	sf, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", filename, err)
+	}
+	return sf, nil
+}
+
+// chunks splits RIFF data into its chunks, by ID. For LISTs, the ID is the list type.
+func chunks(data []byte) (map[string][]byte, error) {
+	result := make(map[string][]byte)
+	for len(data) >= 8 {
+		id := string(data[0:4])
+		size := int(binary.LittleEndian.Uint32(data[4:8]))
+		if 8+size > len(data) {
+			return nil, fmt.Errorf("chunk '%v' is cut off", id)
+		}
+		body := data[8 : 8+size]
+		if id == "LIST" && len(body) >= 4 {
+			id, body = string(body[0:4]), body[4:]
+		}
+		result[id] = body
+		data = data[8+size:]
+		if size%2 == 1 && len(data) > 0 {
+			data = data[1:] // Chunks are padded to an even size.
+		}
+	}
+	return result, nil
+}
+
+func parse(data []byte) (*SoundFont, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "sfbk" {
+		return nil, fmt.Errorf("not a SoundFont 2 file")
+	}
+	top, err := chunks(data[12:])
+	if err != nil {
+		return nil, err
+	}
+	sdta, err := chunks(top["sdta"])
+	if err != nil {
+		return nil, err
+	}
+	pdta, err := chunks(top["pdta"])
+	if err != nil {
+		return nil, err
+	}
+
+	sf := &SoundFont{}
+	smpl := sdta["smpl"]
+	sf.data = make([]int16, len(smpl)/2)
+	binary.Read(bytes.NewReader(smpl), binary.LittleEndian, sf.data)
+
+	for _, id := range []string{"phdr", "pbag", "pgen", "inst", "ibag", "igen", "shdr"} {
+		if _, ok := pdta[id]; !ok {
+			return nil, fmt.Errorf("missing '%v' chunk", id)
+		}
+	}
+	if err := sf.parseSamples(pdta["shdr"]); err != nil {
+		return nil, err
+	}
+	if err := sf.parseInstruments(pdta["inst"], pdta["ibag"], pdta["igen"]); err != nil {
+		return nil, err
+	}
+	if err := sf.parsePresets(pdta["phdr"], pdta["pbag"], pdta["pgen"]); err != nil {
+		return nil, err
+	}
+	return sf, nil
+}
+
+// name reads a fixed-length, zero-padded name.
+func name(b []byte) string {
+	return strings.TrimRight(string(bytes.SplitN(b, []byte{0}, 2)[0]), " ")
+}
+
+func (sf *SoundFont) parseSamples(shdr []byte) error {
+	const size = 46
+	// The last header is the terminal "EOS" record.
+	for i := 0; i+size <= len(shdr)-size; i += size {
+		h := shdr[i : i+size]
+		s := &sampleHeader{
+			name:            name(h[0:20]),
+			start:           int(binary.LittleEndian.Uint32(h[20:24])),
+			end:             int(binary.LittleEndian.Uint32(h[24:28])),
+			startLoop:       int(binary.LittleEndian.Uint32(h[28:32])),
+			endLoop:         int(binary.LittleEndian.Uint32(h[32:36])),
+			sampleRate:      int(binary.LittleEndian.Uint32(h[36:40])),
+			originalPitch:   int(h[40]),
+			pitchCorrection: int(int8(h[41])),
+		}
+		if s.end > len(sf.data) || s.start > s.end {
+			return fmt.Errorf("sample '%v' is out of bounds", s.name)
+		}
+		sf.samples = append(sf.samples, s)
+	}
+	return nil
+}
+
+// zones reads the zones in bags [from, to), given the bag and generator chunks.
+func zones(bags []byte, gens []byte, from, to int) ([]*zone, error) {
+	result := []*zone{}
+	for b := from; b < to; b++ {
+		if (b+2)*4 > len(bags) {
+			return nil, fmt.Errorf("zone %v is out of bounds", b)
+		}
+		start := int(binary.LittleEndian.Uint16(bags[b*4:]))
+		end := int(binary.LittleEndian.Uint16(bags[(b+1)*4:]))
+		if end*4 > len(gens) || start > end {
+			return nil, fmt.Errorf("generators for zone %v are out of bounds", b)
+		}
+		z := &zone{}
+		for g := start; g < end; g++ {
+			oper := int(binary.LittleEndian.Uint16(gens[g*4:]))
+			if oper >= numGenerators {
+				continue
+			}
+			amount := gens[g*4+2 : g*4+4]
+			if oper == genKeyRange || oper == genVelRange {
+				z.gens[oper] = int(amount[0]) | int(amount[1])<<8
+			} else {
+				z.gens[oper] = int(int16(binary.LittleEndian.Uint16(amount)))
+			}
+			z.set[oper] = true
+		}
+		result = append(result, z)
+	}
+	return result, nil
+}
+
+// splitGlobal separates out the global zone, which is the first zone if it doesn't end with
+// the given generator (instrument for presets, sampleID for instruments).
+func splitGlobal(zs []*zone, last int) (*zone, []*zone) {
+	if len(zs) > 0 && !zs[0].set[last] {
+		return zs[0], zs[1:]
+	}
+	return nil, zs
+}
+
+func (sf *SoundFont) parseInstruments(inst, ibag, igen []byte) error {
+	const size = 22
+	for i := 0; i+size <= len(inst)-size; i += size {
+		from := int(binary.LittleEndian.Uint16(inst[i+20:]))
+		to := int(binary.LittleEndian.Uint16(inst[i+size+20:]))
+		zs, err := zones(ibag, igen, from, to)
+		if err != nil {
+			return err
+		}
+		in := &instrument{name: name(inst[i : i+20])}
+		in.global, in.zones = splitGlobal(zs, genSampleID)
+		for _, z := range in.zones {
+			if z.gens[genSampleID] < 0 || z.gens[genSampleID] >= len(sf.samples) {
+				return fmt.Errorf("instrument '%v' has no sample %v", in.name, z.gens[genSampleID])
+			}
+		}
+		sf.instruments = append(sf.instruments, in)
+	}
+	return nil
+}
+
+func (sf *SoundFont) parsePresets(phdr, pbag, pgen []byte) error {
+	const size = 38
+	for i := 0; i+size <= len(phdr)-size; i += size {
+		from := int(binary.LittleEndian.Uint16(phdr[i+24:]))
+		to := int(binary.LittleEndian.Uint16(phdr[i+size+24:]))
+		zs, err := zones(pbag, pgen, from, to)
+		if err != nil {
+			return err
+		}
+		p := &preset{
+			name:   name(phdr[i : i+20]),
+			preset: int(binary.LittleEndian.Uint16(phdr[i+20:])),
+			bank:   int(binary.LittleEndian.Uint16(phdr[i+22:])),
+		}
+		p.global, p.zones = splitGlobal(zs, genInstrument)
+		for _, z := range p.zones {
+			if z.gens[genInstrument] < 0 || z.gens[genInstrument] >= len(sf.instruments) {
+				return fmt.Errorf("preset '%v' has no instrument %v", p.name, z.gens[genInstrument])
+			}
+		}
+		sf.presets = append(sf.presets, p)
+	}
+	return nil
+}
+
+// preset finds a preset by bank and number, or nil.
+func (sf *SoundFont) preset(bank, number int) *preset {
+	for _, p := range sf.presets {
+		if p.bank == bank && p.preset == number {
+			return p
+		}
+	}
+	return nil
+}
+
+// presetNamed finds a preset by name, ignoring case, or nil.
+func (sf *SoundFont) presetNamed(name string) *preset {
+	for _, p := range sf.presets {
+		if strings.EqualFold(p.name, name) {
+			return p
+		}
+	}
+	return nil
+}
+
+// regions gets what to play for a note on a preset.
+func (sf *SoundFont) regions(p *preset, key, vel int) []*region {
+	result := []*region{}
+	for _, pz := range p.zones {
+		if !pz.inRange(genKeyRange, key) || !pz.inRange(genVelRange, vel) {
+			continue
+		}
+		in := sf.instruments[pz.gens[genInstrument]]
+		for _, iz := range in.zones {
+			if !iz.inRange(genKeyRange, key) || !iz.inRange(genVelRange, vel) {
+				continue
+			}
+			r := &region{sample: sf.samples[iz.gens[genSampleID]]}
+			for g := 0; g < numGenerators; g++ {
+				// Instrument generators are absolute: the zone's, else the global zone's, else the default.
+				switch {
+				case iz.set[g]:
+					r.gens[g] = iz.gens[g]
+				case in.global != nil && in.global.set[g]:
+					r.gens[g] = in.global.gens[g]
+				default:
+					r.gens[g] = defaultGenerators[g]
+				}
+				// Preset generators are added on, except for the ones that only make sense in instruments.
+				switch g {
+				case genStartAddrsOffset, genEndAddrsOffset, genStartloopAddrsOffset, genEndloopAddrsOffset,
+					genStartAddrsCoarseOffset, genEndAddrsCoarseOffset, genStartloopCoarseOffset, genEndloopCoarseOffset,
+					genKeyRange, genVelRange, genInstrument, genSampleID, genSampleModes, genOverridingRootKey:
+					continue
+				}
+				if pz.set[g] {
+					r.gens[g] += pz.gens[g]
+				} else if p.global != nil && p.global.set[g] {
+					r.gens[g] += p.global.gens[g]
+				}
+			}
+			result = append(result, r)
+		}
+	}
+	return result
+}
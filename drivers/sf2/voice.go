@@ -0,0 +1,144 @@
+package sf2
+
+import (
+	"math"
+)
+
+// Attenuation, in centibels, at which a note is as good as silent.
+const silent = 960
+
+// timecents converts a time in timecents, as SoundFonts store them, to seconds.
+func timecents(tc int) float64 {
+	return math.Pow(2, float64(tc)/1200)
+}
+
+// amplitude converts an attenuation in centibels to an amplitude from 0 to 1.
+func amplitude(cb float64) float64 {
+	return math.Pow(10, -cb/200)
+}
+
+// envelope is a SoundFont volume envelope. Times are in seconds; sustain is an attenuation
+// in centibels. The decay and release are the times to fall by 100dB, in a straight line in dB.
+type envelope struct {
+	attack  float64
+	hold    float64
+	decay   float64
+	sustain float64
+	release float64
+}
+
+func newEnvelope(gens *[numGenerators]int) envelope {
+	return envelope{
+		attack:  timecents(gens[genAttackVolEnv]),
+		hold:    timecents(gens[genHoldVolEnv]),
+		decay:   timecents(gens[genDecayVolEnv]),
+		sustain: math.Max(0, float64(gens[genSustainVolEnv])),
+		release: timecents(gens[genReleaseVolEnv]),
+	}
+}
+
+// held gets the attenuation t seconds into a note that's still held.
+func (e envelope) held(t float64) float64 {
+	if t < e.attack {
+		if t <= 0 {
+			return silent
+		}
+		return math.Min(silent, -200*math.Log10(t/e.attack))
+	}
+	t -= e.attack + e.hold
+	if t < 0 {
+		return 0
+	}
+	return math.Min(e.sustain, 1000*t/e.decay)
+}
+
+// level gets the amplitude t seconds into a note that was released at the given time,
+// and whether the note is done.
+func (e envelope) level(t float64, released float64) (float64, bool) {
+	if t < released {
+		return amplitude(e.held(t)), false
+	}
+	cb := e.held(released) + 1000*(t-released)/e.release
+	if cb >= silent {
+		return 0, true
+	}
+	return amplitude(cb), false
+}
+
+// tail is how long the note can ring on after it's released.
+func (e envelope) tail() float64 {
+	return e.release * silent / 1000
+}
+
+// voice is one note being played from a sample.
+type voice struct {
+	region   *region
+	key      int
+	vel      int
+	start    int // Output sample the note starts on.
+	released int // Output sample the note is released on.
+}
+
+// render adds the voice into out, which is at the given sample rate, reading samples from data.
+func (v *voice) render(out []float64, rate int, data []int16) {
+	g := &v.region.gens
+	s := v.region.sample
+
+	start := clamp(s.start+g[genStartAddrsOffset]+32768*g[genStartAddrsCoarseOffset], 0, len(data))
+	end := clamp(s.end+g[genEndAddrsOffset]+32768*g[genEndAddrsCoarseOffset], start, len(data))
+	loopStart := s.startLoop + g[genStartloopAddrsOffset] + 32768*g[genStartloopCoarseOffset]
+	loopEnd := s.endLoop + g[genEndloopAddrsOffset] + 32768*g[genEndloopCoarseOffset]
+	// Mode 1 loops for the whole note, mode 3 until it's released, and 0 doesn't loop.
+	mode := g[genSampleModes] & 3
+	looping := (mode == 1 || mode == 3) && loopStart >= start && loopEnd > loopStart && loopEnd <= end
+
+	root := g[genOverridingRootKey]
+	if root < 0 {
+		root = s.originalPitch
+	}
+	cents := float64(v.key-root)*float64(g[genScaleTuning]) + float64(100*g[genCoarseTune]+g[genFineTune]+s.pitchCorrection)
+	step := math.Pow(2, cents/1200) * float64(s.sampleRate) / float64(rate)
+
+	env := newEnvelope(g)
+	vel := float64(v.vel) / 127
+	gain := amplitude(float64(g[genInitialAttenuation])) * vel * vel
+	released := float64(v.released-v.start) / float64(rate)
+
+	pos := float64(start)
+	for i := v.start; i < len(out); i++ {
+		t := float64(i-v.start) / float64(rate)
+		level, done := env.level(t, released)
+		if done {
+			return
+		}
+		n := int(pos)
+		if n >= end {
+			return
+		}
+		// Interpolate between this sample and the next.
+		a := float64(data[n])
+		b := 0.0
+		if n+1 < end {
+			b = float64(data[n+1])
+		}
+		frac := pos - float64(n)
+		out[i] += (a + (b-a)*frac) / 32768 * level * gain
+
+		pos += step
+		if looping && (mode == 1 || t < released) {
+			for pos >= float64(loopEnd) {
+				pos -= float64(loopEnd - loopStart)
+			}
+		}
+	}
+}
+
+func clamp(n, lo, hi int) int {
+	if n < lo {
+		return lo
+	}
+	if n > hi {
+		return hi
+	}
+	return n
+}
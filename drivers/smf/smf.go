@@ -0,0 +1,254 @@
+// Package smf implements an Abstract driver that renders a piece to a Standard MIDI File
+// instead of playing it. Each instrument gets its own track, and one step is one tick.
+package smf
+
+import (
+	"github.com/edemond/abstract/drivers"
+	"github.com/edemond/abstract/msg"
+	"github.com/edemond/abstract/types"
+	"github.com/edemond/midi"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"time"
+)
+
+// Unique Instrument ID to be incremented each time we assign one.
+var instrumentID int
+
+// track collects the events for one MTrk chunk.
+type track struct {
+	name    string
+	events  bytes.Buffer
+	last    uint64  // Tick of the last event written, since SMF stores delta times.
+	pending []event // Messages still to be put in order and written.
+}
+
+// event is a channel message and the tick it goes out on.
+type event struct {
+	tick uint64
+	m    midi.Message
+}
+
+type smfDriver struct {
+	filename string
+	tracks   map[int]*track // Instrument ID -> track
+}
+
+// NewSMFDriver creates a driver that writes the piece to the given file when played.
+func NewSMFDriver(filename string) (drivers.Driver, error) {
+	return &smfDriver{
+		filename: filename,
+		tracks:   make(map[int]*track),
+	}, nil
+}
+
+func (d *smfDriver) OpenInstrument(name string) (int, error) {
+	id := instrumentID
+	d.tracks[id] = &track{name: name}
+	instrumentID += 1
+	return id, nil
+}
+
+func (d *smfDriver) CloseInstrument(id int) error {
+	_, ok := d.tracks[id]
+	if !ok {
+		panic(fmt.Sprintf("Internal error: Couldn't close instrument; no instrument with ID %v is open", id))
+	}
+	delete(d.tracks, id)
+	return nil
+}
+
+func (d *smfDriver) Close() error {
+	return nil
+}
+
+// track gets the track for an instrument ID. Parts without an instrument still
+// send messages, so those get a track of their own rather than being dropped.
+func (d *smfDriver) track(id int) *track {
+	t, ok := d.tracks[id]
+	if !ok {
+		t = &track{name: "(no instrument)"}
+		d.tracks[id] = t
+	}
+	return t
+}
+
+// Render the piece to the file. Looping makes no sense here, so the piece is rendered once.
+func (d *smfDriver) Play(part types.Part, tempo *types.TempoMap, ppq int, loop bool, polyphony int, rnd *rand.Rand) error {
+	// The division field is 15 bits when it's in ticks per quarter note.
+	if ppq <= 0 || ppq > 0x7FFF {
+		return fmt.Errorf("A Standard MIDI File can't have a PPQ of %v (must be 1-%v).", ppq, 0x7FFF)
+	}
+
+	// Humanize moves messages off their steps, so they're collected to be put in order first.
+	err := drivers.Render(part, tempo, ppq, polyphony, rnd, func(step uint64, m *msg.Message) {
+		tick := int64(step) + ticks(m.HumanizeTime, tempo.BPM(step), ppq)
+		if tick < 0 {
+			tick = 0
+		}
+		t := d.track(m.Instrument)
+		t.pending = append(t.pending, event{uint64(tick), m.MidiMessage})
+	})
+	if err != nil {
+		return err
+	}
+	for _, t := range d.tracks {
+		t.writePending()
+	}
+
+	f, err := os.Create(d.filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	err = d.write(w, tempo, part.Length(ppq), ppq)
+	if err != nil {
+		return err
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %v.\n", d.filename)
+	return nil
+}
+
+// write writes out a format 1 file: a tempo track, then one track per instrument, in instrument ID order.
+// The tempo track has a tempo change wherever the tempo changes, which is every step of a ramp.
+func (d *smfDriver) write(w io.Writer, tempo *types.TempoMap, length uint64, ppq int) error {
+	ids := make([]int, 0, len(d.tracks))
+	for id := range d.tracks {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	header := []interface{}{
+		[]byte("MThd"),
+		uint32(6),            // header length
+		uint16(1),            // format 1: simultaneous tracks
+		uint16(len(ids) + 1), // the tempo track, plus one per instrument
+		uint16(ppq),          // division, in ticks per quarter note
+	}
+	for _, field := range header {
+		if err := binary.Write(w, binary.BigEndian, field); err != nil {
+			return err
+		}
+	}
+
+	if err := tempoTrack(tempo, length).writeTo(w); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := d.tracks[id].writeTo(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tempoTrack makes the tempo track for a piece of the given length.
+func tempoTrack(tempo *types.TempoMap, length uint64) *track {
+	t := &track{}
+	last := 0
+	for step := uint64(0); step == 0 || step < length; step++ {
+		if usec := microsPerQuarter(tempo.BPM(step)); usec != last {
+			t.writeTempo(step, usec)
+			last = usec
+		}
+	}
+	return t
+}
+
+// microsPerQuarter gets the length of a beat at the given tempo, the way SMF tempos are given.
+func microsPerQuarter(bpm float64) int {
+	return int(math.Round(60000000 / bpm))
+}
+
+// ticks gets the nearest number of ticks to a length of time at the given tempo.
+func ticks(d time.Duration, bpm float64, ppq int) int64 {
+	return int64(math.Round(float64(d) * bpm * float64(ppq) / float64(time.Minute)))
+}
+
+// writePending writes the messages collected for the track, in the order they go out.
+func (t *track) writePending() {
+	sort.SliceStable(t.pending, func(i, j int) bool {
+		return t.pending[i].tick < t.pending[j].tick
+	})
+	for _, e := range t.pending {
+		t.writeMessage(e.tick, e.m)
+	}
+	t.pending = nil
+}
+
+// writeDelta writes the delta time from the last event to the given tick.
+func (t *track) writeDelta(tick uint64) {
+	writeVarLen(&t.events, tick-t.last)
+	t.last = tick
+}
+
+// writeMessage writes a channel message. Channels are 1-16, as everywhere else in Abstract.
+func (t *track) writeMessage(tick uint64, m midi.Message) {
+	t.writeDelta(tick)
+	t.events.WriteByte((m.Command << 4) | ((m.Channel - 1) & 0x0F))
+	t.events.WriteByte(m.Data1 & 0x7F)
+	// Program change and channel pressure only have one data byte.
+	if m.Command != 0xC && m.Command != 0xD {
+		t.events.WriteByte(m.Data2 & 0x7F)
+	}
+}
+
+// writeMeta writes a meta event.
+func (t *track) writeMeta(tick uint64, kind byte, data []byte) {
+	t.writeDelta(tick)
+	t.events.WriteByte(0xFF)
+	t.events.WriteByte(kind)
+	writeVarLen(&t.events, uint64(len(data)))
+	t.events.Write(data)
+}
+
+// writeTempo writes a set tempo meta event, which is in microseconds per quarter note.
+func (t *track) writeTempo(tick uint64, usec int) {
+	t.writeMeta(tick, 0x51, []byte{byte(usec >> 16), byte(usec >> 8), byte(usec)})
+}
+
+// writeTo writes the whole MTrk chunk, named if it has a name, and with an end of track event.
+func (t *track) writeTo(w io.Writer) error {
+	var chunk track
+	if t.name != "" {
+		chunk.writeMeta(0, 0x03, []byte(t.name))
+	}
+	chunk.events.Write(t.events.Bytes())
+	chunk.last = t.last
+	chunk.writeMeta(t.last, 0x2F, nil)
+
+	if _, err := w.Write([]byte("MTrk")); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, uint32(chunk.events.Len())); err != nil {
+		return err
+	}
+	_, err := w.Write(chunk.events.Bytes())
+	return err
+}
+
+// writeVarLen writes a number as an SMF variable-length quantity: seven bits per byte,
+// most significant first, with the high bit set on every byte but the last.
+func writeVarLen(buf *bytes.Buffer, n uint64) {
+	var out [10]byte
+	i := len(out) - 1
+	out[i] = byte(n & 0x7F)
+	for n >>= 7; n > 0; n >>= 7 {
+		i--
+		out[i] = byte(n&0x7F) | 0x80
+	}
+	buf.Write(out[i:])
+}
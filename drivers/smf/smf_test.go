@@ -0,0 +1,110 @@
+package smf
+
+import (
+	"github.com/edemond/abstract/types"
+	"github.com/edemond/midi"
+	"bytes"
+	"testing"
+	"time"
+)
+
+func expectBytes(t *testing.T, actual []byte, expected []byte) {
+	if !bytes.Equal(actual, expected) {
+		t.Fatalf("expected % x, got % x", expected, actual)
+	}
+}
+
+func TestWriteVarLen(t *testing.T) {
+	cases := map[uint64][]byte{
+		0:          {0x00},
+		0x40:       {0x40},
+		0x7F:       {0x7F},
+		0x80:       {0x81, 0x00},
+		0x2000:     {0xC0, 0x00},
+		0x3FFF:     {0xFF, 0x7F},
+		0x4000:     {0x81, 0x80, 0x00},
+		0x0FFFFFFF: {0xFF, 0xFF, 0xFF, 0x7F},
+	}
+	for n, expected := range cases {
+		var buf bytes.Buffer
+		writeVarLen(&buf, n)
+		expectBytes(t, buf.Bytes(), expected)
+	}
+}
+
+func TestWriteMessageUsesDeltaTimes(t *testing.T) {
+	var tr track
+	tr.writeMessage(0, midi.Message{Command: 0x9, Channel: 1, Data1: 60, Data2: 100})
+	tr.writeMessage(200, midi.Message{Command: 0x8, Channel: 1, Data1: 60, Data2: 100})
+	tr.writeMessage(200, midi.Message{Command: 0xC, Channel: 10, Data1: 5})
+	expectBytes(t, tr.events.Bytes(), []byte{
+		0x00, 0x90, 60, 100,
+		0x81, 0x48, 0x80, 60, 100,
+		0x00, 0xC9, 5,
+	})
+}
+
+func TestWriteTrack(t *testing.T) {
+	tr := &track{name: "bass"}
+	tr.writeMessage(16, midi.Message{Command: 0x9, Channel: 2, Data1: 36, Data2: 127})
+	var buf bytes.Buffer
+	if err := tr.writeTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expectBytes(t, buf.Bytes(), []byte{
+		'M', 'T', 'r', 'k', 0, 0, 0, 16,
+		0x00, 0xFF, 0x03, 4, 'b', 'a', 's', 's',
+		16, 0x91, 36, 127,
+		0x00, 0xFF, 0x2F, 0x00,
+	})
+}
+
+func TestTempoTrack(t *testing.T) {
+	bar := types.NewSimplePart()
+	bar.Rhythm.Meter = &types.Meter{Beats: 4, Value: 4}
+	part := types.NewBlockPart()
+	part.Add(bar)
+	part.Add(types.NewBPMPart(60))
+	part.Add(bar)
+
+	// A tempo change only where the tempo changes, halfway through.
+	tr := tempoTrack(types.NewTempoMap(part, 120, 1), part.Length(1))
+	expectBytes(t, tr.events.Bytes(), []byte{
+		0x00, 0xFF, 0x51, 0x03, 0x07, 0xA1, 0x20, // 500000us a beat
+		4, 0xFF, 0x51, 0x03, 0x0F, 0x42, 0x40, // 1000000us a beat
+	})
+}
+
+func TestTicks(t *testing.T) {
+	// At 120 BPM and 96 PPQ, a tick is about 5.2ms.
+	cases := map[time.Duration]int64{
+		0:                      0,
+		2 * time.Millisecond:   0,
+		3 * time.Millisecond:   1,
+		-10 * time.Millisecond: -2,
+		500 * time.Millisecond: 96,
+	}
+	for d, expected := range cases {
+		if n := ticks(d, 120, 96); n != expected {
+			t.Errorf("expected %v to be %v ticks, got %v", d, expected, n)
+		}
+	}
+}
+
+func TestWritePendingPutsHumanizedMessagesInOrder(t *testing.T) {
+	var tr track
+	tr.pending = []event{
+		{4, midi.Message{Command: 0x9, Channel: 1, Data1: 60, Data2: 100}},
+		{2, midi.Message{Command: 0x8, Channel: 1, Data1: 62, Data2: 100}},
+		{2, midi.Message{Command: 0x9, Channel: 1, Data1: 64, Data2: 100}},
+	}
+	tr.writePending()
+	expectBytes(t, tr.events.Bytes(), []byte{
+		0x02, 0x80, 62, 100,
+		0x00, 0x90, 64, 100,
+		0x02, 0x90, 60, 100,
+	})
+	if tr.pending != nil {
+		t.Errorf("expected the pending messages to be written out, got %v", tr.pending)
+	}
+}
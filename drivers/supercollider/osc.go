@@ -0,0 +1,84 @@
+package supercollider
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Seconds from the NTP epoch (1900) to the Unix epoch (1970), since OSC time tags are NTP times.
+const ntpEpochOffset = 2208988800
+
+// message is an OSC message. Arguments can be int32, float32 or string.
+type message struct {
+	address string
+	args    []interface{}
+}
+
+// bundle is an OSC bundle: messages to be carried out together at the given time.
+type bundle struct {
+	time     time.Time
+	messages []*message
+}
+
+// writeString writes an OSC string: null terminated, then padded to a multiple of 4 bytes.
+func writeString(buf *bytes.Buffer, s string) {
+	buf.WriteString(s)
+	for pad := 4 - len(s)%4; pad > 0; pad-- {
+		buf.WriteByte(0)
+	}
+}
+
+func (m *message) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	writeString(&buf, m.address)
+	tags := ","
+	for _, arg := range m.args {
+		switch arg.(type) {
+		case int32:
+			tags += "i"
+		case float32:
+			tags += "f"
+		case string:
+			tags += "s"
+		default:
+			return nil, fmt.Errorf("Internal error: can't send a %T as an OSC argument", arg)
+		}
+	}
+	writeString(&buf, tags)
+	for _, arg := range m.args {
+		switch a := arg.(type) {
+		case int32:
+			binary.Write(&buf, binary.BigEndian, a)
+		case float32:
+			binary.Write(&buf, binary.BigEndian, math.Float32bits(a))
+		case string:
+			writeString(&buf, a)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// timeTag converts a time to an OSC time tag: NTP seconds, then a 32 bit fraction of a second.
+func timeTag(t time.Time) uint64 {
+	secs := uint64(t.Unix() + ntpEpochOffset)
+	frac := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return secs<<32 | frac
+}
+
+func (b *bundle) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	writeString(&buf, "#bundle")
+	binary.Write(&buf, binary.BigEndian, timeTag(b.time))
+	for _, m := range b.messages {
+		data, err := m.encode()
+		if err != nil {
+			return nil, err
+		}
+		binary.Write(&buf, binary.BigEndian, int32(len(data)))
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
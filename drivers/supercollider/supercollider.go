@@ -1,8 +1,282 @@
+// Package supercollider implements an Abstract driver that sends notes as Open Sound Control
+// (OSC) messages over UDP, for SuperCollider or anything else that speaks OSC.
+//
+// It keeps time itself, like rawmidi, but sends each step's messages a little ahead of time
+// in bundles tagged with when they should happen, so the receiver can play them exactly on
+// time (humanized timing included) however the network jitters.
+//
+// An instrument's name says what to send for it:
+//
+//	"/s_new" or "/s_new <synthdef>" starts a SuperCollider synth for each note (the "default"
+//	synthdef if none is given) with its freq and amp controls set, and releases it with
+//	/n_set <node> gate 0.
+//
+//	Any other address pattern, e.g. "/note", sends "<address> channel note velocity" for note
+//	ons and velocity 0 for note offs, "<address>/cc channel controller value" for controller
+//	changes and "<address>/pc channel program" for program changes.
 package supercollider
 
 import (
-//osc "github.com/hypebeast/go-osc"
+	"github.com/edemond/abstract/drivers"
+	"github.com/edemond/abstract/msg"
+	"github.com/edemond/abstract/types"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
 )
 
-type Supercollider struct {
+// DefaultAddress is where SuperCollider's server (scsynth) listens by default.
+const DefaultAddress = "127.0.0.1:57110"
+
+// How far ahead of time to send messages. Like SuperCollider's own Server.latency.
+const latency = 100 * time.Millisecond
+
+// The first node ID to give synths, leaving room below for the ones SuperCollider makes itself.
+const firstNode = 1000
+
+// Unique Instrument ID to be incremented each time we assign one.
+var instrumentID int
+
+type oscInstrument struct {
+	address  string
+	synthdef string // For /s_new instruments, the synth to start for each note.
+}
+
+// soundingKey identifies a sounding note so its note off can find it.
+type soundingKey struct {
+	instrument int
+	channel    uint8
+	note       uint8
+}
+
+type oscDriver struct {
+	conn        net.Conn
+	latency     time.Duration
+	instruments map[int]*oscInstrument // Instrument ID -> instrument
+	sounding    map[soundingKey]int32  // Sounding notes -> synth node ID, or 0 if they aren't synths.
+	nextNode    int32
+}
+
+// NewOSCDriver creates a driver that sends OSC to the given UDP address (host:port).
+func NewOSCDriver(addr string) (drivers.Driver, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &oscDriver{
+		conn:        conn,
+		latency:     latency,
+		instruments: make(map[int]*oscInstrument),
+		sounding:    make(map[soundingKey]int32),
+		nextNode:    firstNode,
+	}, nil
+}
+
+func (d *oscDriver) OpenInstrument(name string) (int, error) {
+	fields := strings.Fields(name)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return 0, fmt.Errorf("OSC instrument '%v' should be an address pattern, like \"/note\" or \"/s_new default\".", name)
+	}
+	inst := &oscInstrument{address: fields[0]}
+	if inst.address == "/s_new" {
+		inst.synthdef = "default"
+		if len(fields) > 1 {
+			inst.synthdef = fields[1]
+		}
+	}
+	id := instrumentID
+	d.instruments[id] = inst
+	instrumentID += 1
+	return id, nil
+}
+
+func (d *oscDriver) CloseInstrument(id int) error {
+	_, ok := d.instruments[id]
+	if !ok {
+		panic(fmt.Sprintf("Internal error: Couldn't close instrument; no instrument with ID %v is open", id))
+	}
+	delete(d.instruments, id)
+	return nil
+}
+
+func (d *oscDriver) Close() error {
+	return d.conn.Close()
+}
+
+// frequency gets the frequency of a MIDI note number, in equal temperament with A4 (69) at 440Hz.
+func frequency(note uint8) float32 {
+	return float32(440 * math.Pow(2, float64(int(note)-69)/12))
+}
+
+// translate turns a message into the OSC to send for it, if any.
+func (d *oscDriver) translate(m *msg.Message) *message {
+	inst, ok := d.instruments[m.Instrument]
+	if !ok {
+		return nil // Parts without an instrument still send messages, but there's nowhere to send them.
+	}
+	mm := m.MidiMessage
+	key := soundingKey{m.Instrument, mm.Channel, mm.Data1}
+
+	if inst.synthdef != "" {
+		switch mm.Command {
+		case 0x9:
+			node := d.nextNode
+			d.nextNode += 1
+			d.sounding[key] = node
+			// Add to the head (0) of the default group (1).
+			return &message{"/s_new", []interface{}{
+				inst.synthdef, node, int32(0), int32(1),
+				"freq", frequency(mm.Data1),
+				"amp", float32(mm.Data2) / 127,
+			}}
+		case 0x8:
+			node, ok := d.sounding[key]
+			if !ok {
+				return nil
+			}
+			delete(d.sounding, key)
+			return &message{"/n_set", []interface{}{node, "gate", float32(0)}}
+		}
+		return nil // Synths have no use for controller or program changes.
+	}
+
+	switch mm.Command {
+	case 0x9:
+		d.sounding[key] = 0
+		return &message{inst.address, []interface{}{int32(mm.Channel), int32(mm.Data1), int32(mm.Data2)}}
+	case 0x8:
+		delete(d.sounding, key)
+		return &message{inst.address, []interface{}{int32(mm.Channel), int32(mm.Data1), int32(0)}}
+	case 0xB:
+		return &message{inst.address + "/cc", []interface{}{int32(mm.Channel), int32(mm.Data1), int32(mm.Data2)}}
+	case 0xC:
+		return &message{inst.address + "/pc", []interface{}{int32(mm.Channel), int32(mm.Data1)}}
+	}
+	return nil
+}
+
+// send sends a bundle of messages to be carried out at the given time.
+func (d *oscDriver) send(at time.Time, messages []*message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	data, err := (&bundle{at, messages}).encode()
+	if err != nil {
+		return err
+	}
+	_, err = d.conn.Write(data)
+	if err != nil {
+		return fmt.Errorf("Couldn't send OSC: %v", err)
+	}
+	return nil
+}
+
+// noteOffs sends note offs for the notes that are done at a step. Steps fall at the times
+// due gets, counted from start.
+func (d *oscDriver) noteOffs(buf msg.Buffer, step uint64, start time.Time, due func(step uint64) time.Duration) error {
+	return drivers.NoteOffs(buf, step, due, func(off *msg.Message) error {
+		if m := d.translate(off); m != nil {
+			return d.send(start.Add(due(step)+off.HumanizeTime), []*message{m})
+		}
+		return nil
+	})
+}
+
+// step sends the messages for a step.
+func (d *oscDriver) step(buf msg.Buffer, step uint64, start time.Time, due func(step uint64) time.Duration) error {
+	if !buf.Any() {
+		return nil
+	}
+	if err := d.noteOffs(buf, step, start, due); err != nil {
+		return err
+	}
+
+	next := buf.Next()
+	for i := 0; i < buf.NextLength(); i++ {
+		if m := d.translate(next[i]); m != nil {
+			if err := d.send(start.Add(due(step)+next[i].HumanizeTime), []*message{m}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// stopAll turns off everything still sounding, right away.
+func (d *oscDriver) stopAll() {
+	for key := range d.sounding {
+		off := &msg.Message{Instrument: key.instrument}
+		off.MidiMessage.Command = 0x8
+		off.MidiMessage.Channel = key.channel
+		off.MidiMessage.Data1 = key.note
+		if m := d.translate(off); m != nil {
+			d.send(time.Now(), []*message{m})
+		}
+	}
+}
+
+func (d *oscDriver) Play(part types.Part, tempo *types.TempoMap, ppq int, loop bool, polyphony int, rnd *rand.Rand) error {
+	if err := drivers.CheckLoop(part, ppq, loop); err != nil {
+		return err
+	}
+
+	// Use a buffer big enough to handle all the notes that might be playing at once.
+	buf, err := msg.NewBuffer(polyphony)
+	if err != nil {
+		return err
+	}
+	defer drivers.ReportOverflows(buf)
+
+	// Handle SIGINT and SIGKILL so we can cut off any notes that are still ringing.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, os.Kill)
+	defer signal.Stop(signals)
+
+	defer d.stopAll()
+
+	// Each step is sent as it comes up, to happen a little later, at the time it's due.
+	start := time.Now().Add(d.latency)
+	due := func(step uint64) time.Time {
+		return start.Add(tempo.Time(step))
+	}
+	// wait waits until the given step comes up, and reports whether we were interrupted.
+	wait := func(step uint64) bool {
+		select {
+		case <-time.After(time.Until(due(step).Add(-d.latency))):
+			return false
+		case <-signals:
+			return true
+		}
+	}
+
+	var steps uint64
+	for {
+		// A piece that takes no time (e.g. only pc and cc) still plays step 0, to send its messages.
+		length := part.Length(ppq)
+		for step := uint64(0); step < length || step == 0; step++ {
+			part.Play(buf, rnd, ppq, step)
+			if wait(steps + step) {
+				return nil
+			}
+			if err := d.step(buf, steps+step, start, tempo.Time); err != nil {
+				return err
+			}
+			buf.Flip()
+		}
+		steps += length
+		if !loop {
+			// Stop whatever's still sounding.
+			buf.Release()
+			if wait(steps) {
+				return nil
+			}
+			return d.noteOffs(buf, steps, start, tempo.Time)
+		}
+		fmt.Println("Looping.")
+	}
 }
@@ -0,0 +1,185 @@
+package supercollider
+
+import (
+	"github.com/edemond/abstract/types"
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeMessage(t *testing.T) {
+	m := &message{"/note", []interface{}{int32(1), float32(0.5), "hi"}}
+	data, err := m.encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{
+		'/', 'n', 'o', 't', 'e', 0, 0, 0,
+		',', 'i', 'f', 's', 0, 0, 0, 0,
+		0, 0, 0, 1,
+		0x3F, 0, 0, 0,
+		'h', 'i', 0, 0,
+	}
+	if !bytes.Equal(data, expected) {
+		t.Fatalf("expected % x, got % x", expected, data)
+	}
+}
+
+func TestTimeTag(t *testing.T) {
+	tag := timeTag(time.Unix(1, int64(time.Second/2)))
+	if tag != (ntpEpochOffset+1)<<32|1<<31 {
+		t.Fatalf("expected 1.5s after the Unix epoch, got %x", tag)
+	}
+}
+
+// decoded is a received OSC message, with the time tag of the bundle it came in.
+type decoded struct {
+	time    time.Time
+	address string
+	args    []interface{}
+}
+
+func readString(data []byte) (string, []byte) {
+	end := bytes.IndexByte(data, 0)
+	return string(data[:end]), data[(end/4+1)*4:]
+}
+
+// decode decodes a bundle of messages, as sent by the driver.
+func decode(t *testing.T, data []byte) []decoded {
+	head, data := readString(data)
+	if head != "#bundle" {
+		t.Fatalf("expected a bundle, got %q", head)
+	}
+	tag := binary.BigEndian.Uint64(data)
+	at := time.Unix(int64(tag>>32)-ntpEpochOffset, int64((tag&0xFFFFFFFF)*uint64(time.Second)>>32))
+	data = data[8:]
+
+	result := []decoded{}
+	for len(data) > 0 {
+		size := binary.BigEndian.Uint32(data)
+		m := data[4 : 4+size]
+		data = data[4+size:]
+
+		d := decoded{time: at}
+		var tags string
+		d.address, m = readString(m)
+		tags, m = readString(m)
+		for _, tag := range tags[1:] {
+			switch tag {
+			case 'i':
+				d.args = append(d.args, int32(binary.BigEndian.Uint32(m)))
+				m = m[4:]
+			case 'f':
+				d.args = append(d.args, math.Float32frombits(binary.BigEndian.Uint32(m)))
+				m = m[4:]
+			case 's':
+				var s string
+				s, m = readString(m)
+				d.args = append(d.args, s)
+			}
+		}
+		result = append(result, d)
+	}
+	return result
+}
+
+// play plays a bar of C on an instrument with the given name to a local listener,
+// and returns what it received.
+func play(t *testing.T, name string) []decoded {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	d, err := NewOSCDriver(conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	id, err := d.OpenInstrument(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	part := types.NewSimplePart()
+	part.Rhythm.Meter = &types.Meter{Beats: 4, Value: 4}
+	part.Rhythm.Dynamics = types.NewDynamics(127)
+	part.Rhythm.Gate = types.DefaultGate()
+	part.Instrument = &types.Instrument{ID: id, Channel: 2}
+	pitch, err := types.LookUpPitch("A")
+	if err != nil {
+		t.Fatalf("Error in test: %v", err)
+	}
+	part.Harmony.Pitch = pitch
+
+	// Fast, so the bar only takes 40ms.
+	start := time.Now()
+	err = d.Play(part, types.NewTempoMap(part, 6000, 1), 1, false, 16, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received := []decoded{}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 1024)
+	for len(received) < 2 {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("expected 2 messages, got %v (%v)", len(received), err)
+		}
+		received = append(received, decode(t, buf[:n])...)
+	}
+	if received[0].time.Before(start.Add(latency)) {
+		t.Fatalf("expected the note to be sent ahead of time, to play at %v, but it's tagged %v", start.Add(latency), received[0].time)
+	}
+	if !received[1].time.After(received[0].time) {
+		t.Fatalf("expected the note off to be tagged after the note on, got %v and %v", received[0].time, received[1].time)
+	}
+	return received
+}
+
+func TestNote(t *testing.T) {
+	received := play(t, "/note")
+	on, off := received[0], received[1]
+	if on.address != "/note" || off.address != "/note" {
+		t.Fatalf("expected to send to /note, got %v and %v", on.address, off.address)
+	}
+	if len(on.args) != 3 || on.args[0] != int32(2) || on.args[2] != int32(127) {
+		t.Fatalf("expected channel 2, velocity 127, got %v", on.args)
+	}
+	if off.args[1] != on.args[1] || off.args[2] != int32(0) {
+		t.Fatalf("expected a note off for note %v, got %v", on.args[1], off.args)
+	}
+}
+
+func TestSynth(t *testing.T) {
+	received := play(t, "/s_new piano")
+	on, off := received[0], received[1]
+	if on.address != "/s_new" || len(on.args) != 8 || on.args[0] != "piano" {
+		t.Fatalf("expected /s_new for the piano synthdef, got %v %v", on.address, on.args)
+	}
+	if on.args[4] != "freq" || on.args[6] != "amp" || on.args[7] != float32(1) {
+		t.Fatalf("expected freq and full amp, got %v", on.args)
+	}
+	if off.address != "/n_set" || off.args[0] != on.args[1] || off.args[1] != "gate" {
+		t.Fatalf("expected the synth's gate to be released, got %v %v", off.address, off.args)
+	}
+}
+
+func TestBadInstrumentName(t *testing.T) {
+	d, err := NewOSCDriver("127.0.0.1:57110")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	_, err = d.OpenInstrument("UM-2 MIDI 1 (hw:1,0,0)")
+	if err == nil || !strings.Contains(err.Error(), "address pattern") {
+		t.Fatalf("expected an error about address patterns, got %v", err)
+	}
+}
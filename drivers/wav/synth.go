@@ -0,0 +1,181 @@
+package wav
+
+import (
+	"math"
+	"math/rand"
+)
+
+// oscillator is the basic shape of a sound's wave.
+type oscillator int
+
+const (
+	sine oscillator = iota
+	square
+	saw
+	triangle
+	noise
+)
+
+// sample gets the oscillator's value, from -1 to 1, at the given phase (0 to 1) of a cycle.
+func (o oscillator) sample(phase float64, rnd *rand.Rand) float64 {
+	switch o {
+	case sine:
+		return math.Sin(2 * math.Pi * phase)
+	case square:
+		if phase < 0.5 {
+			return 1
+		}
+		return -1
+	case saw:
+		return 2*phase - 1
+	case triangle:
+		if phase < 0.5 {
+			return 4*phase - 1
+		}
+		return 3 - 4*phase
+	case noise:
+		return 2*rnd.Float64() - 1
+	}
+	panic("Internal error: unknown oscillator")
+}
+
+// envelope is an ADSR envelope. Times are in seconds, sustain is a level from 0 to 1.
+type envelope struct {
+	attack  float64
+	decay   float64
+	sustain float64
+	release float64
+}
+
+// held gets the envelope's level t seconds into a note that's still held.
+func (e envelope) held(t float64) float64 {
+	if t < e.attack {
+		return t / e.attack
+	}
+	t -= e.attack
+	if t < e.decay {
+		return 1 - (1-e.sustain)*t/e.decay
+	}
+	return e.sustain
+}
+
+// level gets the envelope's level t seconds into a note that was released at the given time.
+func (e envelope) level(t float64, released float64) float64 {
+	if t < released {
+		return e.held(t)
+	}
+	if e.release <= 0 {
+		return 0
+	}
+	level := e.held(released) * (1 - (t-released)/e.release)
+	if level < 0 {
+		return 0
+	}
+	return level
+}
+
+// patch is how an instrument sounds.
+type patch struct {
+	name  string
+	osc   oscillator
+	env   envelope
+	gain  float64
+	sweep float64 // If more than 1, the pitch starts this many times higher and falls over the decay, like a drum.
+	freq  float64 // If not 0, the note doesn't change the pitch, like a cymbal.
+}
+
+// Built-in patches. An instrument gets the one named after it, or the next one in this
+// list if there isn't one, so different instruments sound different. pc picks one by number.
+var patches = []*patch{
+	{name: "piano", osc: triangle, env: envelope{0.005, 0.6, 0.3, 0.3}, gain: 1},
+	{name: "organ", osc: square, env: envelope{0.01, 0.05, 0.8, 0.05}, gain: 0.4},
+	{name: "bass", osc: saw, env: envelope{0.005, 0.2, 0.6, 0.08}, gain: 0.6},
+	{name: "pad", osc: saw, env: envelope{0.4, 0.5, 0.7, 0.8}, gain: 0.4},
+	{name: "sine", osc: sine, env: envelope{0.01, 0.1, 0.8, 0.1}, gain: 1},
+	{name: "square", osc: square, env: envelope{0.005, 0.1, 0.7, 0.05}, gain: 0.4},
+	{name: "saw", osc: saw, env: envelope{0.005, 0.1, 0.7, 0.05}, gain: 0.5},
+	{name: "triangle", osc: triangle, env: envelope{0.005, 0.1, 0.8, 0.1}, gain: 1},
+	{name: "pluck", osc: saw, env: envelope{0.002, 0.3, 0, 0.1}, gain: 0.6},
+}
+
+// patchNamed gets the built-in patch with the given name, or nil.
+func patchNamed(name string) *patch {
+	for _, p := range patches {
+		if p.name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// The drum kit, for an instrument called "drums" or anything on channel 10. The notes are
+// General MIDI's.
+var (
+	kick   = &patch{name: "kick", osc: sine, env: envelope{0.001, 0.3, 0, 0.05}, gain: 1.5, sweep: 4}
+	tom    = &patch{name: "tom", osc: sine, env: envelope{0.001, 0.4, 0, 0.1}, gain: 1.2, sweep: 2}
+	snare  = &patch{name: "snare", osc: noise, env: envelope{0.001, 0.2, 0, 0.05}, gain: 0.8}
+	clap   = &patch{name: "clap", osc: noise, env: envelope{0.005, 0.15, 0, 0.05}, gain: 0.7}
+	hihat  = &patch{name: "hihat", osc: noise, env: envelope{0.001, 0.05, 0, 0.02}, gain: 0.4}
+	openhh = &patch{name: "openhihat", osc: noise, env: envelope{0.001, 0.4, 0, 0.1}, gain: 0.4}
+	cymbal = &patch{name: "cymbal", osc: noise, env: envelope{0.001, 1.2, 0, 0.3}, gain: 0.3}
+	bell   = &patch{name: "bell", osc: square, env: envelope{0.001, 0.3, 0, 0.1}, gain: 0.3, freq: 800}
+)
+
+// drum gets the patch for a note on the drum kit.
+func drum(note int) *patch {
+	switch note {
+	case 35, 36:
+		return kick
+	case 38, 40:
+		return snare
+	case 37, 39:
+		return clap
+	case 41, 43, 45, 47, 48, 50:
+		return tom
+	case 42, 44:
+		return hihat
+	case 46:
+		return openhh
+	case 49, 51, 52, 55, 57, 59:
+		return cymbal
+	case 53, 56:
+		return bell
+	}
+	return snare
+}
+
+// frequency gets the frequency of a MIDI note number, in equal temperament with A4 (69) at 440Hz.
+func frequency(note int) float64 {
+	return 440 * math.Pow(2, float64(note-69)/12)
+}
+
+// voice is one note being synthesized.
+type voice struct {
+	patch    *patch
+	freq     float64
+	amp      float64 // From the velocity.
+	start    int     // Sample the note starts on.
+	released int     // Sample the note is released on.
+}
+
+// render adds the voice into out, which is at the given sample rate, until its release is done.
+func (v *voice) render(out []float64, rate int, rnd *rand.Rand) {
+	p := v.patch
+	released := float64(v.released-v.start) / float64(rate)
+	end := v.released + int(p.env.release*float64(rate)) + 1
+	if end > len(out) {
+		end = len(out)
+	}
+	phase := 0.0
+	for i := v.start; i < end; i++ {
+		t := float64(i-v.start) / float64(rate)
+		freq := v.freq
+		if p.sweep > 1 {
+			// Fall from sweep times the pitch to the pitch over the decay.
+			freq *= 1 + (p.sweep-1)*math.Exp(-5*t/p.env.decay)
+		}
+		out[i] += p.osc.sample(phase, rnd) * p.env.level(t, released) * v.amp * p.gain
+		phase += freq / float64(rate)
+		phase -= math.Floor(phase)
+	}
+}
@@ -0,0 +1,205 @@
+// Package wav implements an Abstract driver that synthesizes the piece itself and renders it
+// to a WAV file, so there's something to listen to without any MIDI gear or softsynths.
+//
+// The synthesizer is deliberately simple: each note is one oscillator through an ADSR envelope.
+// How an instrument sounds (its patch) comes from its name, e.g. instrument("bass", 1, 4),
+// and a program change picks a patch by number. An instrument named "drums", or anything
+// on channel 10, plays a drum kit laid out like General MIDI's.
+package wav
+
+import (
+	"github.com/edemond/abstract/drivers"
+	"github.com/edemond/abstract/msg"
+	"github.com/edemond/abstract/types"
+	"bufio"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"time"
+)
+
+const sampleRate = 44100
+
+// How loud one note at full velocity is, leaving headroom for chords.
+const voiceGain = 0.3
+
+// Unique Instrument ID to be incremented each time we assign one.
+var instrumentID int
+
+type instrument struct {
+	name     string
+	patch    *patch
+	drums    bool
+	programs map[uint8]*patch // Channel -> patch picked with a program change.
+}
+
+// soundingKey identifies a sounding note so its note off can find it.
+type soundingKey struct {
+	instrument int
+	channel    uint8
+	note       uint8
+}
+
+type wavDriver struct {
+	filename    string
+	instruments map[int]*instrument // Instrument ID -> instrument
+}
+
+// NewWAVDriver creates a driver that synthesizes the piece to the given file when played.
+func NewWAVDriver(filename string) (drivers.Driver, error) {
+	return &wavDriver{
+		filename:    filename,
+		instruments: make(map[int]*instrument),
+	}, nil
+}
+
+func (d *wavDriver) OpenInstrument(name string) (int, error) {
+	id := instrumentID
+	inst := &instrument{
+		name:     name,
+		patch:    patchNamed(name),
+		drums:    name == "drums",
+		programs: make(map[uint8]*patch),
+	}
+	if inst.patch == nil {
+		// Not a patch name (e.g. it's a MIDI device name), so give it the next patch in line.
+		inst.patch = patches[len(d.instruments)%len(patches)]
+	}
+	d.instruments[id] = inst
+	instrumentID += 1
+	return id, nil
+}
+
+func (d *wavDriver) CloseInstrument(id int) error {
+	_, ok := d.instruments[id]
+	if !ok {
+		panic(fmt.Sprintf("Internal error: Couldn't close instrument; no instrument with ID %v is open", id))
+	}
+	delete(d.instruments, id)
+	return nil
+}
+
+func (d *wavDriver) Close() error {
+	return nil
+}
+
+// patch gets the patch to play a note with on an instrument and channel.
+func (d *wavDriver) patch(id int, channel uint8, note uint8) *patch {
+	inst, ok := d.instruments[id]
+	if !ok {
+		return patches[0] // Parts without an instrument still send messages.
+	}
+	if inst.drums || channel == 10 {
+		return drum(int(note))
+	}
+	if p, ok := inst.programs[channel]; ok {
+		return p
+	}
+	return inst.patch
+}
+
+// program handles a program change, picking a built-in patch by number.
+func (d *wavDriver) program(id int, channel uint8, program uint8) {
+	inst, ok := d.instruments[id]
+	if !ok {
+		return
+	}
+	inst.programs[channel] = patches[int(program)%len(patches)]
+}
+
+// Render the piece to the file. Looping makes no sense here, so the piece is rendered once.
+func (d *wavDriver) Play(part types.Part, tempo *types.TempoMap, ppq int, loop bool, polyphony int, rnd *rand.Rand) error {
+	if ppq <= 0 {
+		return fmt.Errorf("Can't render a piece with a PPQ of %v.", ppq)
+	}
+
+	voices := []*voice{}
+	sounding := make(map[soundingKey]*voice)
+	err := drivers.Render(part, tempo, ppq, polyphony, rnd, func(step uint64, m *msg.Message) {
+		// Humanize moves everything, note offs included, off the step.
+		at := int((tempo.Time(step) + m.HumanizeTime) * sampleRate / time.Second)
+		if at < 0 {
+			at = 0
+		}
+		mm := m.MidiMessage
+		key := soundingKey{m.Instrument, mm.Channel, mm.Data1}
+		switch mm.Command {
+		case 0x9:
+			p := d.patch(m.Instrument, mm.Channel, mm.Data1)
+			freq := frequency(int(mm.Data1))
+			if p.freq != 0 {
+				freq = p.freq
+			}
+			v := &voice{
+				patch: p,
+				freq:  freq,
+				amp:   voiceGain * float64(mm.Data2) / 127,
+				start: at,
+			}
+			voices = append(voices, v)
+			sounding[key] = v
+		case 0x8:
+			v, ok := sounding[key]
+			if !ok {
+				return
+			}
+			v.released = at
+			if v.released < v.start {
+				v.released = v.start
+			}
+			delete(sounding, key)
+		case 0xC:
+			d.program(m.Instrument, mm.Channel, mm.Data1)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	samples := mix(voices, int(tempo.Time(part.Length(ppq))*sampleRate/time.Second), rnd)
+
+	f, err := os.Create(d.filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	err = Write(w, samples, sampleRate)
+	if err != nil {
+		return err
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %v.\n", d.filename)
+	return nil
+}
+
+// mix synthesizes all the voices together. The result is at least length samples long, and
+// longer if notes ring on past the end. If it would clip, it's turned down to fit.
+func mix(voices []*voice, length int, rnd *rand.Rand) []float64 {
+	for _, v := range voices {
+		end := v.released + int(v.patch.env.release*sampleRate) + 1
+		if end > length {
+			length = end
+		}
+	}
+	out := make([]float64, length)
+	for _, v := range voices {
+		v.render(out, sampleRate, rnd)
+	}
+
+	peak := 0.0
+	for _, s := range out {
+		peak = math.Max(peak, math.Abs(s))
+	}
+	if peak > 1 {
+		for i := range out {
+			out[i] /= peak
+		}
+	}
+	return out
+}
@@ -0,0 +1,134 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func expectClose(t *testing.T, what string, actual, expected float64) {
+	if math.Abs(actual-expected) > 1e-9 {
+		t.Fatalf("expected %v to be %v, got %v", what, expected, actual)
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	env := envelope{attack: 0.1, decay: 0.2, sustain: 0.5, release: 0.4}
+	expectClose(t, "start", env.level(0, 1), 0)
+	expectClose(t, "halfway through the attack", env.level(0.05, 1), 0.5)
+	expectClose(t, "peak", env.level(0.1, 1), 1)
+	expectClose(t, "halfway through the decay", env.level(0.2, 1), 0.75)
+	expectClose(t, "sustain", env.level(0.5, 1), 0.5)
+	expectClose(t, "halfway through the release", env.level(1.2, 1), 0.25)
+	expectClose(t, "after the release", env.level(2, 1), 0)
+}
+
+func TestReleaseDuringAttack(t *testing.T) {
+	env := envelope{attack: 0.1, decay: 0.2, sustain: 0.5, release: 0.1}
+	// Released at half the peak, so the release falls from there.
+	expectClose(t, "halfway through the release", env.level(0.1, 0.05), 0.25)
+}
+
+func TestFrequency(t *testing.T) {
+	expectClose(t, "A4", frequency(69), 440)
+	expectClose(t, "A5", frequency(81), 880)
+	if c := frequency(60); math.Abs(c-261.6256) > 0.001 {
+		t.Fatalf("expected middle C to be 261.626Hz, got %v", c)
+	}
+}
+
+func TestVoiceSoundsUntilReleaseIsDone(t *testing.T) {
+	rate := 1000
+	v := &voice{
+		patch:    &patch{osc: square, env: envelope{0.001, 0.001, 1, 0.1}, gain: 1},
+		freq:     10,
+		amp:      0.5,
+		start:    100,
+		released: 200,
+	}
+	out := make([]float64, 500)
+	v.render(out, rate, rand.New(rand.NewSource(1)))
+	for i, s := range out {
+		sounding := s != 0
+		if i < 100 && sounding {
+			t.Fatalf("expected silence before the note, got %v at sample %v", s, i)
+		}
+		if i >= 300 && sounding {
+			t.Fatalf("expected silence after the release, got %v at sample %v", s, i)
+		}
+	}
+	if math.Abs(out[150]) != 0.5 {
+		t.Fatalf("expected the held note at full amplitude, got %v", out[150])
+	}
+}
+
+func TestMixTurnsDownClipping(t *testing.T) {
+	p := &patch{osc: square, env: envelope{0.001, 0.001, 1, 0}, gain: 1}
+	voices := []*voice{
+		{patch: p, freq: 1, amp: 1, start: 0, released: 50000},
+		{patch: p, freq: 1, amp: 1, start: 0, released: 50000},
+	}
+	out := mix(voices, 100, rand.New(rand.NewSource(1)))
+	for _, s := range out {
+		if math.Abs(s) > 1 {
+			t.Fatalf("expected the mix to be turned down to fit, got %v", s)
+		}
+	}
+	if len(out) < 50000 {
+		t.Fatalf("expected the mix to last until the notes stop, got %v samples", len(out))
+	}
+}
+
+func TestWriteWAV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, []float64{0, 1, -1, 2}, 8000); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	if len(b) != 44+8 {
+		t.Fatalf("expected a 44 byte header and 8 bytes of samples, got %v bytes", len(b))
+	}
+	if string(b[0:4]) != "RIFF" || string(b[8:16]) != "WAVEfmt " || string(b[36:40]) != "data" {
+		t.Fatalf("bad header: % x", b[:44])
+	}
+	if rate := binary.LittleEndian.Uint32(b[24:28]); rate != 8000 {
+		t.Fatalf("expected a sample rate of 8000, got %v", rate)
+	}
+	samples := make([]int16, 4)
+	binary.Read(bytes.NewReader(b[44:]), binary.LittleEndian, samples)
+	expected := []int16{0, math.MaxInt16, -math.MaxInt16, math.MaxInt16}
+	for i := range samples {
+		if samples[i] != expected[i] {
+			t.Fatalf("expected samples %v, got %v", expected, samples)
+		}
+	}
+}
+
+func TestInstrumentPatches(t *testing.T) {
+	d, _ := NewWAVDriver("test.wav")
+	w := d.(*wavDriver)
+	bass, _ := w.OpenInstrument("bass")
+	other, _ := w.OpenInstrument("UM-2 MIDI 1 (hw:1,0,0)")
+	drums, _ := w.OpenInstrument("drums")
+	if p := w.patch(bass, 1, 60); p.name != "bass" {
+		t.Fatalf("expected the bass patch, got %v", p.name)
+	}
+	if p := w.patch(other, 1, 60); p.name != patches[1].name {
+		t.Fatalf("expected the second instrument to get the second patch, got %v", p.name)
+	}
+	if p := w.patch(drums, 1, 36); p != kick {
+		t.Fatalf("expected a kick drum, got %v", p.name)
+	}
+	if p := w.patch(other, 10, 42); p != hihat {
+		t.Fatalf("expected channel 10 to play drums, got %v", p.name)
+	}
+	w.program(bass, 2, 3)
+	if p := w.patch(bass, 2, 60); p != patches[3] {
+		t.Fatalf("expected the program change to pick %v, got %v", patches[3].name, p.name)
+	}
+	if p := w.patch(bass, 1, 60); p.name != "bass" {
+		t.Fatalf("expected the program change to only affect its channel, got %v", p.name)
+	}
+}
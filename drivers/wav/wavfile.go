@@ -0,0 +1,44 @@
+package wav
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// Write writes mono samples (-1 to 1) out as a 16-bit PCM WAV file at the given sample rate.
+// Anything louder than full scale is clipped.
+func Write(w io.Writer, samples []float64, rate int) error {
+	const (
+		channels = 1
+		bits     = 16
+	)
+	size := len(samples) * channels * bits / 8
+	header := []interface{}{
+		[]byte("RIFF"),
+		uint32(36 + size),
+		[]byte("WAVE"),
+		[]byte("fmt "),
+		uint32(16),                         // fmt chunk length
+		uint16(1),                          // PCM
+		uint16(channels),                   // channels
+		uint32(rate),                       // sample rate
+		uint32(rate * channels * bits / 8), // bytes per second
+		uint16(channels * bits / 8),        // bytes per frame
+		uint16(bits),                       // bits per sample
+		[]byte("data"),
+		uint32(size),
+	}
+	for _, field := range header {
+		if err := binary.Write(w, binary.LittleEndian, field); err != nil {
+			return err
+		}
+	}
+
+	data := make([]int16, len(samples))
+	for i, s := range samples {
+		s = math.Max(-1, math.Min(1, s))
+		data[i] = int16(math.Round(s * math.MaxInt16))
+	}
+	return binary.Write(w, binary.LittleEndian, data)
+}
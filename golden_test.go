@@ -0,0 +1,115 @@
+package main
+
+import (
+	"github.com/edemond/abstract/drivers/dump"
+	"github.com/edemond/abstract/parser"
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the existing golden files in testdata/golden from the tunes")
+
+// The tunes that don't play, and why. They're skipped, but still tried, so one that plays
+// again has to come off the list. A tune with a golden file can't be on it.
+var brokenTunes = map[string]string{
+	"arp":             "redefines the built-in major and minor scales",
+	"arp2":            "redefines the built-in major scale",
+	"block_expr_test": "gives a part two pitches and uses an undefined name",
+	"chords":          "redefines the built-in mixolydian scale",
+	"comments":        "has no instruments",
+	"lex_no_newline":  "is lexer test input, with arp(stuff) and no instruments",
+	"lexer_test":      "is lexer test input, with arp(stuff) and no instruments",
+	"meter":           "uses an undefined name",
+	"meterex":         "has no instruments",
+	"scale":           "redefines the built-in major scale",
+	"syntaxerror":     "has errors on purpose",
+	"x1":              "calls a let without all of its arguments",
+}
+
+// Every tune in tunes/ that has a golden file is dumped with the same seed and compared with it,
+// so any change in what gets played shows up. Run "go test -update" to accept changes; that
+// only rewrites the golden files there already are, so to check one in for a new tune, make
+// an empty one first. Only the short tunes have them; the rest only have to play.
+func TestTunesGolden(t *testing.T) {
+	tunes, err := filepath.Glob(filepath.Join("tunes", "*.abs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tune := range tunes {
+		name := strings.TrimSuffix(filepath.Base(tune), ".abs")
+		t.Run(name, func(t *testing.T) {
+			golden := filepath.Join("testdata", "golden", name+".txt")
+			_, err := os.Stat(golden)
+			hasGolden := err == nil
+			reason, broken := brokenTunes[name]
+			if broken && hasGolden {
+				t.Fatalf("has a golden file, but is listed as broken (%v)", reason)
+			}
+			fail := t.Fatalf
+			if broken {
+				fail = t.Skipf
+			}
+
+			p, err := parser.FromFile(tune)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stmt, err := p.Parse()
+			if err != nil {
+				fail("doesn't parse: %v", err)
+			}
+
+			var out bytes.Buffer
+			driver := dump.NewDumpDriver(&out)
+			part, polyphony, tempo, ppq, err := analyze(tune, stmt, driver)
+			if err != nil {
+				fail("doesn't compile: %v", err)
+			}
+			if polyphony == 0 {
+				fail("has no instruments to play")
+			}
+			err = driver.Play(part, tempo, ppq, false, polyphony, rand.New(rand.NewSource(1)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if broken {
+				t.Fatalf("plays now, so take it off brokenTunes (%v)", reason)
+			}
+
+			if !hasGolden {
+				return
+			}
+			if *update {
+				if err := ioutil.WriteFile(golden, out.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			expected, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectSameLines(t, string(expected), out.String())
+		})
+	}
+}
+
+// expectSameLines fails at the first line that differs, since a dump can be long.
+func expectSameLines(t *testing.T, expected string, actual string) {
+	e := strings.Split(expected, "\n")
+	a := strings.Split(actual, "\n")
+	for i := 0; i < len(e) && i < len(a); i++ {
+		if e[i] != a[i] {
+			t.Fatalf("line %v: expected\n\t%v\ngot\n\t%v", i+1, e[i], a[i])
+		}
+	}
+	if len(e) != len(a) {
+		t.Fatalf("expected %v lines, got %v", len(e), len(a))
+	}
+}
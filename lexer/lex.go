@@ -39,6 +39,10 @@ const (
 
 	LBRACKET // [
 	RBRACKET // ]
+
+	IMPORT // import
+
+	MELODY // mel"1 3 5"
 )
 
 func (t Token) String() string {
@@ -83,6 +87,10 @@ func (t Token) String() string {
 		return "bpm"
 	case PPQ:
 		return "ppq"
+	case IMPORT:
+		return "import"
+	case MELODY:
+		return "melody"
 	default:
 		panic("unknown token type")
 	}
@@ -94,6 +102,7 @@ var keywords = map[string]Token{
 	"default": DEFAULT,
 	"bpm":     BPM,
 	"ppq":     PPQ,
+	"import":  IMPORT,
 }
 
 type Lexer struct {
@@ -101,6 +110,7 @@ type Lexer struct {
 	start  int  // start position
 	end    int  // end position
 	line   int  // current line
+	ended  bool // whether the last token was a newline ending the current line
 	last   rune // last non-whitespace char (TODO: It'd be easier if this were a token.)
 	char   rune
 }
@@ -278,6 +288,11 @@ func (lex *Lexer) shouldIgnoreNewline() bool {
 func (lex *Lexer) Scan() (tok Token, val string, err error) {
 	keepGoing := true
 
+	if lex.ended {
+		lex.line++
+		lex.ended = false
+	}
+
 	for keepGoing {
 		keepGoing = false
 
@@ -291,16 +306,19 @@ func (lex *Lexer) Scan() (tok Token, val string, err error) {
 		// them to the parser), but are ignored as whitespace the rest of the time.
 		if lex.char == '\n' {
 			// Found a newline, do we yield it (as the statement terminator) or skip it?
-			lex.line++
-
 			if !lex.shouldIgnoreNewline() {
-				// Statement terminator.
+				// Statement terminator. It's still on the line it ends (so the statement the
+				// parser finishes with it is too); we move on to the next line with the next token.
+				lex.ended = true
 				val = string(lex.char)
 				lex.next()
 				return NEWLINE, val, nil
 			}
 
 			for (lex.shouldIgnoreNewline() && lex.char == '\n') || isWhitespace(lex.char) {
+				if lex.char == '\n' {
+					lex.line++
+				}
 				if err = lex.next(); err != nil {
 					return INVALID, string(lex.char), err
 				}
@@ -335,6 +353,14 @@ func (lex *Lexer) Scan() (tok Token, val string, err error) {
 			}
 		case isLetter(ch) || chord.IsChordNotationSymbol(ch) || ch == '_':
 			val, err = lex.scanIdent()
+			if err == nil && val == "mel" && lex.char == '"' {
+				// A melody literal, e.g. mel"1 3 5". Its notes are parsed in the analyzer.
+				if err = lex.next(); err != nil {
+					return INVALID, val, err
+				}
+				val, err = lex.scanString()
+				return MELODY, val, err
+			}
 			tok = getTextTokenType(val)
 			return tok, val, err
 		case isDigit(ch):
@@ -159,6 +159,11 @@ func TestLet(t *testing.T) {
 	expect(t, tok, val, err, LET, "let")
 }
 
+func TestImport(t *testing.T) {
+	tok, val, err := lex(t, `import`)
+	expect(t, tok, val, err, IMPORT, "import")
+}
+
 func TestIdent(t *testing.T) {
 	tok, val, err := lex(t, `mixolydian`)
 	expect(t, tok, val, err, IDENT, "mixolydian")
@@ -184,7 +189,37 @@ func TestString(t *testing.T) {
 	expect(t, tok, val, err, STRING, "a string, dude")
 }
 
+func TestMelody(t *testing.T) {
+	tok, val, err := lex(t, `mel"1 3 5 _ 4 3 2 ."`)
+	expect(t, tok, val, err, MELODY, "1 3 5 _ 4 3 2 .")
+}
+
+func TestMelIsStillAnIdent(t *testing.T) {
+	lexer := FromBytes([]byte(`mel "1 3 5"`))
+	scanAndExpect(t, lexer, IDENT, "mel")
+	scanAndExpect(t, lexer, STRING, "1 3 5")
+}
+
 func TestRest(t *testing.T) {
 	tok, val, err := lex(t, `_`)
 	expect(t, tok, val, err, IDENT, "_")
 }
+
+func TestLineNumbers(t *testing.T) {
+	lexer := FromBytes([]byte("a\n\n\nb\n// comment\n\nc\n"))
+	scanAndExpect(t, lexer, IDENT, "a")
+	scanAndExpect(t, lexer, NEWLINE, "\n")
+	// The newline ending a statement is still on that statement's line.
+	if lexer.Line() != 1 {
+		t.Fatalf("expected line 1 at the end of the first statement, got %v", lexer.Line())
+	}
+	scanAndExpect(t, lexer, IDENT, "b")
+	if lexer.Line() != 4 {
+		t.Fatalf("expected line 4 after blank lines, got %v", lexer.Line())
+	}
+	scanAndExpect(t, lexer, NEWLINE, "\n")
+	scanAndExpect(t, lexer, IDENT, "c")
+	if lexer.Line() != 7 {
+		t.Fatalf("expected line 7 after a comment and a blank line, got %v", lexer.Line())
+	}
+}
@@ -1,28 +1,40 @@
 // Abstract implements an interpreter for a higher-level music notation and a player
-// that emits MIDI.
+// that emits MIDI (or renders audio itself).
 package main
 
 import (
 	"github.com/edemond/abstract/ast"
 	"github.com/edemond/abstract/drivers"
 	"github.com/edemond/abstract/drivers/alsa"
+	"github.com/edemond/abstract/drivers/dump"
 	"github.com/edemond/abstract/drivers/jack"
+	"github.com/edemond/abstract/drivers/sf2"
+	"github.com/edemond/abstract/drivers/smf"
+	"github.com/edemond/abstract/drivers/supercollider"
+	"github.com/edemond/abstract/drivers/wav"
 	"github.com/edemond/abstract/parser"
 	"github.com/edemond/abstract/types"
 	"github.com/edemond/midi"
 	"flag"
 	"fmt"
 	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
 const VERSION = "0.0.2"
 
 // TODO: We need a way of listing potential drivers (and whether or not they'd work on the target system?)
-var driverFlag = flag.String("d", "rawmidi", "\tMIDI driver (rawmidi).")
+var driverFlag = flag.String("d", "rawmidi", "\tDriver (rawmidi, seq, jack, osc, smf, wav, sf2, dump).")
 var modeListDevices = flag.Bool("a", false, "\tList available ALSA MIDI device names.")
 var modeVersion = flag.Bool("v", false, "\tPrint version information.")
 var loopFlag = flag.Bool("l", false, "\tLoop (Ctrl+C to stop).")
+var outFlag = flag.String("o", "", "\tOutput file, for drivers that write to a file (default: named after the input file).")
+var soundfontFlag = flag.String("sf", "", "\tSoundFont (.sf2) file, for the sf2 driver.")
+var oscFlag = flag.String("osc", supercollider.DefaultAddress, "\tUDP address (host:port) to send to, for the osc driver.")
+var seedFlag = flag.Int64("seed", 0, "\tRandom seed, to play a piece the same way every time (default: seeded from the clock).")
 
 func listDevices() error {
 	// TODO: Let the user choose which driver to list devices for at the command line.
@@ -51,25 +63,52 @@ func parse(filename string) (*ast.PlayStatement, error) {
 }
 
 // Perform semantic analysis on an AST.
-// Returns root part, total polyphony, BPM, PPQ, error.
-func analyze(stmt *ast.PlayStatement, driver drivers.Driver) (types.Part, int, int, int, error) {
+// Returns root part, total polyphony, tempo map, PPQ, error.
+func analyze(filename string, stmt *ast.PlayStatement, driver drivers.Driver) (types.Part, int, *types.TempoMap, int, error) {
 	a := NewAnalyzer()
-	part, err := a.Analyze(stmt)
+	part, err := a.AnalyzeFile(filename, stmt)
 	if err != nil {
-		return nil, 0, 0, 0, err
+		return nil, 0, nil, 0, err
 	}
 
 	// Open instruments.
 	insts, err := a.OpenInstruments(driver)
 	if err != nil {
-		return nil, 0, 0, 0, err
+		return nil, 0, nil, 0, err
 	}
-	return part, types.TotalVoices(insts), a.bpm, a.ppq, nil
+	return part, types.TotalVoices(insts), types.NewTempoMap(part, a.bpm, a.ppq), a.ppq, nil
+}
+
+// check parses and analyzes a file without opening a driver, and prints every error in it.
+// Returns whether the file is okay.
+func check(filename string) bool {
+	p, err := parser.FromFile(filename)
+	if err != nil {
+		fmt.Printf("%v: %v\n", filename, err)
+		return false
+	}
+	stmt, err := p.Parse()
+	if err != nil {
+		fmt.Printf("%v: %v\n", filename, err)
+		return false
+	}
+	_, err = NewAnalyzer().AnalyzeFile(filename, stmt)
+	if errs, ok := err.(analysisErrors); ok {
+		for _, e := range errs {
+			fmt.Printf("%v: %v\n", filename, e)
+		}
+		return false
+	} else if err != nil {
+		fmt.Printf("%v: %v\n", filename, err)
+		return false
+	}
+	return true
 }
 
 func printUsage() {
-	fmt.Println("usage: abstract <file.abs>\n")
-	fmt.Println("commands:\n")
+	fmt.Println("usage: abstract <file.abs>")
+	fmt.Print("       abstract check <file.abs>...  (check files for errors without playing them)\n\n")
+	fmt.Print("commands:\n\n")
 	flag.PrintDefaults()
 	fmt.Println("")
 }
@@ -84,14 +123,65 @@ var _drivers = map[string]supportedDriver{
 		"ALSA 'rawmidi' driver.",
 		alsa.NewRawMidiDriver,
 	},
+	"seq": {
+		"ALSA sequencer driver, with a virtual port per instrument.",
+		alsa.NewSeqDriver,
+	},
 	"jack": {
 		"JACK 1.x driver.",
 		jack.NewJACKDriver,
 	},
+	"osc": {
+		"Open Sound Control over UDP, e.g. to SuperCollider (the address given with -osc).",
+		func() (drivers.Driver, error) {
+			return supercollider.NewOSCDriver(*oscFlag)
+		},
+	},
+	"smf": {
+		"Standard MIDI File export.",
+		func() (drivers.Driver, error) {
+			return smf.NewSMFDriver(outputFilename(".mid"))
+		},
+	},
+	"wav": {
+		"Built-in synthesizer, rendering to a WAV file.",
+		func() (drivers.Driver, error) {
+			return wav.NewWAVDriver(outputFilename(".wav"))
+		},
+	},
+	"sf2": {
+		"SoundFont player (the .sf2 file given with -sf), rendering to a WAV file.",
+		func() (drivers.Driver, error) {
+			return sf2.NewSF2Driver(*soundfontFlag, outputFilename(".wav"))
+		},
+	},
+	"dump": {
+		"Text dump of every message, to the file given with -o or the terminal.",
+		func() (drivers.Driver, error) {
+			if *outFlag == "" {
+				return dump.NewDumpDriver(os.Stdout), nil
+			}
+			f, err := os.Create(*outFlag)
+			if err != nil {
+				return nil, err
+			}
+			return dump.NewDumpDriver(f), nil
+		},
+	},
+}
+
+// outputFilename gets the file to write to for drivers that write to a file: either
+// what the user passed with -o, or the input file with the given extension instead.
+func outputFilename(ext string) string {
+	if *outFlag != "" {
+		return *outFlag
+	}
+	name := filepath.Base(flag.Arg(0))
+	return strings.TrimSuffix(name, filepath.Ext(name)) + ext
 }
 
 func printSupportedDrivers() {
-	fmt.Println("Supported drivers:\n")
+	fmt.Print("Supported drivers:\n\n")
 	for name, driver := range _drivers {
 		fmt.Printf("\t%v\t\t%v\n", name, driver.description)
 	}
@@ -111,10 +201,24 @@ func getDriver(name string) (drivers.Driver, error) {
 	return driver, nil
 }
 
+// seed returns the seed given with -seed, or one from the clock if there wasn't one.
+func seed() int64 {
+	seeded := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			seeded = true
+		}
+	})
+	if seeded {
+		return *seedFlag
+	}
+	return time.Now().UnixNano()
+}
+
 func main() {
 	flag.Parse()
 
-	rand.Seed(time.Now().UnixNano())
+	rnd := rand.New(rand.NewSource(seed()))
 
 	if *modeListDevices {
 		err := listDevices()
@@ -132,6 +236,22 @@ func main() {
 		printUsage()
 		return
 	}
+	if args[0] == "check" {
+		if len(args) < 2 {
+			printUsage()
+			os.Exit(1)
+		}
+		ok := true
+		for _, filename := range args[1:] {
+			if !check(filename) {
+				ok = false
+			}
+		}
+		if !ok {
+			os.Exit(1)
+		}
+		return
+	}
 	filename := args[0]
 
 	stmt, err := parse(filename)
@@ -151,13 +271,13 @@ func main() {
 
 	// TODO: Really, five return values? Can we put this into a struct
 	// that analyze and driver.Play use to communicate?
-	part, polyphony, bpm, ppq, err := analyze(stmt, driver)
+	part, polyphony, tempo, ppq, err := analyze(filename, stmt, driver)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 
-	err = driver.Play(part, bpm, ppq, *loopFlag, polyphony)
+	err = driver.Play(part, tempo, ppq, *loopFlag, polyphony, rnd)
 	if err != nil {
 		fmt.Println(err)
 		return
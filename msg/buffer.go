@@ -6,6 +6,8 @@ import (
 )
 
 // Buffer is where Messages are collected as the song is being played.
+// At each step, parts Add the messages to send. The driver then turns off the notes in
+// Last, sends the messages in Next, and calls Flip to move on to the next step.
 type Buffer interface {
 	Add(msg *Message)
 	Any() bool
@@ -14,21 +16,33 @@ type Buffer interface {
 	LastLength() int
 	Next() []*Message
 	NextLength() int
+	Release()
 	Sort()
+	Sounding(instrument int) []*Message
 	Print()
+	Overflows() int
 }
 
 const MAX_BUFFER_SIZE = 8192 // completely arbitrary, hopefully no one needs this many voices of polyphony
 
-// We use double-buffering so that we know what to note-off when it's time to note-on new stuff.
-// That is, at any one time step, there are two buffers for each instrument, "next" and "last".
-// "next" holds the notes to play next, and "last" has the notes we just played. At each step,
-// we play the notes in "next", stop the notes in "last", then just swap the buffers.
-type doubleBuffer struct {
+// noteBuffer keeps track of which notes are sounding and how long they have left, so that
+// it knows what to note-off at each step. "next" holds the messages to send at this step,
+// and "last" holds the note ons whose time is up, to be turned off at this step.
+type noteBuffer struct {
 	next, last *buffer
+	sounding   []sounding
+	step       uint64 // How many times it's been flipped.
+	overflows  int    // How many notes didn't fit, and were dropped or cut short.
 }
 
-// Make a note buffer per instrument, each sized to the number of voices of polyphony that instrument has.
+// A note on that's been sent, and the number of steps until it's turned off.
+type sounding struct {
+	msg  *Message
+	left uint64
+}
+
+// Make a note buffer sized to the number of voices of polyphony of all the instruments.
+// Only notes count against that; other messages (e.g. pc and cc) always fit.
 func NewBuffer(size int) (Buffer, error) {
 	if (size <= 0) || (size > MAX_BUFFER_SIZE) {
 		return nil, fmt.Errorf(
@@ -39,75 +53,228 @@ func NewBuffer(size int) (Buffer, error) {
 		)
 	}
 
-	return &doubleBuffer{
-		next: &buffer{buf: make([]*Message, size)},
-		last: &buffer{buf: make([]*Message, size)},
+	return &noteBuffer{
+		next:     &buffer{buf: make([]*Message, size), size: size},
+		last:     &buffer{buf: make([]*Message, size), size: size},
+		sounding: make([]sounding, 0, size),
 	}, nil
 }
 
-func (b *doubleBuffer) Add(msg *Message) {
+// Add a message to send at this step. A note on for a key that's still sounding
+// turns that note off first, so the key can be struck again. A note on for an instrument
+// with all of its voices in use steals one, as its Voices say, or isn't played at all.
+func (b *noteBuffer) Add(msg *Message) {
+	if msg.IsNoteOn() {
+		msg.Step = b.step
+		for i := 0; i < len(b.sounding); i++ {
+			if b.sounding[i].msg.SameKey(msg) {
+				b.last.Add(b.sounding[i].msg)
+				b.sounding = append(b.sounding[:i], b.sounding[i+1:]...)
+				break
+			}
+		}
+		if msg.Voices != nil && msg.Voices.Count > 0 && !b.allocate(msg) {
+			return
+		}
+		if b.next.notes >= b.next.size {
+			b.overflows++
+			return
+		}
+	}
 	b.next.Add(msg)
 }
 
-func (b *doubleBuffer) Any() bool {
-	return b.next.Any()
+// allocate makes room for a note on among its instrument's voices, stealing one if they're
+// all in use. It reports whether there's room for the note on.
+func (b *noteBuffer) allocate(msg *Message) bool {
+	playing := b.Sounding(msg.Instrument)
+	if len(playing) < msg.Voices.Count {
+		return true
+	}
+
+	// The playing notes are oldest first, so on a tie, the oldest is stolen.
+	victim := playing[0]
+	switch msg.Voices.Steal {
+	case StealNone:
+		return false
+	case StealQuietest:
+		for _, m := range playing {
+			if m.MidiMessage.Data2 < victim.MidiMessage.Data2 {
+				victim = m
+			}
+		}
+		if msg.MidiMessage.Data2 < victim.MidiMessage.Data2 {
+			return false
+		}
+	case StealLowest:
+		for _, m := range playing {
+			if m.Priority < victim.Priority {
+				victim = m
+			}
+		}
+		if msg.Priority < victim.Priority {
+			return false
+		}
+	}
+	b.steal(victim)
+	return true
+}
+
+// steal cuts off a note: turned off now if it's sounding, or never sent if it was
+// going out at this step.
+func (b *noteBuffer) steal(msg *Message) {
+	for i := 0; i < len(b.sounding); i++ {
+		if b.sounding[i].msg == msg {
+			b.last.Add(msg)
+			b.sounding = append(b.sounding[:i], b.sounding[i+1:]...)
+			return
+		}
+	}
+	b.next.Remove(msg)
+}
+
+// Sounding gets the note ons an instrument is sounding, oldest first, counting the
+// ones going out at this step.
+func (b *noteBuffer) Sounding(instrument int) []*Message {
+	playing := []*Message{}
+	for _, s := range b.sounding {
+		if s.msg.Instrument == instrument {
+			playing = append(playing, s.msg)
+		}
+	}
+	for i := 0; i < b.next.Len(); i++ {
+		if m := b.next.buf[i]; m.IsNoteOn() && m.Instrument == instrument {
+			playing = append(playing, m)
+		}
+	}
+	return playing
+}
+
+// Any tells if there's anything to send at this step, note ons or note offs.
+func (b *noteBuffer) Any() bool {
+	return b.next.Any() || b.last.Any()
 }
 
-// TODO: this will go away when the buffer no longer has to worry about "last" vs. "next"
-// orrrr....we could use slices as a "window" on the buffer, but that involves allocating a slice constantly.
-func (b *doubleBuffer) LastLength() int {
+func (b *noteBuffer) LastLength() int {
 	return b.last.Len()
 }
 
-func (b *doubleBuffer) Last() []*Message {
+func (b *noteBuffer) Last() []*Message {
 	return b.last.buf
 }
 
-// TODO: this will go away when the buffer no longer has to worry about "last" vs. "next"
-// orrrr....we could use slices as a "window" on the buffer, but that involves allocating a slice constantly.
-func (b *doubleBuffer) NextLength() int {
+func (b *noteBuffer) NextLength() int {
 	return b.next.Len()
 }
 
-func (b *doubleBuffer) Next() []*Message {
+func (b *noteBuffer) Next() []*Message {
 	return b.next.buf
 }
 
-// Flip the double buffer.
-func (b *doubleBuffer) Flip() {
+// Flip moves on to the next step. The note ons just sent start sounding, every sounding
+// note gets a step shorter, and the ones that are done go in Last to be turned off.
+// This has to be called at every step, whether or not there was Any.
+func (b *noteBuffer) Flip() {
+	// The notes in Last were turned off at the step we just finished.
 	b.last.Clear()
-	b.next, b.last = b.last, b.next
+
+	for i := 0; i < b.next.Len(); i++ {
+		msg := b.next.buf[i]
+		if !msg.IsNoteOn() {
+			continue
+		}
+		if len(b.sounding) == cap(b.sounding) {
+			// Too many notes; cut this one short rather than leave it hanging.
+			b.overflows++
+			b.last.Add(msg)
+			continue
+		}
+		left := msg.Length
+		if left == 0 {
+			left = 1 // Every note sounds for at least a step.
+		}
+		b.sounding = append(b.sounding, sounding{msg: msg, left: left})
+	}
+	b.next.Clear()
+
+	i := 0
+	for _, s := range b.sounding {
+		s.left--
+		if s.left == 0 {
+			b.last.Add(s.msg)
+		} else {
+			b.sounding[i] = s
+			i++
+		}
+	}
+	b.sounding = b.sounding[:i]
+	b.step++
+}
+
+// Release puts every sounding note in Last, to stop them all (e.g. at the end of the piece.)
+func (b *noteBuffer) Release() {
+	for _, s := range b.sounding {
+		b.last.Add(s.msg)
+	}
+	b.sounding = b.sounding[:0]
+}
+
+// Sort the buffer by HumanizeTime, increasing. Messages that go out at the same time
+// stay in the order they were added, so e.g. a program change still goes before its notes.
+func (b *noteBuffer) Sort() {
+	sort.Stable(b.next)
 }
 
-// Sort the buffer by HumanizeTime, increasing.
-func (b *doubleBuffer) Sort() {
-	// TODO: We could try sort.Stable here if we have problems with that.
-	sort.Sort(b.next)
+// Overflows tells how many notes didn't fit in the buffer, and were dropped or cut short.
+func (b *noteBuffer) Overflows() int {
+	return b.overflows
 }
 
-func (b *doubleBuffer) Print() {
+func (b *noteBuffer) Print() {
 	for i := 0; i < b.next.ptr; i++ {
 		fmt.Printf("%v\n", b.next.buf[i])
 	}
 }
 
-// buffer: one half of a doubleBuffer. Implements sort.Interface for sorting on msg.HumanizeTime.
+// buffer: a list of messages. Implements sort.Interface for sorting on msg.HumanizeTime.
 type buffer struct {
-	buf []*Message
-	ptr int
+	buf   []*Message
+	ptr   int
+	notes int // How many of the messages are note ons.
+	size  int // How many note ons fit.
 }
 
+// Add a message to the list, growing it if need be. Only note ons count against its size,
+// so a pc or cc doesn't take a voice; the noteBuffer checks there's room for a note on.
 func (b *buffer) Add(msg *Message) {
+	if msg.IsNoteOn() {
+		b.notes += 1
+	}
 	if b.ptr < len(b.buf) {
 		b.buf[b.ptr] = msg
-		b.ptr += 1
 	} else {
-		fmt.Println("Note buffer overflow!")
+		b.buf = append(b.buf, msg)
+	}
+	b.ptr += 1
+}
+
+// Remove takes a message back out, keeping the rest in order.
+func (b *buffer) Remove(msg *Message) {
+	for i := 0; i < b.ptr; i++ {
+		if b.buf[i] == msg {
+			copy(b.buf[i:b.ptr], b.buf[i+1:b.ptr])
+			b.ptr--
+			if msg.IsNoteOn() {
+				b.notes--
+			}
+			return
+		}
 	}
 }
 
 func (b *buffer) Clear() {
 	b.ptr = 0
+	b.notes = 0
 }
 
 func (b *buffer) Any() bool {
@@ -124,7 +291,6 @@ func (b *buffer) Len() int {
 
 func (b *buffer) Less(i, j int) bool {
 	// TODO: Should this take into account note off vs. note on, and ensure we're writing note off first?
-	// I don't think the sort is guaranteed to be stable. We don't really have note offs here yet, though.
 	return b.buf[i].HumanizeTime < b.buf[j].HumanizeTime
 }
 
@@ -0,0 +1,225 @@
+package msg
+
+import (
+	"github.com/edemond/midi"
+	"testing"
+)
+
+func noteOn(key byte, length uint64) *Message {
+	return &Message{
+		MidiMessage: midi.Message{Command: 0x9, Channel: 1, Data1: key, Data2: 100},
+		Length:      length,
+	}
+}
+
+// Step the buffer until the note comes up in Last, and return how many steps that took.
+func stepsUntilOff(t *testing.T, buf Buffer, key byte) int {
+	for steps := 1; steps < 100; steps++ {
+		buf.Flip()
+		last := buf.Last()
+		for i := 0; i < buf.LastLength(); i++ {
+			if last[i].MidiMessage.Data1 == key {
+				return steps
+			}
+		}
+	}
+	t.Fatalf("note %v was never turned off", key)
+	return 0
+}
+
+func TestNoteSoundsForItsLength(t *testing.T) {
+	buf, err := NewBuffer(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Add(noteOn(60, 3))
+	if steps := stepsUntilOff(t, buf, 60); steps != 3 {
+		t.Fatalf("expected the note to be turned off after 3 steps, got %v", steps)
+	}
+}
+
+func TestZeroLengthNoteSoundsForAStep(t *testing.T) {
+	buf, err := NewBuffer(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Add(noteOn(60, 0))
+	if steps := stepsUntilOff(t, buf, 60); steps != 1 {
+		t.Fatalf("expected the note to be turned off after 1 step, got %v", steps)
+	}
+}
+
+func TestRetriggerTurnsOffSoundingNote(t *testing.T) {
+	buf, err := NewBuffer(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Add(noteOn(60, 8))
+	buf.Flip()
+	buf.Add(noteOn(60, 8))
+	if buf.LastLength() != 1 {
+		t.Fatalf("expected the sounding note to be turned off before it's struck again, got %v note offs", buf.LastLength())
+	}
+}
+
+func TestReleaseStopsEverything(t *testing.T) {
+	buf, err := NewBuffer(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Add(noteOn(60, 8))
+	buf.Add(noteOn(64, 8))
+	buf.Flip()
+	buf.Release()
+	if buf.LastLength() != 2 {
+		t.Fatalf("expected 2 note offs on release, got %v", buf.LastLength())
+	}
+	buf.Flip()
+	if buf.Any() {
+		t.Fatalf("expected nothing left sounding after release")
+	}
+}
+
+// keys gets the keys of the given messages.
+func keys(msgs []*Message, n int) []byte {
+	k := []byte{}
+	for i := 0; i < n; i++ {
+		k = append(k, msgs[i].MidiMessage.Data1)
+	}
+	return k
+}
+
+func expectKeys(t *testing.T, what string, msgs []*Message, n int, expected ...byte) {
+	t.Helper()
+	if got := keys(msgs, n); string(got) != string(expected) {
+		t.Fatalf("expected %v to be %v, got %v", what, expected, got)
+	}
+}
+
+// steal plays two notes on an instrument with two voices, a step apart, then a third on the
+// step after, and returns the buffer as it is at the third step.
+func steal(t *testing.T, mode Steal, third *Message) Buffer {
+	t.Helper()
+	buf, err := NewBuffer(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	voices := &Voices{Count: 2, Steal: mode}
+	first, second := noteOn(60, 8), noteOn(64, 8)
+	first.Voices, second.Voices = voices, voices
+	first.MidiMessage.Data2 = 50
+	first.Priority = 1
+	buf.Add(first)
+	buf.Flip()
+	buf.Add(second)
+	buf.Flip()
+	third.Voices = voices
+	buf.Add(third)
+	return buf
+}
+
+func TestStealOldest(t *testing.T) {
+	buf := steal(t, StealOldest, noteOn(67, 8))
+	expectKeys(t, "the note offs", buf.Last(), buf.LastLength(), 60)
+	expectKeys(t, "the note ons", buf.Next(), buf.NextLength(), 67)
+}
+
+func TestStealQuietest(t *testing.T) {
+	buf := steal(t, StealQuietest, noteOn(67, 8))
+	expectKeys(t, "the note offs", buf.Last(), buf.LastLength(), 60)
+	expectKeys(t, "the note ons", buf.Next(), buf.NextLength(), 67)
+
+	// Quieter than anything sounding, so it's the one that goes.
+	quiet := noteOn(67, 8)
+	quiet.MidiMessage.Data2 = 10
+	buf = steal(t, StealQuietest, quiet)
+	expectKeys(t, "the note offs", buf.Last(), buf.LastLength())
+	expectKeys(t, "the note ons", buf.Next(), buf.NextLength())
+}
+
+func TestStealLowest(t *testing.T) {
+	// The first note matters more, so the second goes, even though the first is older.
+	buf := steal(t, StealLowest, noteOn(67, 8))
+	expectKeys(t, "the note offs", buf.Last(), buf.LastLength(), 64)
+	expectKeys(t, "the note ons", buf.Next(), buf.NextLength(), 67)
+
+	// Matters less than anything sounding, so it's the one that goes.
+	low := noteOn(67, 8)
+	low.Priority = -1
+	buf = steal(t, StealLowest, low)
+	expectKeys(t, "the note offs", buf.Last(), buf.LastLength())
+	expectKeys(t, "the note ons", buf.Next(), buf.NextLength())
+}
+
+func TestStealNone(t *testing.T) {
+	buf := steal(t, StealNone, noteOn(67, 8))
+	expectKeys(t, "the note offs", buf.Last(), buf.LastLength())
+	expectKeys(t, "the note ons", buf.Next(), buf.NextLength())
+	expectKeys(t, "the sounding notes", buf.Sounding(0), 2, 60, 64)
+}
+
+func TestStealFromTheSameStep(t *testing.T) {
+	buf, err := NewBuffer(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	voices := &Voices{Count: 2, Steal: StealOldest}
+	for _, key := range []byte{60, 64, 67} {
+		m := noteOn(key, 8)
+		m.Voices = voices
+		buf.Add(m)
+	}
+	// The oldest was never sent, so there's nothing to turn off.
+	expectKeys(t, "the note offs", buf.Last(), buf.LastLength())
+	expectKeys(t, "the note ons", buf.Next(), buf.NextLength(), 64, 67)
+}
+
+func TestVoicesAreCountedPerInstrument(t *testing.T) {
+	buf, err := NewBuffer(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	voices := &Voices{Count: 1, Steal: StealNone}
+	for i, key := range []byte{60, 64} {
+		m := noteOn(key, 8)
+		m.Instrument = i
+		m.Voices = voices
+		buf.Add(m)
+	}
+	expectKeys(t, "the note ons", buf.Next(), buf.NextLength(), 60, 64)
+}
+
+func TestMessagesDontTakeVoices(t *testing.T) {
+	buf, err := NewBuffer(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Add(&Message{MidiMessage: midi.Message{Command: 0xC, Channel: 1, Data1: 5}})
+	buf.Add(&Message{MidiMessage: midi.Message{Command: 0xB, Channel: 1, Data1: 7, Data2: 100}})
+	buf.Add(noteOn(60, 8))
+	if buf.NextLength() != 3 {
+		t.Fatalf("expected the pc, cc and note to all go out, got %v messages", buf.NextLength())
+	}
+	next := buf.Next()
+	if next[0].MidiMessage.Command != 0xC || next[1].MidiMessage.Command != 0xB || !next[2].IsNoteOn() {
+		t.Fatalf("expected the pc, cc and note in order, got %v %v %v", next[0], next[1], next[2])
+	}
+}
+
+func TestOverflowsAreCounted(t *testing.T) {
+	buf, err := NewBuffer(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Add(noteOn(60, 8))
+	buf.Add(noteOn(64, 8)) // No room at this step.
+	expectKeys(t, "the note ons", buf.Next(), buf.NextLength(), 60)
+	buf.Flip()
+
+	buf.Add(noteOn(67, 8)) // Room at this step, but not among the sounding notes.
+	buf.Flip()
+	expectKeys(t, "the note offs", buf.Last(), buf.LastLength(), 67)
+	if buf.Overflows() != 2 {
+		t.Fatalf("expected 2 overflows, got %v", buf.Overflows())
+	}
+}
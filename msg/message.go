@@ -2,13 +2,30 @@ package msg
 
 import (
 	"github.com/edemond/midi"
+	"time"
 )
 
 // When played, parts emit a series of Messages.
 type Message struct {
 	// TODO: Decouple this from MIDI.
-	MidiMessage      midi.Message // MIDI message to send.
-	Instrument       int          // Instrument ID to send the MIDI message to.
-	HumanizeTime     int          // TODO: This is currently JACK frames, but ought to be independent.
+	MidiMessage      midi.Message  // MIDI message to send.
+	Instrument       int           // Instrument ID to send the MIDI message to.
+	HumanizeTime     time.Duration // How far from its step the message goes out, early or late.
 	HumanizeVelocity int
+	Priority         int     // For note ons, how much the note matters when its instrument runs out of voices.
+	Length           uint64  // For note ons, how many steps the note sounds before it's turned off.
+	Step             uint64  // For note ons, the step it went out at, counted by the Buffer.
+	Voices           *Voices // The voices of the instrument it's for. No limit if nil.
+}
+
+// IsNoteOn tells if the message starts a note, and so has to be turned off later.
+func (m *Message) IsNoteOn() bool {
+	return m.MidiMessage.Command == 0x9
+}
+
+// SameKey tells if two messages are for the same key on the same channel of the same instrument.
+func (m *Message) SameKey(other *Message) bool {
+	return m.Instrument == other.Instrument &&
+		m.MidiMessage.Channel == other.MidiMessage.Channel &&
+		m.MidiMessage.Data1 == other.MidiMessage.Data1
 }
@@ -0,0 +1,39 @@
+package msg
+
+import (
+	"fmt"
+)
+
+// Steal says which note an instrument cuts off when a note on comes in and all of its
+// voices are in use.
+type Steal int
+
+const (
+	StealOldest   Steal = iota // The note that's been sounding the longest.
+	StealQuietest              // The softest note, which could be the new one.
+	StealLowest                // The note with the lowest Priority, which could be the new one; the oldest on a tie.
+	StealNone                  // Nothing; the new note isn't played.
+)
+
+var stealNames = []string{"oldest", "quietest", "lowest", "none"}
+
+func (s Steal) String() string {
+	return stealNames[s]
+}
+
+// LookUpSteal gets a stealing mode by name, e.g. "quietest".
+func LookUpSteal(name string) (Steal, error) {
+	for i, n := range stealNames {
+		if n == name {
+			return Steal(i), nil
+		}
+	}
+	return StealOldest, fmt.Errorf("voice stealing must be oldest, quietest, lowest or none (got %v)", name)
+}
+
+// Voices is how many notes an instrument can sound at once, and what it does when a
+// note comes in past that.
+type Voices struct {
+	Count int // No limit if 0.
+	Steal Steal
+}
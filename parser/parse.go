@@ -194,7 +194,7 @@ func (p *handwrittenParser) parseLetStatement() (ast.Statement, error) {
 			if !ok {
 				return nil, p.errorf("expected all identifiers in parameter list")
 			}
-			e.AddParameter(param)
+			e.AddParameter(param, nil)
 		}
 	}
 
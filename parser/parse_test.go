@@ -189,6 +189,55 @@ func TestParamExpr(t *testing.T) {
 	}
 }
 
+func TestNamedArg(t *testing.T) {
+	text := "rock(C, s = clap)\n"
+	block := coreTests(t, text)
+	stmt, ok := block.Statements[0].(*ast.PlayStatement)
+	if !ok {
+		t.Fatal("expected *ast.PlayStatement")
+	}
+	expr, ok := stmt.Expr.(*ast.ParamExpr)
+	if !ok {
+		t.Fatalf("expected *ast.ParamExpr, got %v", stmt.Expr)
+	}
+	if len(expr.Params) != 2 {
+		t.Fatalf("expected 2 arguments, got %v", len(expr.Params))
+	}
+	named, ok := expr.Params[1].(*ast.NamedArgExpr)
+	if !ok {
+		t.Fatalf("expected *ast.NamedArgExpr, got %v", expr.Params[1])
+	}
+	if named.Name != "s" || named.Expr.String() != "clap" {
+		t.Fatalf("expected s = clap, got %v", named)
+	}
+}
+
+func TestDefaultParameter(t *testing.T) {
+	text := "let rock(k, s = snare) = k s\n"
+	block := coreTests(t, text)
+	stmt, ok := block.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatal("expected *ast.LetStatement")
+	}
+	p, ok := stmt.Expr.(ast.Parameterized)
+	if !ok {
+		t.Fatalf("expected a parameterized expression, got %v", stmt.Expr)
+	}
+	params, defaults := p.Parameters(), p.Defaults()
+	if len(params) != 2 || params[0] != "k" || params[1] != "s" {
+		t.Fatalf("expected parameters k and s, got %v", params)
+	}
+	if defaults[0] != nil {
+		t.Fatalf("expected no default for k, got %v", defaults[0])
+	}
+	if defaults[1] == nil || defaults[1].String() != "snare" {
+		t.Fatalf("expected default snare for s, got %v", defaults[1])
+	}
+	if stmt.String() != "let rock(k, s = snare) = k s" {
+		t.Fatalf("unexpected let statement: %v", stmt)
+	}
+}
+
 func TestSeqExpr(t *testing.T) {
 	text := "[a b _]\n"
 	block := coreTests(t, text)
@@ -225,3 +274,62 @@ func TestSeqExpr(t *testing.T) {
 		t.Fatalf("expected third value to be '_', got '%v'", rest)
 	}
 }
+
+func TestMelodyExpr(t *testing.T) {
+	text := "let m = mel\"1 3 5 -\"\n"
+	block := coreTests(t, text)
+	if len(block.Statements) != 1 {
+		t.Fatalf("expected 1 statement in block, got %v", len(block.Statements))
+	}
+	stmt, ok := block.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatal("expected *ast.LetStatement")
+	}
+	mel, ok := stmt.Expr.(*ast.MelodyExpr)
+	if !ok {
+		t.Fatalf("expected *ast.MelodyExpr on RHS, got %v", stmt.Expr)
+	}
+	if mel.Notes != "1 3 5 -" {
+		t.Fatalf("expected melody '1 3 5 -', got '%v'", mel.Notes)
+	}
+}
+
+func TestImport(t *testing.T) {
+	block := coreTests(t, "import \"drums/boss.abs\"\n")
+	if len(block.Statements) != 1 {
+		t.Fatalf("expected 1 statement, got %v", len(block.Statements))
+	}
+	stmt, ok := block.Statements[0].(*ast.ImportStatement)
+	if !ok {
+		t.Fatalf("expected *ast.ImportStatement, got %T", block.Statements[0])
+	}
+	if stmt.Path != "drums/boss.abs" {
+		t.Fatalf("expected path 'drums/boss.abs', got '%v'", stmt.Path)
+	}
+}
+
+func TestImportNeedsString(t *testing.T) {
+	expectParseError(t, "import boss\n")
+}
+
+func TestSyntaxErrorIsReturned(t *testing.T) {
+	parser, err := FromBytes([]byte("let x = (\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = parser.Parse()
+	if err == nil {
+		t.Fatal("expected a syntax error")
+	}
+}
+
+func TestLexerErrorIsReturned(t *testing.T) {
+	parser, err := FromBytes([]byte("let x = \"unterminated\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = parser.Parse()
+	if err == nil {
+		t.Fatal("expected an error for an unterminated string")
+	}
+}
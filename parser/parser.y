@@ -2,11 +2,11 @@
 package parser
 
 import(
-	"edemond/abstract/lexer"
-	"edemond/abstract/ast"
-	//"fmt"
-	//"strconv"
-	//"strings"
+	"github.com/edemond/abstract/ast"
+	"github.com/edemond/abstract/lexer"
+	"fmt"
+	"strconv"
+	"strings"
 )
 
 const PARSER_TRACE = false
@@ -51,6 +51,8 @@ const PARSER_TRACE = false
 %token PPQ 17
 %token LBRACKET '[' 18
 %token RBRACKET ']' 19
+%token IMPORT 20
+%token <val> MELODY 21
 
 %type <val> error
 %type <statement> piece 
@@ -60,6 +62,7 @@ const PARSER_TRACE = false
 %type <statement> ppqstatement
 %type <statement> defaultstatement
 %type <statement> letstatement
+%type <statement> importstatement
 %type <statement> playstatement
 %type <simpleexpr> simpleexpr
 %type <compoundexpr> compoundexpr
@@ -69,6 +72,9 @@ const PARSER_TRACE = false
 %type <exprlist> valueexprlist
 %type <exprlist> exprlist
 %type <exprlist> formalparameterlist
+%type <expr> formalparameter
+%type <expr> arg
+%type <expr> namedarg
 %type <paramexpr> paramexpr
 
 %%
@@ -104,6 +110,7 @@ statement : bpmstatement
     | ppqstatement
     | defaultstatement
     | letstatement
+    | importstatement
     | playstatement
     ;
 
@@ -220,11 +227,13 @@ letstatement : LET IDENT '=' expr terminator
 			ablex.Error("expected simple, compound, or block expression")
 		} else {
             for _,prm := range params {
-                param, ok := prm.(ast.IdentExpr)
-                if !ok {
+                switch param := prm.(type) {
+                case ast.IdentExpr:
+                    e.AddParameter(param, nil)
+                case *ast.NamedArgExpr:
+                    e.AddParameter(ast.IdentExpr(param.Name), param.Expr)
+                default:
                     ablex.Error("expected only identifiers in parameter list")
-                } else {
-                    e.AddParameter(param)
                 }
             }
             expr = e
@@ -240,6 +249,16 @@ letstatement : LET IDENT '=' expr terminator
     trace("error in let statement")
 }
 
+importstatement : IMPORT STRING terminator
+{
+    stmt := &ast.ImportStatement{
+        Line: ablex.(*abLexerImpl).Line(),
+        Path: $2,
+    }
+    $$ = stmt
+    trace("Parsed an import statement: %v\n", stmt)
+}
+
 valueexpr : IDENT
 {
     $$ = ast.IdentExpr($1)
@@ -254,6 +273,15 @@ valueexpr : IDENT
 {
     $$ = $1
     trace("Parsed a parameterized value expression: %v\n", $1)
+}
+    | MELODY
+{
+    expr := &ast.MelodyExpr{
+        Line: ablex.(*abLexerImpl).Line(),
+        Notes: $1,
+    }
+    $$ = expr
+    trace("Parsed a melody expression: %v\n", expr)
 }
     | NUMBER '/' NUMBER
 {
@@ -332,19 +360,39 @@ paramexpr : IDENT '(' exprlist ')'
     trace("Parsed a parameterized expression: %v\n", expr)
 }
 
-exprlist : expr
+exprlist : arg
 {
     expr := []ast.Expression{$1}
     $$ = expr
     trace("Parsed an expression list (start): %v\n", expr)
 }
-    | exprlist ',' expr
+    | exprlist ',' arg
 {
     $1 = append($1, $3)
     $$ = $1
     trace("Parsed an expression list (more): %v\n", $1)
 }
 
+arg : expr
+{
+    $$ = $1
+}
+    | namedarg
+{
+    $$ = $1
+}
+
+namedarg : IDENT '=' expr
+{
+    expr := &ast.NamedArgExpr{
+        Line: ablex.(*abLexerImpl).Line(),
+        Name: $1,
+        Expr: $3,
+    }
+    $$ = expr
+    trace("Parsed a named argument: %v\n", expr)
+}
+
 simpleorvalueexpr : simpleexpr
 {
     $$ = $1
@@ -389,21 +437,28 @@ simpleexpr : valueexpr valueexpr
     trace("Parsed a simple expression (more): %v\n", $1)
 }
 
-formalparameterlist : IDENT
+formalparameterlist : formalparameter
 {
-    exprs := []ast.Expression{
-        ast.IdentExpr($1),
-    }
+    exprs := []ast.Expression{$1}
     $$ = exprs
     trace("Parsed a formal parameter list: %v\n", $1)
 }
-    | formalparameterlist ',' IDENT
+    | formalparameterlist ',' formalparameter
 {
-    $1 = append($1, ast.IdentExpr($3))
+    $1 = append($1, $3)
     $$ = $1
     trace("Parsed a formal parameter list (more): %v\n", $1)
 }
 
+formalparameter : IDENT
+{
+    $$ = ast.IdentExpr($1)
+}
+    | namedarg
+{
+    $$ = $1
+}
+
 %%
 
 // Wrap a lexer.Lexer in a struct that implements abLexer.
@@ -411,19 +466,28 @@ formalparameterlist : IDENT
 type abLexerImpl struct {
     *lexer.Lexer 
     parseResult *ast.PlayStatement // The root of the parsed AST is stored here after parsing.
+    err error // The first error, lexing or parsing.
 }
 
 func (lex *abLexerImpl) Lex(yylval *abSymType) int {
 	tok, val, err := lex.Scan()
     if err != nil {
-        panic(err) // TODO: this is total garbage
+        // Stop parsing here, as if the file ended.
+        if lex.err == nil {
+            lex.err = err
+        }
+        return 0
     }
     yylval.val = val
     return int(tok)
 }
 
+// Error records a parse error. Only the first one counts; whatever comes after it
+// (e.g. a syntax error at the end of a file we stopped lexing) is a consequence of it.
 func (lex *abLexerImpl) Error(e string) {
-    fmt.Printf("line %v: %v\n", lex.Line(), e)
+    if lex.err == nil {
+        lex.err = fmt.Errorf("line %v: %v", lex.Line(), e)
+    }
 }
 
 type generatedParser struct {
@@ -440,6 +504,9 @@ func (p *generatedParser) Parse() (*ast.PlayStatement, error) {
     // Call the entry point of the yacc-generated parser.
     lex := &abLexerImpl{Lexer: p.lex}
     _ = abParse(lex) // TODO: Handle this return value?
+    if lex.err != nil {
+        return nil, lex.err
+    }
     if lex.parseResult == nil {
         return nil, fmt.Errorf("Couldn't parse.") // TODO: actual error message here? filename?
     }
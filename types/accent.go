@@ -0,0 +1,65 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The strengths of the levels of the pulse (see Rhythm.Pulse), strongest first: the
+// downbeat, the half bar, the beat, then eighths, sixteenths and thirty-seconds.
+var pulseLevels = []int{1, 2, 4, 8, 16, 32}
+
+// Accent is how much louder, in velocity, notes are on the stronger beats of the bar, so the
+// downbeat lands harder than the offbeats. e.g. accent(20), accent(24, 8, 16)
+// The amounts are for each level of the pulse, strongest first; levels not given get nothing.
+// With only one amount, the accent falls off by a quarter at each level, to nothing on sixteenths.
+type Accent struct {
+	Amounts []int
+}
+
+func (a *Accent) String() string {
+	amounts := make([]string, len(a.Amounts))
+	for i, amount := range a.Amounts {
+		amounts[i] = fmt.Sprint(amount)
+	}
+	return fmt.Sprintf("accent(%v)", strings.Join(amounts, ", "))
+}
+
+func (a *Accent) HasValue() bool {
+	return a != nil
+}
+
+func NewAccent(amounts ...int) (*Accent, error) {
+	if len(amounts) < 1 || len(amounts) > len(pulseLevels) {
+		return nil, fmt.Errorf("accent takes 1-%v amounts (got %v)", len(pulseLevels), len(amounts))
+	}
+	for _, amount := range amounts {
+		if amount < 0 || amount > 127 {
+			return nil, fmt.Errorf("accent amounts must be 0-127 (got %v)", amount)
+		}
+	}
+	if len(amounts) == 1 {
+		n := amounts[0]
+		amounts = []int{n, n * 3 / 4, n / 2, n / 4}
+	}
+	return &Accent{Amounts: amounts}, nil
+}
+
+func NoAccent() *Accent {
+	return nil
+}
+
+// Every beat is as loud as the next unless asked otherwise.
+func DefaultAccent() *Accent {
+	return &Accent{}
+}
+
+// Amount gets how much louder to play a note struck on a pulse of the given strength.
+func (a *Accent) Amount(strength int) int {
+	for i, level := range pulseLevels {
+		if level == strength && i < len(a.Amounts) {
+			return a.Amounts[i]
+		}
+	}
+	return 0
+}
@@ -0,0 +1,219 @@
+package types
+
+import (
+	"github.com/edemond/abstract/util"
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// ArpPattern is the order an arpeggio goes through the notes of a chord.
+type ArpPattern int
+
+const (
+	ArpUp     ArpPattern = iota // Lowest to highest.
+	ArpDown                     // Highest to lowest.
+	ArpUpDown                   // Up and back down, without playing the top and bottom twice.
+	ArpRandom                   // Any note of the chord, each time.
+	ArpPlayed                   // The order the chord gives its notes in, e.g. C G E for chord(C, G, E).
+	ArpCustom                   // Indexes into the chord, given with the arp.
+)
+
+var arpPatternNames = []string{"up", "down", "updown", "random", "played"}
+
+func (p ArpPattern) String() string {
+	if p == ArpCustom {
+		return "custom"
+	}
+	return arpPatternNames[p]
+}
+
+// LookUpArpPattern gets an arpeggio pattern by name, e.g. "updown".
+func LookUpArpPattern(name string) (ArpPattern, error) {
+	for i, n := range arpPatternNames {
+		if n == name {
+			return ArpPattern(i), nil
+		}
+	}
+	return ArpUp, fmt.Errorf("arp pattern must be up, down, updown, random, played or chord indexes (got %v)", name)
+}
+
+// Arp is an arpeggio (part of the harmonic context): the notes of the chord one at a time, in
+// some pattern. The chord is whatever the harmonic context resolves to in its key and scale,
+// from the context's octave up, with the bass at the bottom. On its own, an arp plays at a
+// steady rate; with an Interpretation, e.g. bjork(), it plays the next note of its pattern at
+// each of the Interpretation's hits instead.
+type Arp struct {
+	pattern ArpPattern
+	indexes []int // For a custom pattern: 1 for the lowest note of the chord, and so on; 0 rests.
+	rate    int   // How many notes fit in a bar of the part's meter, e.g. 16 for sixteenths in 4/4.
+	octaves int   // How many octaves the pattern goes over.
+}
+
+func NoArp() *Arp {
+	return nil
+}
+
+// NewArp creates an arpeggio in one of the named patterns, over the given number of octaves.
+func NewArp(pattern ArpPattern, rate int, octaves int) (*Arp, error) {
+	if rate <= 0 {
+		return nil, fmt.Errorf("arp rate must be above 0 (got %v)", rate)
+	}
+	if octaves <= 0 {
+		return nil, fmt.Errorf("arp has to go over at least 1 octave (got %v)", octaves)
+	}
+	return &Arp{pattern: pattern, rate: rate, octaves: octaves}, nil
+}
+
+// NewCustomArp creates an arpeggio that plays the given notes of the chord in turn. Indexes past
+// the top of the chord carry on up into the next octave, e.g. 4 is the root an octave up in a triad.
+func NewCustomArp(indexes []int, rate int) (*Arp, error) {
+	if len(indexes) == 0 {
+		return nil, fmt.Errorf("arp needs at least one index")
+	}
+	for _, i := range indexes {
+		if i < 0 {
+			return nil, fmt.Errorf("arp indexes start at 1, or 0 for a rest (got %v)", i)
+		}
+	}
+	arp, err := NewArp(ArpCustom, rate, 1)
+	if err != nil {
+		return nil, err
+	}
+	arp.indexes = indexes
+	return arp, nil
+}
+
+// Where the given note of the arpeggio starts, in steps from the start of the part, with bars
+// of the given length.
+func (a *Arp) start(i uint64, bar uint64) uint64 {
+	return i * bar / uint64(a.rate)
+}
+
+// Which note of the arpeggio the given step is in.
+func (a *Arp) at(step uint64, bar uint64) uint64 {
+	i := step * uint64(a.rate) / bar
+	if a.start(i+1, bar) <= step {
+		i++ // The starts are rounded down, so a note can start a step before this says.
+	}
+	return i
+}
+
+// Play plays the arpeggio at its own rate, when there's no Interpretation to play it.
+func (a *Arp) Play(notesOut []Note, h *Harmony, r *Rhythm, rnd *rand.Rand, step uint64, length uint64, ppq int) {
+	bar := r.Meter.Length(ppq)
+	i := a.at(step, bar)
+	if step >= length || a.start(i, bar) != step {
+		return
+	}
+	a.play(notesOut, h, rnd, i)
+}
+
+// Pick turns whatever an Interpretation struck at this step into the given note of the
+// arpeggio, and tells if it struck anything.
+func (a *Arp) Pick(notesOut []Note, h *Harmony, rnd *rand.Rand, i uint64) bool {
+	hit := false
+	for j := range notesOut {
+		if notesOut[j].HasValue() {
+			notesOut[j] = NoNote()
+			hit = true
+		}
+	}
+	if hit {
+		a.play(notesOut, h, rnd, i)
+	}
+	return hit
+}
+
+// play plays the given note of the arpeggio.
+func (a *Arp) play(notesOut []Note, h *Harmony, rnd *rand.Rand, i uint64) {
+	// TODO: Allocation during playback again.
+	notes := a.notes(h)
+	var note int
+	switch a.pattern {
+	case ArpRandom:
+		note = notes[rnd.Intn(len(notes))]
+	case ArpCustom:
+		index := a.indexes[i%uint64(len(a.indexes))]
+		if index == 0 {
+			return // A rest.
+		}
+		note = notes[(index-1)%len(notes)] + 12*((index-1)/len(notes))
+	default:
+		note = notes[i%uint64(len(notes))]
+	}
+	if note >= 0 && note <= 127 {
+		notesOut[0] = Note(note)
+	}
+}
+
+// NoteLength holds each note until the next one, or the end of the part, when the arpeggio
+// plays at its own rate.
+func (a *Arp) NoteLength(r *Rhythm, step uint64, length uint64, ppq int) uint64 {
+	bar := r.Meter.Length(ppq)
+	end := a.start(a.at(step, bar)+1, bar)
+	if end > length {
+		end = length
+	}
+	if end <= step {
+		return 1
+	}
+	return end - step
+}
+
+// notes gets the notes the pattern goes through, in order. A custom pattern gets the chord
+// in one octave, lowest first, for its indexes to pick from.
+func (a *Arp) notes(h *Harmony) []int {
+	var pitches []Pitch
+	if h.Chord.HasValue() {
+		pitches = h.Chord.ResolveIn(h.Pitch, h.Scale)
+	} else {
+		pitches = []Pitch{h.Pitch}
+	}
+
+	// The first note (the bass) in the context's octave, and the rest above it.
+	bass := int(h.Octave)*12 + int(pitches[0])
+	chord := make([]int, len(pitches))
+	for i, p := range pitches {
+		n := int(h.Octave)*12 + int(p)
+		for i > 0 && n <= bass {
+			n += 12
+		}
+		chord[i] = n
+	}
+	if a.pattern != ArpPlayed {
+		sort.Ints(chord)
+	}
+	if a.pattern == ArpCustom {
+		return chord
+	}
+
+	notes := make([]int, 0, len(chord)*a.octaves)
+	for o := 0; o < a.octaves; o++ {
+		for _, n := range chord {
+			notes = append(notes, n+12*o)
+		}
+	}
+	switch a.pattern {
+	case ArpDown:
+		for i, j := 0, len(notes)-1; i < j; i, j = i+1, j-1 {
+			notes[i], notes[j] = notes[j], notes[i]
+		}
+	case ArpUpDown:
+		for i := len(notes) - 2; i > 0; i-- {
+			notes = append(notes, notes[i])
+		}
+	}
+	return notes
+}
+
+func (a *Arp) String() string {
+	if a.pattern == ArpCustom {
+		return fmt.Sprintf("arp(\"%v\", %v)", util.JoinInts(a.indexes, " "), a.rate)
+	}
+	return fmt.Sprintf("arp(%v, %v, %v)", a.pattern, a.rate, a.octaves)
+}
+
+func (a *Arp) HasValue() bool {
+	return a != nil
+}
@@ -0,0 +1,175 @@
+package types
+
+import (
+	"github.com/edemond/abstract/msg"
+	"math/rand"
+	"testing"
+)
+
+// arpNotes plays an arpeggio over a bar of 4/4 of the given chord, and gets the note at each step it plays one.
+func arpNotes(t *testing.T, arp *Arp, chord Chord) map[uint64]Note {
+	t.Helper()
+	h := &Harmony{Chord: chord, Octave: NewOctave(4), Pitch: Pitch(0), Scale: DefaultScale(), Voicing: NoVoicing()}
+	h.SetDefaults()
+	r := &Rhythm{Meter: DefaultMeter()}
+	rnd := rand.New(rand.NewSource(1))
+	played := map[uint64]Note{}
+	notes := makeNoteBuffer(8)
+	for step := uint64(0); step < 16; step++ {
+		arp.Play(notes, h, r, rnd, step, 16, 4)
+		if notes[0].HasValue() {
+			played[step] = notes[0]
+			notes[0] = NoNote()
+		}
+	}
+	return played
+}
+
+func expectArp(t *testing.T, arp *Arp, chord Chord, expected ...Note) {
+	t.Helper()
+	played := arpNotes(t, arp, chord)
+	if len(played) != len(expected) {
+		t.Fatalf("%v: expected %v, got %v", arp, expected, played)
+	}
+	for i, note := range expected {
+		if played[uint64(i)] != note {
+			t.Fatalf("%v: expected %v, got %v", arp, expected, played)
+		}
+	}
+}
+
+func newArp(t *testing.T, pattern ArpPattern, octaves int) *Arp {
+	t.Helper()
+	arp, err := NewArp(pattern, 16, octaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return arp
+}
+
+func TestArpPatterns(t *testing.T) {
+	cmaj := NewRelativeChord(1, []int{0, 4, 7})
+	// With a ppq of 4, sixteenths fall on every step of the 16 step bar.
+	expectArp(t, newArp(t, ArpUp, 1), cmaj, 48, 52, 55, 48, 52, 55, 48, 52, 55, 48, 52, 55, 48, 52, 55, 48)
+	expectArp(t, newArp(t, ArpDown, 1), cmaj, 55, 52, 48, 55, 52, 48, 55, 52, 48, 55, 52, 48, 55, 52, 48, 55)
+	expectArp(t, newArp(t, ArpUpDown, 1), cmaj, 48, 52, 55, 52, 48, 52, 55, 52, 48, 52, 55, 52, 48, 52, 55, 52)
+	expectArp(t, newArp(t, ArpUp, 2), cmaj, 48, 52, 55, 60, 64, 67, 48, 52, 55, 60, 64, 67, 48, 52, 55, 60)
+}
+
+func TestArpPlayedKeepsTheChordsOrder(t *testing.T) {
+	cge := NewAbsoluteChordFromPitches([]Pitch{0, 7, 4})
+	expectArp(t, newArp(t, ArpPlayed, 1), cge, 48, 55, 52, 48, 55, 52, 48, 55, 52, 48, 55, 52, 48, 55, 52, 48)
+	expectArp(t, newArp(t, ArpUp, 1), cge, 48, 52, 55, 48, 52, 55, 48, 52, 55, 48, 52, 55, 48, 52, 55, 48)
+}
+
+func TestArpStartsFromTheBass(t *testing.T) {
+	// C/E goes up from the E.
+	c1 := NewInvertedChord(NewRelativeChord(1, []int{0, 4, 7}), 1)
+	expectArp(t, newArp(t, ArpUp, 1), c1, 52, 55, 60, 52, 55, 60, 52, 55, 60, 52, 55, 60, 52, 55, 60, 52)
+}
+
+func TestArpCustom(t *testing.T) {
+	arp, err := NewCustomArp([]int{1, 3, 0, 4}, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Eighths fall on every other step; 0 rests, and 4 is the root an octave up.
+	played := arpNotes(t, arp, NewRelativeChord(1, []int{0, 4, 7}))
+	expected := map[uint64]Note{0: 48, 2: 55, 6: 60, 8: 48, 10: 55, 14: 60}
+	if len(played) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, played)
+	}
+	for step, note := range expected {
+		if played[step] != note {
+			t.Fatalf("expected %v, got %v", expected, played)
+		}
+	}
+	if length := arp.NoteLength(&Rhythm{Meter: DefaultMeter()}, 14, 16, 4); length != 2 {
+		t.Fatalf("expected each eighth to last 2 steps, got %v", length)
+	}
+}
+
+func TestArpRandomStaysInTheChord(t *testing.T) {
+	for _, note := range arpNotes(t, newArp(t, ArpRandom, 2), NewRelativeChord(1, []int{0, 4, 7})) {
+		switch note {
+		case 48, 52, 55, 60, 64, 67:
+		default:
+			t.Fatalf("expected a random note of C major over 2 octaves, got %v", note)
+		}
+	}
+}
+
+func TestNewArp(t *testing.T) {
+	if _, err := NewArp(ArpUp, 0, 1); err == nil {
+		t.Error("expected a rate of 0 to be an error")
+	}
+	if _, err := NewArp(ArpUp, 16, 0); err == nil {
+		t.Error("expected 0 octaves to be an error")
+	}
+	if _, err := NewCustomArp([]int{}, 16); err == nil {
+		t.Error("expected a custom arp with no indexes to be an error")
+	}
+}
+
+func TestArpTriplets(t *testing.T) {
+	arp := newArp(t, ArpUp, 1)
+	arp.rate = 12
+	// Eighth triplets don't fall evenly on the steps of a bar with a ppq of 64, but every one still plays.
+	count := 0
+	for step := uint64(0); step < 256; step++ {
+		if arp.start(arp.at(step, 256), 256) == step {
+			count++
+		}
+	}
+	if count != 12 {
+		t.Fatalf("expected 12 triplets in a bar, got %v", count)
+	}
+}
+
+func TestArpFillsTheBarOfTheMeter(t *testing.T) {
+	arp := newArp(t, ArpUp, 1)
+	arp.rate = 12
+	// A bar of 3/4 with a ppq of 4 is 12 steps, so twelve notes to a bar fall on every step.
+	r := &Rhythm{Meter: &Meter{Beats: 3, Value: 4}}
+	for step := uint64(0); step < 12; step++ {
+		if arp.start(arp.at(step, r.Meter.Length(4)), r.Meter.Length(4)) != step {
+			t.Fatalf("expected a note at every step of a bar of 3/4, but not at %v", step)
+		}
+		if length := arp.NoteLength(r, step, 12, 4); length != 1 {
+			t.Fatalf("expected each note to last a step, got %v at step %v", length, step)
+		}
+	}
+}
+
+func TestArpPlaysAtTheInterpretationsHits(t *testing.T) {
+	part := NewSimplePart()
+	part.Harmony.Chord = NewRelativeChord(1, []int{0, 4, 7})
+	part.Harmony.Octave = NewOctave(4)
+	part.Harmony.Arp = newArp(t, ArpUp, 1)
+	part.Interpretation = NewBjork(4, 8, 0)
+	buf, err := msg.NewBuffer(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	played := map[uint64]byte{}
+	for step := uint64(0); step < part.Length(4); step++ {
+		part.Play(buf, rand.New(rand.NewSource(1)), 4, step)
+		next := buf.Next()
+		for i := 0; i < buf.NextLength(); i++ {
+			if next[i].IsNoteOn() {
+				played[step] = next[i].MidiMessage.Data1
+			}
+		}
+		buf.Flip()
+	}
+	// bjork(4, 8) hits every fourth step, and each hit plays the next note of the arp.
+	expected := map[uint64]byte{0: 48, 4: 52, 8: 55, 12: 48}
+	if len(played) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, played)
+	}
+	for step, note := range expected {
+		if played[step] != note {
+			t.Fatalf("expected %v, got %v", expected, played)
+		}
+	}
+}
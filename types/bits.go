@@ -0,0 +1,65 @@
+package types
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// Bits is a rhythm written as bit patterns (Interpretation), e.g. rhythm(0x8080). Each set bit
+// is a hit, most significant bit first, spread over the bar; 0x8080 is two half notes in 4/4.
+// With more than one pattern, each time the part is played again it moves on to the next one.
+type Bits struct {
+	bars []pulsePattern
+	text []string // As written, e.g. "0x8080", since leading zeroes count.
+}
+
+func NewBits() *Bits {
+	return &Bits{
+		bars: []pulsePattern{},
+		text: []string{},
+	}
+}
+
+// AddBar adds a bar's worth of hits from a number written with the given number of hex digits.
+func (b *Bits) AddBar(value uint64, digits int) {
+	width := digits * 4
+	bar := make(pulsePattern, width)
+	for i := 0; i < width; i++ {
+		if value&(1<<uint(width-1-i)) != 0 {
+			bar[i] = 1
+		}
+	}
+	b.bars = append(b.bars, bar)
+	b.text = append(b.text, fmt.Sprintf("0x%0*X", digits, value))
+}
+
+// The pattern for the bar being played, given how many steps of the part have been played.
+func (b *Bits) bar(counter uint64, length uint64) pulsePattern {
+	return b.bars[(counter/length)%uint64(len(b.bars))]
+}
+
+func (b *Bits) Play(notesOut []Note, h *Harmony, r *Rhythm, rnd *rand.Rand, counter uint64, step uint64, length uint64, ppq int) {
+	if len(b.bars) == 0 || length == 0 {
+		return
+	}
+	if b.bar(counter, length).hit(step, length) {
+		playNotes(notesOut, h)
+	}
+}
+
+// NoteLength holds a note until the next hit, or the end of the bar.
+func (b *Bits) NoteLength(counter uint64, step uint64, length uint64, ppq int) uint64 {
+	if len(b.bars) == 0 || length == 0 {
+		return 1
+	}
+	return b.bar(counter, length).noteLength(step, length)
+}
+
+func (b *Bits) String() string {
+	return fmt.Sprintf("rhythm(%v)", strings.Join(b.text, ", "))
+}
+
+func (b *Bits) HasValue() bool {
+	return b != nil
+}
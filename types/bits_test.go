@@ -0,0 +1,59 @@
+package types
+
+import (
+	"testing"
+)
+
+func bitsHits(b *Bits, counter uint64, length uint64) []uint64 {
+	h := &Harmony{Chord: NoChord(), Pitch: Pitch(0), Octave: Octave(4)}
+	hits := []uint64{}
+	for step := uint64(0); step < length; step++ {
+		notes := makeNoteBuffer(4)
+		b.Play(notes, h, nil, nil, counter+step, step, length, 4)
+		if notes[0].HasValue() {
+			hits = append(hits, step)
+		}
+	}
+	return hits
+}
+
+func expectBitsHits(t *testing.T, b *Bits, counter uint64, length uint64, expected []uint64) {
+	hits := bitsHits(b, counter, length)
+	if len(hits) != len(expected) {
+		t.Fatalf("%v: expected hits at %v, got %v", b, expected, hits)
+	}
+	for i := range hits {
+		if hits[i] != expected[i] {
+			t.Fatalf("%v: expected hits at %v, got %v", b, expected, hits)
+		}
+	}
+}
+
+func TestBitsSpreadOverBar(t *testing.T) {
+	b := NewBits()
+	b.AddBar(0x8080, 4)
+	expectBitsHits(t, b, 0, 16, []uint64{0, 8})
+}
+
+func TestBitsLeadingZeroesCount(t *testing.T) {
+	b := NewBits()
+	b.AddBar(0x08, 2)
+	expectBitsHits(t, b, 0, 16, []uint64{8})
+}
+
+func TestBitsSuccessiveBars(t *testing.T) {
+	b := NewBits()
+	b.AddBar(0x80, 2)
+	b.AddBar(0x88, 2)
+	expectBitsHits(t, b, 0, 16, []uint64{0})
+	expectBitsHits(t, b, 16, 16, []uint64{0, 8})
+	expectBitsHits(t, b, 32, 16, []uint64{0})
+}
+
+func TestBitsString(t *testing.T) {
+	b := NewBits()
+	b.AddBar(0x0808, 4)
+	if b.String() != "rhythm(0x0808)" {
+		t.Fatalf("expected rhythm(0x0808), got %v", b.String())
+	}
+}
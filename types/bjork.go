@@ -0,0 +1,53 @@
+package types
+
+import (
+	"github.com/edemond/abstract/util"
+	"fmt"
+	"math/rand"
+)
+
+// Bjork is a "Euclidean" rhythm (Interpretation): some number of pulses spread as evenly as
+// possible over some number of steps, which are spread over the length of the part.
+type Bjork struct {
+	pulses   int
+	steps    int
+	rotation int
+	pattern  pulsePattern // Already rotated.
+}
+
+func NewBjork(pulses, steps, rotation int) *Bjork {
+	beats := util.Bjorklund(pulses, steps)
+	pattern := make(pulsePattern, steps)
+	for i := 0; i < steps; i++ {
+		// Rotate left, so e.g. bjork(3, 8, 1) starts on the step after the first pulse.
+		pattern[i] = beats[(((i+rotation)%steps)+steps)%steps]
+	}
+	return &Bjork{
+		pulses:   pulses,
+		steps:    steps,
+		rotation: rotation,
+		pattern:  pattern,
+	}
+}
+
+func (b *Bjork) Play(notesOut []Note, h *Harmony, r *Rhythm, rnd *rand.Rand, counter uint64, step uint64, length uint64, ppq int) {
+	if b.pattern.hit(step, length) {
+		playNotes(notesOut, h)
+	}
+}
+
+// NoteLength holds a note until the next pulse, or the end of the part.
+func (b *Bjork) NoteLength(counter uint64, step uint64, length uint64, ppq int) uint64 {
+	return b.pattern.noteLength(step, length)
+}
+
+func (b *Bjork) String() string {
+	if b.rotation != 0 {
+		return fmt.Sprintf("bjork(%v, %v, %v)", b.pulses, b.steps, b.rotation)
+	}
+	return fmt.Sprintf("bjork(%v, %v)", b.pulses, b.steps)
+}
+
+func (b *Bjork) HasValue() bool {
+	return b != nil
+}
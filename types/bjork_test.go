@@ -0,0 +1,49 @@
+package types
+
+import (
+	"testing"
+)
+
+// Steps of a part of the given length where a bjork strikes.
+func bjorkHits(b *Bjork, length uint64) []uint64 {
+	h := &Harmony{Chord: NoChord(), Pitch: Pitch(0), Octave: Octave(4)}
+	hits := []uint64{}
+	for step := uint64(0); step < length; step++ {
+		notes := makeNoteBuffer(4)
+		b.Play(notes, h, nil, nil, step, step, length, 4)
+		if notes[0].HasValue() {
+			hits = append(hits, step)
+		}
+	}
+	return hits
+}
+
+func expectHits(t *testing.T, b *Bjork, length uint64, expected []uint64) {
+	hits := bjorkHits(b, length)
+	if len(hits) != len(expected) {
+		t.Fatalf("%v: expected hits at %v, got %v", b, expected, hits)
+	}
+	for i := range hits {
+		if hits[i] != expected[i] {
+			t.Fatalf("%v: expected hits at %v, got %v", b, expected, hits)
+		}
+	}
+}
+
+func TestBjorkSpreadsOverPart(t *testing.T) {
+	expectHits(t, NewBjork(3, 8, 0), 16, []uint64{0, 6, 12})
+}
+
+func TestBjorkRotation(t *testing.T) {
+	expectHits(t, NewBjork(3, 8, 2), 16, []uint64{2, 8, 12})
+}
+
+func TestBjorkNoteLengthRunsToNextPulse(t *testing.T) {
+	b := NewBjork(3, 8, 0)
+	if n := b.NoteLength(0, 6, 16, 4); n != 6 {
+		t.Fatalf("expected the note to last until the next pulse (6 steps), got %v", n)
+	}
+	if n := b.NoteLength(0, 12, 16, 4); n != 4 {
+		t.Fatalf("expected the last note to last until the end of the part (4 steps), got %v", n)
+	}
+}
@@ -1,5 +1,9 @@
 package types
 
+import (
+	"math/rand"
+)
+
 // A "block" expression is the default way to play a chord or a note (Interpretation).
 // It's a direction to play the entire block chord or single note right on the downbeat.
 // Not to be confused with BlockPart.
@@ -9,7 +13,7 @@ func NewBlock() *Block {
 	return &Block{}
 }
 
-func (b *Block) Play(notesOut []Note, h *Harmony, r *Rhythm, counter uint64, step uint64, length uint64, ppq int) {
+func (b *Block) Play(notesOut []Note, h *Harmony, r *Rhythm, rnd *rand.Rand, counter uint64, step uint64, length uint64, ppq int) {
 	beat, strength := r.Pulse(step, ppq)
 	// Block chords only play on the downbeat (1,1).
 	if beat == 1 && strength == 1 {
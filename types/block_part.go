@@ -2,6 +2,7 @@ package types
 
 import (
 	"github.com/edemond/abstract/msg"
+	"math/rand"
 )
 
 // A list of parts to play sequentially.
@@ -31,9 +32,15 @@ func (b *BlockPart) FirstPart() Part {
 	return b.parts[0]
 }
 
-func (b *BlockPart) Play(buf msg.Buffer, ppq int, step uint64) {
+func (b *BlockPart) Play(buf msg.Buffer, rnd *rand.Rand, ppq int, step uint64) {
 	length := b.Length(ppq)
 	if length == 0 {
+		// Nothing but zero-length parts (messages), which all go at the start.
+		if step == 0 {
+			for _, p := range b.parts {
+				p.Play(buf, rnd, ppq, step)
+			}
+		}
 		return
 	}
 
@@ -43,22 +50,37 @@ func (b *BlockPart) Play(buf msg.Buffer, ppq int, step uint64) {
 
 	for _, p := range b.parts {
 		length := p.Length(ppq)
+		if length == 0 {
+			// Zero-length parts don't count; they play at the same step as the next one.
+			if step == steps {
+				p.Play(buf, rnd, ppq, 0)
+			}
+			continue
+		}
 		if step < (steps + length) {
 			// okay, here we need to give it what step it is in the child part.
 			// that's (local step - start of child part in steps)
-			p.Play(buf, ppq, step-steps)
+			p.Play(buf, rnd, ppq, step-steps)
 			played = true
-			// Zero-length parts don't count; play the next one immediately.
-			if length != 0 {
-				break
-			}
-		} else {
-			steps += length
+			break
 		}
+		steps += length
 	}
 	if !played {
 		panic("Internal error: Step counter exceeded block part index!")
 	}
+
+	// Zero-length parts after the last timed one have no part to go with, so they go at
+	// the block's last step instead.
+	if step == length-1 {
+		i := len(b.parts)
+		for i > 0 && b.parts[i-1].Length(ppq) == 0 {
+			i--
+		}
+		for _, p := range b.parts[i:] {
+			p.Play(buf, rnd, ppq, 0)
+		}
+	}
 }
 
 func (b *BlockPart) String() string {
@@ -0,0 +1,47 @@
+package types
+
+import (
+	"github.com/edemond/abstract/msg"
+	"github.com/edemond/midi"
+	"testing"
+)
+
+// messageSteps plays a part through, and gets the steps each of its non-note messages go out on.
+func messageSteps(t *testing.T, part Part, ppq int) []uint64 {
+	t.Helper()
+	buf, err := msg.NewBuffer(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := []uint64{}
+	for step := uint64(0); step < part.Length(ppq); step++ {
+		part.Play(buf, nil, ppq, step)
+		next := buf.Next()
+		for i := 0; i < buf.NextLength(); i++ {
+			if !next[i].IsNoteOn() {
+				steps = append(steps, step)
+			}
+		}
+		buf.Flip()
+	}
+	return steps
+}
+
+func pc(program byte) *MIDIMessagePart {
+	return &MIDIMessagePart{Messages: []midi.Message{{Command: 0xC, Channel: 1, Data1: program}}}
+}
+
+func TestBlockMessagesGoWithTheNextPart(t *testing.T) {
+	steps := messageSteps(t, block(pc(1), beats(1), pc(2), beats(1)), 4)
+	if len(steps) != 2 || steps[0] != 0 || steps[1] != 4 {
+		t.Fatalf("expected messages at steps 0 and 4, got %v", steps)
+	}
+}
+
+func TestBlockSendsTrailingMessages(t *testing.T) {
+	// Nothing comes after the pc, so it goes at the block's last step.
+	steps := messageSteps(t, block(beats(1), beats(1), pc(1)), 4)
+	if len(steps) != 1 || steps[0] != 7 {
+		t.Fatalf("expected a message at step 7, got %v", steps)
+	}
+}
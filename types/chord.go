@@ -9,9 +9,10 @@ import (
 // There are several ways to specify a chord.
 type Chord interface {
 	Value
-	Root() Pitch // Returns a pitch with .HasValue() == false if not an absolute chord.
-	ResolveIn(key Pitch, scale *Scale) []Pitch
-	Play(notesOut []Note, h *Harmony) // Used by Interpretation. Sound a block chord.
+	Root() Pitch                               // Returns a pitch with .HasValue() == false if not an absolute chord.
+	Bass(key Pitch, scale *Scale) Pitch        // The note on the bottom, which is the root unless the chord is inverted.
+	ResolveIn(key Pitch, scale *Scale) []Pitch // Bass first.
+	Play(notesOut []Note, h *Harmony)          // Used by Interpretation. Sound a block chord.
 }
 
 // Neither major/minor qualities nor root pitch is specified, just a set of scale degrees.
@@ -39,6 +40,14 @@ type absoluteChord struct {
 	avoidNotes []Pitch // TODO: Notes which are traditionally avoided. (This isn't used yet, just an idea.)
 }
 
+// invertedChord is a chord with something other than its root on the bottom: either one of
+// its own notes (an inversion, e.g. Cmaj/1 or I6) or a bass note of its own (e.g. F/Ab).
+type invertedChord struct {
+	chord     Chord
+	inversion int   // Which of the chord's notes is on the bottom, counting the root as 0.
+	bass      Pitch // The bass note, if it has a value, in place of an inversion.
+}
+
 // Diatonic chords don't have a specified root pitch.
 func (c *diatonicChord) Root() Pitch {
 	return NoPitch()
@@ -53,6 +62,29 @@ func (c *absoluteChord) Root() Pitch {
 	return c.pitches[0] // Treat the first note specified as the root.
 }
 
+func (c *invertedChord) Root() Pitch {
+	return c.chord.Root()
+}
+
+func (c *absoluteChord) Bass(key Pitch, scale *Scale) Pitch {
+	return c.pitches[0]
+}
+
+func (c *relativeChord) Bass(key Pitch, scale *Scale) Pitch {
+	return c.ResolveIn(key, scale)[0]
+}
+
+func (c *diatonicChord) Bass(key Pitch, scale *Scale) Pitch {
+	return c.ResolveIn(key, scale)[0]
+}
+
+func (c *invertedChord) Bass(key Pitch, scale *Scale) Pitch {
+	if c.bass.HasValue() {
+		return c.bass
+	}
+	return c.ResolveIn(key, scale)[0]
+}
+
 // Create a chord from a set of absolute pitches, e.g. chord(C, Eb, G, Bb)
 func NewAbsoluteChordFromPitches(pitches []Pitch) Chord {
 	c := &absoluteChord{}
@@ -107,6 +139,18 @@ func NewDiatonicChord(scaleDegrees []int) Chord {
 	return c
 }
 
+// Create an inversion of a chord, with the given note of it on the bottom (1 for the third
+// in a triad, and so on.) Used for resolving chord notation like Cmaj/1 or V65.
+func NewInvertedChord(chord Chord, inversion int) Chord {
+	return &invertedChord{chord: chord, inversion: inversion, bass: NoPitch()}
+}
+
+// Create a chord with the given bass note on the bottom, which may or may not be in the chord.
+// Used for resolving chord notation like F/Ab or Cmaj7/D.
+func NewSlashChord(chord Chord, bass Pitch) Chord {
+	return &invertedChord{chord: chord, bass: bass}
+}
+
 // NoChord creates a null chord.
 func NoChord() *absoluteChord {
 	return nil
@@ -138,6 +182,13 @@ func (c *relativeChord) String() string {
 	return fmt.Sprintf("relative chord(%v) on %v", util.JoinInts(c.intervalsInHalfSteps, ", "), c.rootScaleDegree)
 }
 
+func (c *invertedChord) String() string {
+	if c.bass.HasValue() {
+		return fmt.Sprintf("%v over %v", c.chord, c.bass)
+	}
+	return fmt.Sprintf("%v, inversion %v", c.chord, c.inversion)
+}
+
 func (c *absoluteChord) HasValue() bool {
 	return c != nil
 }
@@ -150,6 +201,10 @@ func (c *relativeChord) HasValue() bool {
 	return c != nil
 }
 
+func (c *invertedChord) HasValue() bool {
+	return c != nil
+}
+
 func (c *absoluteChord) ResolveIn(key Pitch, scale *Scale) []Pitch {
 	pitches := make([]Pitch, len(c.pitches))
 	for i, p := range c.pitches {
@@ -176,6 +231,24 @@ func (c *diatonicChord) ResolveIn(key Pitch, scale *Scale) []Pitch {
 	return pitches
 }
 
+// The bass comes first, then the rest of the chord in order, without the bass if it's in there.
+func (c *invertedChord) ResolveIn(key Pitch, scale *Scale) []Pitch {
+	chord := c.chord.ResolveIn(key, scale)
+	pitches := make([]Pitch, 0, len(chord)+1)
+	if c.bass.HasValue() {
+		pitches = append(pitches, c.bass)
+		for _, p := range chord {
+			if p != c.bass {
+				pitches = append(pitches, p)
+			}
+		}
+		return pitches
+	}
+	inversion := c.inversion % len(chord)
+	pitches = append(pitches, chord[inversion:]...)
+	return append(pitches, chord[:inversion]...)
+}
+
 // TODO: The Play methods all have to be refactored into methods that get chordal info.
 // Chord should no longer be an Interpretation, but a source of harmonic information for Interpretations.
 
@@ -184,7 +257,7 @@ func (c *diatonicChord) ResolveIn(key Pitch, scale *Scale) []Pitch {
 
 func (c *absoluteChord) Play(notesOut []Note, h *Harmony) {
 	if h.Voicing.HasValue() {
-		voiced := h.Voicing.Voice(c.pitches)
+		voiced := h.Voicing.Voice(c.pitches, h.Octave)
 		for i, note := range voiced {
 			notesOut[i] = note
 		}
@@ -202,9 +275,8 @@ func (c *relativeChord) Play(notesOut []Note, h *Harmony) {
 	pitches := c.ResolveIn(h.Pitch, h.Scale)
 
 	if h.Voicing.HasValue() {
-		// The voicing takes care of the octave of each pitch, so ignore context.Octave.
-		// TODO: until...octave-relative voicings? someday? that oughtta be possible/useful.
-		voiced := h.Voicing.Voice(pitches)
+		// The voicing takes care of the octave of each pitch, starting from context.Octave if it's octave-relative.
+		voiced := h.Voicing.Voice(pitches, h.Octave)
 		for i, note := range voiced {
 			notesOut[i] = note
 		}
@@ -222,9 +294,8 @@ func (c *diatonicChord) Play(notesOut []Note, h *Harmony) {
 	pitches := c.ResolveIn(h.Pitch, h.Scale)
 
 	if h.Voicing.HasValue() {
-		// The voicing takes care of the octave of each pitch, so ignore context.Octave.
-		// TODO: until...octave-relative voicings? someday? that oughtta be possible/useful.
-		voiced := h.Voicing.Voice(pitches)
+		// The voicing takes care of the octave of each pitch, starting from context.Octave if it's octave-relative.
+		voiced := h.Voicing.Voice(pitches, h.Octave)
 		for i, note := range voiced {
 			notesOut[i] = note
 		}
@@ -237,3 +308,68 @@ func (c *diatonicChord) Play(notesOut []Note, h *Harmony) {
 		}
 	}
 }
+
+func (c *invertedChord) Play(notesOut []Note, h *Harmony) {
+	pitches := c.ResolveIn(h.Pitch, h.Scale)
+
+	if h.Voicing.HasValue() {
+		// The voicing picks the octaves, but the bass still has to be on the bottom.
+		var voiced []Note
+		if v, ok := h.Voicing.(bassVoicer); ok {
+			voiced = v.VoiceOverBass(pitches)
+		} else {
+			voiced = putBassOnBottom(h.Voicing.Voice(pitches, h.Octave), pitches[0])
+		}
+		for i, note := range voiced {
+			notesOut[i] = note
+		}
+	} else {
+		// Put the bass in the context's octave, and stack the rest of the chord above it.
+		bass := pitches[0].At(h.Octave)
+		notesOut[0] = bass
+		i := 1
+		for _, pitch := range pitches[1:] {
+			note := int(pitch.At(h.Octave))
+			for note <= int(bass) {
+				note += 12
+			}
+			if note <= 127 {
+				notesOut[i] = Note(note)
+				i++
+			}
+		}
+	}
+}
+
+// A voicing that can keep the first pitch on the bottom itself, rather than having it moved there after.
+type bassVoicer interface {
+	VoiceOverBass(pitches []Pitch) []Note
+}
+
+// Make sure the lowest of the voiced notes is the bass, dropping a bass note down by octaves
+// below the rest of the notes if it has to, or adding one if the voicing left it out.
+func putBassOnBottom(notes []Note, bass Pitch) []Note {
+	lowest, lowestBass := -1, -1 // Indexes of the lowest note that isn't the bass, and the lowest bass note.
+	for i, note := range notes {
+		if NewPitch(uint64(note)) == bass {
+			if lowestBass < 0 || note < notes[lowestBass] {
+				lowestBass = i
+			}
+		} else if lowest < 0 || note < notes[lowest] {
+			lowest = i
+		}
+	}
+	if lowest < 0 || (lowestBass >= 0 && notes[lowestBass] < notes[lowest]) {
+		return notes // The bass is already on the bottom.
+	}
+	top := int(notes[lowest])
+	below := top - ((top-int(bass))%12+12)%12
+	if below < 0 {
+		return notes // There's no room under the chord for the bass.
+	}
+	if lowestBass >= 0 {
+		notes[lowestBass] = Note(below)
+		return notes
+	}
+	return append(notes, Note(below))
+}
@@ -0,0 +1,57 @@
+package types
+
+import (
+	"testing"
+)
+
+func playChord(chord Chord, voicing Voicing) []Note {
+	h := &Harmony{Chord: chord, Octave: NewOctave(4), Pitch: NoPitch(), Scale: DefaultScale(), Voicing: voicing}
+	h.SetDefaults()
+	notes := make([]Note, 8)
+	for i := range notes {
+		notes[i] = NoNote()
+	}
+	chord.Play(notes, h)
+	played := []Note{}
+	for _, note := range notes {
+		if note.HasValue() {
+			played = append(played, note)
+		}
+	}
+	return played
+}
+
+func expectNotes(t *testing.T, got []Note, expected ...Note) {
+	t.Helper()
+	if len(got) != len(expected) {
+		t.Fatalf("expected notes %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("expected notes %v, got %v", expected, got)
+		}
+	}
+}
+
+func TestInvertedChordStacksAboveTheBass(t *testing.T) {
+	cmaj := NewAbsoluteChord(Pitch(0), []int{0, 4, 7})
+	expectNotes(t, playChord(NewInvertedChord(cmaj, 1), NoVoicing()), 52, 55, 60)
+	expectNotes(t, playChord(NewInvertedChord(cmaj, 2), NoVoicing()), 55, 60, 64)
+	expectNotes(t, playChord(NewSlashChord(cmaj, Pitch(2)), NoVoicing()), 50, 60, 52, 55)
+}
+
+func TestVoicedInversionKeepsTheBassOnTheBottom(t *testing.T) {
+	cmaj := NewAbsoluteChord(Pitch(0), []int{0, 4, 7})
+	// The bass (E) in octave 5 and the rest in octave 4: the E drops below them.
+	voicing := NewVoicing(0b001<<40 | 0b110<<32)
+	expectNotes(t, playChord(NewInvertedChord(cmaj, 1), voicing), 40, 55, 48)
+	// A voicing that leaves the bass out gets one added under the chord.
+	expectNotes(t, playChord(NewSlashChord(cmaj, Pitch(2)), NewVoicing(0b1110<<32)), 48, 52, 55, 38)
+}
+
+func TestHarmonyBass(t *testing.T) {
+	h := &Harmony{Chord: NewInvertedChord(NewRelativeChord(5, []int{0, 4, 7, 10}), 1), Pitch: Pitch(0)}
+	if bass := h.Bass(); bass != Pitch(11) {
+		t.Errorf("expected the bass of V65 in C to be B, got %v", bass)
+	}
+}
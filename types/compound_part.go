@@ -2,6 +2,7 @@ package types
 
 import (
 	"edemond/abstract/msg"
+	"math/rand"
 )
 
 // A list of parts to play concurrently.
@@ -16,6 +17,7 @@ func NewCompoundPart() *CompoundPart {
 	return &CompoundPart{
 		parts:  make([]Part, 0),
 		length: 0,
+		scale:  1,
 	}
 }
 
@@ -23,11 +25,24 @@ func (c *CompoundPart) Add(p Part) {
 	c.parts = append(c.parts, p)
 }
 
-func (c *CompoundPart) Play(buf msg.Buffer, ppq int, step uint64) {
+func (c *CompoundPart) Play(buf msg.Buffer, rnd *rand.Rand, ppq int, step uint64) {
+	c.playInBar(buf, rnd, ppq, step, step)
+}
+
+// playInBar plays all the parts at the same step of the bar.
+func (c *CompoundPart) playInBar(buf msg.Buffer, rnd *rand.Rand, ppq int, step uint64, bar uint64) {
 	for _, part := range c.parts {
+		length := part.Length(ppq)
+		if length == 0 {
+			// Zero-length parts (messages) only play at the start.
+			if step == 0 {
+				playInBar(part, buf, rnd, ppq, step, bar)
+			}
+			continue
+		}
 		// TODO: this currently only implements a limited looping play. need one-shot play, polymeter, polyrhythm
-		s := step % part.Length(ppq)
-		part.Play(buf, ppq, s)
+		s := step % length
+		playInBar(part, buf, rnd, ppq, s, bar)
 	}
 }
 
@@ -38,13 +53,23 @@ func (c *CompoundPart) Length(ppq int) uint64 {
 		for _, part := range c.parts {
 			length := part.Length(ppq)
 			if length > c.length {
-				c.length = length / c.scale
+				c.length = length
 			}
 		}
 	}
 	return c.length
 }
 
+// SetScale scales all the parts down together, e.g. to fit in a slot of a seq.
+func (c *CompoundPart) SetScale(scale int) {
+	c.scale = scale
+	for _, part := range c.parts {
+		if s, ok := part.(Scalable); ok {
+			s.SetScale(scale)
+		}
+	}
+}
+
 func (c *CompoundPart) String() string {
 	return "compoundpart()"
 }
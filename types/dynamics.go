@@ -36,10 +36,17 @@ func DefaultDynamics() *Dynamics {
 	return &Dynamics{Center: 127, Human: 0} // full volume
 }
 
-func (d *Dynamics) Humanize(velocity uint64) uint64 {
-	if d.Human == 0 || velocity == 0 {
-		return velocity
+// Humanize moves a velocity up to Human either way at random, keeping it within what a
+// note on can have (1-127).
+func (d *Dynamics) Humanize(rnd *rand.Rand, velocity int) int {
+	if d.Human > 0 {
+		velocity += rnd.Intn(d.Human*2+1) - d.Human
 	}
-	h := uint64(rand.Int31n(int32(d.Human)))
-	return (velocity - h) + (h * 2)
+	if velocity < 1 {
+		return 1
+	}
+	if velocity > 127 {
+		return 127
+	}
+	return velocity
 }
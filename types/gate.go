@@ -0,0 +1,44 @@
+package types
+
+import (
+	"fmt"
+)
+
+// Gate is how much of its length a note sounds for, in percent. e.g. gate(50), staccato, legato
+// Over 100 overlaps the next note.
+type Gate struct {
+	Percent int
+}
+
+func (g *Gate) String() string {
+	return fmt.Sprintf("gate(%v)", g.Percent)
+}
+
+func (g *Gate) HasValue() bool {
+	return g != nil
+}
+
+func NewGate(percent int) (*Gate, error) {
+	if percent < 1 || percent > 200 {
+		return nil, fmt.Errorf("gate must be from 1-200 percent (got %v)", percent)
+	}
+	return &Gate{Percent: percent}, nil
+}
+
+func NoGate() *Gate {
+	return nil
+}
+
+// Notes are slightly detached unless asked otherwise.
+func DefaultGate() *Gate {
+	return &Gate{Percent: 90}
+}
+
+// Apply gates a note length in steps. Every note sounds for at least a step.
+func (g *Gate) Apply(length uint64) uint64 {
+	gated := length * uint64(g.Percent) / 100
+	if gated == 0 {
+		return 1
+	}
+	return gated
+}
@@ -2,6 +2,7 @@ package types
 
 // Harmonic context.
 type Harmony struct {
+	Arp         *Arp
 	Chord       Chord
 	Octave      Octave
 	Pitch       Pitch
@@ -46,6 +47,14 @@ func (h *Harmony) Root() Pitch {
 	return root
 }
 
+// Get the bass pitch of the harmonic context: the chord's bottom note, which is the root unless it's inverted.
 func (h *Harmony) Bass() Pitch {
-	return h.Root() // TODO: Inversions!
+	if !h.Chord.HasValue() {
+		return h.Pitch
+	}
+	scale := h.Scale
+	if !scale.HasValue() {
+		scale = DefaultScale()
+	}
+	return h.Chord.Bass(h.Pitch, scale)
 }
@@ -2,22 +2,27 @@ package types
 
 import (
 	"fmt"
-	"math"
 	"math/rand"
+	"time"
 )
 
+// The most a note can be humanized either way, in milliseconds.
+const maxHumanize = 1000
+
 // Humanized time.
 type Humanize struct {
-	Time int32 // +/- range of randomized time offset for each note
+	Time time.Duration // +/- range of randomized time offset for each note
 }
 
-func NewHumanize(time uint64) (*Humanize, error) {
-	if time > math.MaxInt32 {
-		return nil, fmt.Errorf("Humanize must be 0-%v", math.MaxInt32)
+// NewHumanize makes a Humanize that moves each note up to the given number of
+// milliseconds early or late, whatever the driver.
+func NewHumanize(ms uint64) (*Humanize, error) {
+	if ms > maxHumanize {
+		return nil, fmt.Errorf("Humanize must be 0-%v milliseconds", maxHumanize)
 	}
 
 	return &Humanize{
-		Time: int32(time),
+		Time: time.Duration(ms) * time.Millisecond,
 	}, nil
 }
 
@@ -26,7 +31,7 @@ func (h *Humanize) HasValue() bool {
 }
 
 func (h *Humanize) String() string {
-	return fmt.Sprintf("humanize(%v)", h.Time)
+	return fmt.Sprintf("humanize(%v)", int64(h.Time/time.Millisecond))
 }
 
 func NoHumanize() *Humanize {
@@ -37,10 +42,10 @@ func DefaultHumanize() *Humanize {
 	return NoHumanize()
 }
 
-func (h *Humanize) TimeOffset() int {
+func (h *Humanize) TimeOffset(rnd *rand.Rand) time.Duration {
 	if h.Time == 0 {
 		return 0
 	}
-	offset := rand.Int31n(h.Time * 2)
-	return int(offset - h.Time)
+	offset := time.Duration(rnd.Int63n(int64(h.Time) * 2))
+	return offset - h.Time
 }
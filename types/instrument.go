@@ -1,21 +1,21 @@
 package types
 
 import (
+	"github.com/edemond/abstract/msg"
 	"fmt"
 )
 
 // Instrument defines a instrument for output (ALSA devices, JACK output ports, etc.)
 // Its "Name" field is essentially a connection string that a driver knows how to interpret.
 type Instrument struct {
-	ID      int    // Index of the instrument in the list of open ones.
-	Name    string // Name of the synth (meaningful to the driver for opening the instrument.)
-	Channel byte   // MIDI channel
-	Voices  int    // number of polyphonic voices
-	// TODO: note stealing modes! (none, steal first, steal random, etc...)
+	ID      int        // Index of the instrument in the list of open ones.
+	Name    string     // Name of the synth (meaningful to the driver for opening the instrument.)
+	Channel byte       // MIDI channel
+	Voices  msg.Voices // How many notes it can sound at once, and which one it cuts off past that.
 }
 
 func (i *Instrument) String() string {
-	return fmt.Sprintf("instrument(\"%v\", channel %v, %v voices)", i.Name, i.Channel, i.Voices)
+	return fmt.Sprintf("instrument(\"%v\", channel %v, %v voices, steals %v)", i.Name, i.Channel, i.Voices.Count, i.Voices.Steal)
 }
 
 func (i *Instrument) HasValue() bool {
@@ -26,10 +26,10 @@ func NoInstrument() *Instrument {
 	return nil
 }
 
-func NewInstrument(name string, channel byte, voices int) *Instrument {
+func NewInstrument(name string, channel byte, voices int, steal msg.Steal) *Instrument {
 	return &Instrument{
 		Channel: byte(channel),
-		Voices:  int(voices),
+		Voices:  msg.Voices{Count: voices, Steal: steal},
 		Name:    name,
 	}
 }
@@ -37,7 +37,7 @@ func NewInstrument(name string, channel byte, voices int) *Instrument {
 func defaultInstrument() *Instrument {
 	return &Instrument{
 		Channel: 1,
-		Voices:  0,
+		Voices:  msg.Voices{Count: 0},
 		Name:    "(no instrument)",
 	}
 }
@@ -45,7 +45,7 @@ func defaultInstrument() *Instrument {
 func TotalVoices(insts []*Instrument) int {
 	voices := 0
 	for _, i := range insts {
-		voices += i.Voices
+		voices += i.Voices.Count
 	}
 	return voices
 }
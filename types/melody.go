@@ -0,0 +1,211 @@
+package types
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// Melody is a tune written in scale degrees (Interpretation), e.g. mel"1 3 5 - 4 3 2 _". Its
+// notes are worked out from the harmonic context's pitch, scale, and octave as it plays, so the
+// same melody follows the part into another key or scale. Each note takes an even slot of the
+// part, like the parts of a seq:
+//
+//	1 2 #4 b7  scale degrees, sharpened or flattened; degrees past the scale go on up
+//	5' 5,      up or down an octave (one for each mark)
+//	-          hold the note before through this slot, as in a seq
+//	_ or .     a rest
+//	[1 2 3]    split a slot into smaller ones
+type Melody struct {
+	text  string
+	notes []melodyNote // In the order they start.
+}
+
+// melodyNote is one note (or rest) of a melody.
+type melodyNote struct {
+	start      position
+	end        position
+	degree     int // 1 for the root of the scale.
+	accidental int // In half-steps.
+	octave     int
+	rest       bool
+}
+
+// position is a point in a part, as a fraction of its length.
+type position struct {
+	num uint64
+	den uint64
+}
+
+// in gets the step a position falls on in a part of the given length.
+func (p position) in(length uint64) uint64 {
+	return length * p.num / p.den
+}
+
+// NewMelody parses a melody, e.g. "1 3 5 - 4 3 2 _".
+func NewMelody(text string) (*Melody, error) {
+	tokens := tokenizeMelody(text)
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("melody needs at least one note")
+	}
+	if tokens[0] == "]" {
+		return nil, fmt.Errorf("unexpected ] in melody")
+	}
+	m := &Melody{text: text}
+	rest, err := m.layOut(tokens, position{0, 1}, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("unexpected ] in melody")
+	}
+	return m, nil
+}
+
+// tokenizeMelody splits a melody into notes, rests, ties and brackets.
+func tokenizeMelody(text string) []string {
+	text = strings.Replace(text, "[", " [ ", -1)
+	text = strings.Replace(text, "]", " ] ", -1)
+	return strings.Fields(text)
+}
+
+// layOut adds the notes of a group to the melody, spread evenly over a span starting at the
+// given position and the given number of 1/from.den parts long. It stops at the ] that closes
+// the group, and returns whatever comes after it.
+func (m *Melody) layOut(tokens []string, from position, width uint64) ([]string, error) {
+	// Find the tokens in this group, with their subgroups, to know how far to split the span.
+	var slots [][]string
+	depth := 0
+	i := 0
+	for ; i < len(tokens) && (depth > 0 || tokens[i] != "]"); i++ {
+		if depth == 0 {
+			slots = append(slots, nil)
+		}
+		slots[len(slots)-1] = append(slots[len(slots)-1], tokens[i])
+		switch tokens[i] {
+		case "[":
+			depth++
+		case "]":
+			depth--
+		}
+	}
+	if depth > 0 {
+		return nil, fmt.Errorf("unclosed [ in melody")
+	}
+	if len(slots) == 0 {
+		return nil, fmt.Errorf("empty [] in melody")
+	}
+
+	n := uint64(len(slots))
+	for j, slot := range slots {
+		start := position{from.num*n + uint64(j)*width, from.den * n}
+		end := position{start.num + width, start.den}
+		tok := slot[0]
+		switch {
+		case tok == "[":
+			rest, err := m.layOut(slot[1:], start, width)
+			if err != nil {
+				return nil, err
+			}
+			if len(rest) != 1 {
+				panic("Internal error: melody group didn't end at its ]")
+			}
+		case tok == "-":
+			if len(m.notes) == 0 {
+				return nil, fmt.Errorf("a tie (%v) in a melody has to follow a note", tok)
+			}
+			m.notes[len(m.notes)-1].end = end
+		case tok == "_" || tok == ".":
+			m.notes = append(m.notes, melodyNote{start: start, end: end, rest: true})
+		default:
+			note, err := parseMelodyNote(tok)
+			if err != nil {
+				return nil, err
+			}
+			note.start, note.end = start, end
+			m.notes = append(m.notes, note)
+		}
+	}
+	if i < len(tokens) {
+		return tokens[i:], nil // The closing ].
+	}
+	return nil, nil
+}
+
+// parseMelodyNote parses a scale degree with any accidentals and octave marks, e.g. #4 or 5'.
+func parseMelodyNote(tok string) (melodyNote, error) {
+	var note melodyNote
+	i := 0
+	for ; i < len(tok) && (tok[i] == '#' || tok[i] == 'b'); i++ {
+		if tok[i] == '#' {
+			note.accidental++
+		} else {
+			note.accidental--
+		}
+	}
+	j := i
+	for ; j < len(tok) && tok[j] >= '0' && tok[j] <= '9'; j++ {
+	}
+	degree, err := strconv.Atoi(tok[i:j])
+	if err != nil {
+		return note, fmt.Errorf("melody notes must be scale degrees, e.g. 1, #4 or 5' (got %v)", tok)
+	}
+	if degree < 1 {
+		return note, fmt.Errorf("melody scale degrees start at 1 (got %v)", tok)
+	}
+	note.degree = degree
+	for ; j < len(tok); j++ {
+		switch tok[j] {
+		case '\'':
+			note.octave++
+		case ',':
+			note.octave--
+		default:
+			return note, fmt.Errorf("melody notes must be scale degrees, e.g. 1, #4 or 5' (got %v)", tok)
+		}
+	}
+	return note, nil
+}
+
+func (m *Melody) Play(notesOut []Note, h *Harmony, r *Rhythm, rnd *rand.Rand, counter uint64, step uint64, length uint64, ppq int) {
+	if step >= length {
+		return
+	}
+	for _, n := range m.notes {
+		start := n.start.in(length)
+		if start > step {
+			return
+		}
+		if start < step || n.rest {
+			continue
+		}
+		note := int(h.Octave)*12 + int(h.Pitch) + h.Scale.StepsAtDegree(n.degree-1) + n.accidental + 12*n.octave
+		if note >= 0 && note <= 127 {
+			notesOut[0] = Note(note)
+		}
+		return
+	}
+}
+
+// NoteLength holds each note through its slot and any slots it's tied through.
+func (m *Melody) NoteLength(counter uint64, step uint64, length uint64, ppq int) uint64 {
+	for _, n := range m.notes {
+		if n.start.in(length) == step {
+			end := n.end.in(length)
+			if end > step {
+				return end - step
+			}
+			break
+		}
+	}
+	return 1
+}
+
+func (m *Melody) String() string {
+	return fmt.Sprintf("mel%q", m.text)
+}
+
+func (m *Melody) HasValue() bool {
+	return m != nil
+}
@@ -0,0 +1,79 @@
+package types
+
+import (
+	"testing"
+)
+
+// melodyNotes plays a melody over a 16 step bar, and gets the note and length of everything it plays.
+func melodyNotes(t *testing.T, text string, h *Harmony) (map[uint64]Note, map[uint64]uint64) {
+	t.Helper()
+	mel, err := NewMelody(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.SetDefaults()
+	played := map[uint64]Note{}
+	lengths := map[uint64]uint64{}
+	notes := makeNoteBuffer(8)
+	for step := uint64(0); step < 16; step++ {
+		mel.Play(notes, h, nil, nil, 0, step, 16, 4)
+		if notes[0].HasValue() {
+			played[step] = notes[0]
+			lengths[step] = mel.NoteLength(0, step, 16, 4)
+			notes[0] = NoNote()
+		}
+	}
+	return played, lengths
+}
+
+// harmonyIn makes a harmonic context without a chord, for a melody to play in.
+func harmonyIn(pitch Pitch, scale *Scale, octave Octave) *Harmony {
+	return &Harmony{Chord: NoChord(), Octave: octave, Pitch: pitch, Scale: scale, Voicing: NoVoicing()}
+}
+
+func expectMelody(t *testing.T, text string, h *Harmony, expected map[uint64]Note, lengths map[uint64]uint64) {
+	t.Helper()
+	played, playedLengths := melodyNotes(t, text, h)
+	if len(played) != len(expected) {
+		t.Fatalf("%q: expected %v, got %v", text, expected, played)
+	}
+	for step, note := range expected {
+		if played[step] != note {
+			t.Fatalf("%q: expected %v, got %v", text, expected, played)
+		}
+	}
+	for step, length := range lengths {
+		if playedLengths[step] != length {
+			t.Fatalf("%q: expected lengths %v, got %v", text, lengths, playedLengths)
+		}
+	}
+}
+
+func TestMelodyInC(t *testing.T) {
+	// Eight slots of two steps each; the - holds the 5 and the _ rests.
+	expectMelody(t, "1 3 5 - 4 3 2 _", harmonyIn(Pitch(0), DefaultScale(), NewOctave(4)),
+		map[uint64]Note{0: 48, 2: 52, 4: 55, 8: 53, 10: 52, 12: 50},
+		map[uint64]uint64{0: 2, 4: 4, 12: 2})
+}
+
+func TestMelodyFollowsTheKey(t *testing.T) {
+	dorian := NewScale([]int{2, 1, 2, 2, 2, 1, 2})
+	expectMelody(t, "1 3 5 7", harmonyIn(Pitch(2), dorian, NewOctave(3)),
+		map[uint64]Note{0: 38, 4: 41, 8: 45, 12: 48}, nil)
+}
+
+func TestMelodyOctavesAndAccidentals(t *testing.T) {
+	// 8 is the root an octave up, the same as 1'.
+	expectMelody(t, "1' 5, #4 8", harmonyIn(Pitch(0), DefaultScale(), NewOctave(4)),
+		map[uint64]Note{0: 60, 4: 43, 8: 54, 12: 60}, nil)
+	expectMelody(t, "b3 1'' 2,, bb7", harmonyIn(Pitch(0), DefaultScale(), NewOctave(4)),
+		map[uint64]Note{0: 51, 4: 72, 8: 26, 12: 57}, nil)
+}
+
+func TestMelodyGroups(t *testing.T) {
+	// The groups split their slots; the tie holds the 3 through the next slot, and triplets round
+	// down. A . rests, the same as _.
+	expectMelody(t, "1 [2 3] - [4 . 5]", harmonyIn(Pitch(0), DefaultScale(), NewOctave(4)),
+		map[uint64]Note{0: 48, 4: 50, 6: 52, 12: 53, 14: 55},
+		map[uint64]uint64{4: 2, 6: 6, 12: 1, 14: 2})
+}
@@ -1,7 +1,11 @@
 package types
 
 import (
+	"github.com/edemond/midi"
 	"edemond/abstract/msg"
+	"fmt"
+	"math/rand"
+	"strings"
 )
 
 // A Part that sends a message (e.g. MIDI, OSC) immediately.
@@ -9,25 +13,56 @@ type MessagePart interface {
 	Part
 }
 
+// MIDIMessagePart sends MIDI messages when it starts. It takes up no time, so
+// whatever comes after it starts on the same step.
 type MIDIMessagePart struct {
-	// TODO: Make this support more than one message?
-	// TODO: Is this struct redundant?
-	Command byte
-	Channel byte
-	Data1   byte
-	Data2   byte
+	Messages   []midi.Message
+	Instrument *Instrument // Where to send them. The default instrument if nil.
 }
 
-func (p *MIDIMessagePart) Play(buf msg.Buffer, ppq int, step uint64) {
-	var m msg.Message
-	m.MidiMessage.Command = p.Command
-	m.MidiMessage.Channel = p.Channel
-	m.MidiMessage.Data1 = p.Data1
-	m.MidiMessage.Data2 = p.Data2
-	//msg.Instrument = s.Instrument.ID // TODO: Do we need this?! What is this used for?
-	buf.Add(&m)
+func (p *MIDIMessagePart) Play(buf msg.Buffer, rnd *rand.Rand, ppq int, step uint64) {
+	if step != 0 {
+		return // Only sent once, at the start.
+	}
+	sendMessages(buf, p.Messages, p.Instrument)
+}
+
+// A message part has no length; it only marks the point where the messages are sent.
+func (p *MIDIMessagePart) Length(ppq int) uint64 {
+	return 0
+}
+
+func (p *MIDIMessagePart) String() string {
+	msgs := make([]string, len(p.Messages))
+	for i, m := range p.Messages {
+		msgs[i] = midiMessageString(m)
+	}
+	return strings.Join(msgs, " ")
 }
 
 func (p *MIDIMessagePart) HasValue() bool {
 	return p != nil
 }
+
+// sendMessages adds MIDI messages to the buffer, addressed to the given instrument.
+func sendMessages(buf msg.Buffer, msgs []midi.Message, inst *Instrument) {
+	if inst == nil {
+		inst = defaultInstrument()
+	}
+	for _, mm := range msgs {
+		var m msg.Message
+		m.MidiMessage = mm
+		m.Instrument = inst.ID
+		buf.Add(&m)
+	}
+}
+
+func midiMessageString(m midi.Message) string {
+	switch m.Command {
+	case 0xB:
+		return fmt.Sprintf("cc(%v, %v, %v)", m.Channel, m.Data1, m.Data2)
+	case 0xC:
+		return fmt.Sprintf("pc(%v, %v)", m.Channel, m.Data1)
+	}
+	return fmt.Sprintf("midi(%x, %v, %v, %v)", m.Command, m.Channel, m.Data1, m.Data2)
+}
@@ -0,0 +1,106 @@
+package types
+
+// NamedVoicing is one of the built-in voicings, e.g. drop2. Rather than a bit mask of which
+// note goes in which octave, each is a shape, so it works for chords of any size. They start
+// from the octave of the harmonic context, with the first note of the chord (the root, or the
+// bass of an inverted chord) at the bottom, and the rest close above it before the shape is applied.
+type NamedVoicing int
+
+const (
+	CloseVoicing    NamedVoicing = iota // Every note as close above the one before as it goes.
+	OpenVoicing                         // Close, with every other note from the bottom up an octave.
+	Drop2Voicing                        // Close, with the second note from the top dropped an octave.
+	Drop3Voicing                        // Close, with the third note from the top dropped an octave.
+	ShellVoicing                        // Just the root, third and seventh, close.
+	SpreadVoicing                       // The root on its own, and the rest close an octave above it.
+	RootlessVoicing                     // Close, without the root.
+)
+
+var namedVoicingNames = []string{"close", "open", "drop2", "drop3", "shell", "spread", "rootless"}
+
+func (v NamedVoicing) String() string {
+	return namedVoicingNames[v]
+}
+
+func (v NamedVoicing) HasValue() bool {
+	return true
+}
+
+func (v NamedVoicing) Voice(pitches []Pitch, octave Octave) []Note {
+	if len(pitches) == 0 {
+		return []Note{}
+	}
+	bottom := int(octave)*12 + int(pitches[0])
+
+	chord := pitches
+	switch v {
+	case ShellVoicing:
+		chord = shell(pitches)
+	case RootlessVoicing:
+		if len(pitches) > 1 {
+			chord = pitches[1:]
+		}
+	}
+
+	// Stack the chord up close from the bottom.
+	notes := make([]int, len(chord))
+	for i, p := range chord {
+		n := bottom + ((int(p)-bottom)%12+12)%12
+		if i > 0 && n <= notes[i-1] {
+			n += ((notes[i-1]-n)/12 + 1) * 12
+		}
+		notes[i] = n
+	}
+
+	top := len(notes) - 1
+	switch v {
+	case OpenVoicing:
+		for i := 1; i < len(notes); i += 2 {
+			notes[i] += 12
+		}
+	case Drop2Voicing:
+		if len(notes) >= 3 {
+			notes[top-1] -= 12
+		}
+	case Drop3Voicing:
+		if len(notes) >= 4 {
+			notes[top-2] -= 12
+		}
+	case SpreadVoicing:
+		for i := 1; i < len(notes); i++ {
+			notes[i] += 12
+		}
+	}
+
+	voiced := make([]Note, 0, len(notes))
+	for _, n := range notes {
+		if n >= 0 && n <= 127 {
+			voiced = append(voiced, Note(n))
+		}
+	}
+	return voiced
+}
+
+// shell picks the root, third and seventh out of a chord, root first. A chord without a third
+// uses its sus note, and one without a seventh uses its sixth, or else its fifth.
+func shell(pitches []Pitch) []Pitch {
+	root := pitches[0]
+	find := func(intervals ...int) (Pitch, bool) {
+		for _, interval := range intervals {
+			for _, p := range pitches[1:] {
+				if (int(p)-int(root)+12)%12 == interval {
+					return p, true
+				}
+			}
+		}
+		return NoPitch(), false
+	}
+	notes := []Pitch{root}
+	if third, ok := find(3, 4, 2, 5); ok {
+		notes = append(notes, third)
+	}
+	if seventh, ok := find(10, 11, 9, 7, 6, 8); ok {
+		notes = append(notes, seventh)
+	}
+	return notes
+}
@@ -0,0 +1,54 @@
+package types
+
+import (
+	"testing"
+)
+
+func TestNamedVoicings(t *testing.T) {
+	cmaj7 := pitchSet(0, 4, 7, 11)
+	c7 := pitchSet(0, 4, 7, 10)
+	tests := []struct {
+		voicing  NamedVoicing
+		pitches  []Pitch
+		expected []Note
+	}{
+		{CloseVoicing, cmaj7, []Note{48, 52, 55, 59}},
+		{OpenVoicing, cmaj7, []Note{48, 64, 55, 71}},
+		{Drop2Voicing, cmaj7, []Note{48, 52, 43, 59}},
+		{Drop3Voicing, cmaj7, []Note{48, 40, 55, 59}},
+		{ShellVoicing, c7, []Note{48, 52, 58}},
+		{SpreadVoicing, cmaj7, []Note{48, 64, 67, 71}},
+		{RootlessVoicing, cmaj7, []Note{52, 55, 59}},
+		// The same shapes work on bigger and smaller chords.
+		{Drop2Voicing, pitchSet(0, 4, 7), []Note{48, 40, 55}},
+		{Drop2Voicing, pitchSet(0, 4, 7, 10, 2), []Note{48, 52, 55, 46, 62}},
+		{Drop3Voicing, pitchSet(0, 4, 7), []Note{48, 52, 55}},
+		// Close voicing keeps going up past the octave, e.g. for the ninth of C9.
+		{CloseVoicing, pitchSet(0, 4, 7, 10, 2), []Note{48, 52, 55, 58, 62}},
+		// A shell without a seventh uses the sixth, then the fifth.
+		{ShellVoicing, pitchSet(0, 4, 7, 9), []Note{48, 52, 57}},
+		{ShellVoicing, pitchSet(0, 3, 7), []Note{48, 51, 55}},
+	}
+	for _, test := range tests {
+		got := test.voicing.Voice(test.pitches, 4)
+		if len(got) != len(test.expected) {
+			t.Fatalf("%v of %v: expected %v, got %v", test.voicing, test.pitches, test.expected, got)
+		}
+		for i := range got {
+			if got[i] != test.expected[i] {
+				t.Fatalf("%v of %v: expected %v, got %v", test.voicing, test.pitches, test.expected, got)
+			}
+		}
+	}
+}
+
+func TestNamedVoicingFollowsTheOctave(t *testing.T) {
+	expectNotes(t, Drop2Voicing.Voice(pitchSet(0, 4, 7, 11), 3), 36, 40, 31, 47)
+}
+
+func TestNamedVoicingOfAnInversion(t *testing.T) {
+	// Cmaj7/1 drop 2: E, G, B, C stacked from the E, with the B dropped under it, then the
+	// E dropped under that so it's still the bass.
+	cmaj7 := NewAbsoluteChord(Pitch(0), []int{0, 4, 7, 11})
+	expectNotes(t, playChord(NewInvertedChord(cmaj7, 1), Drop2Voicing), 40, 55, 47, 60)
+}
@@ -2,6 +2,8 @@ package types
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 )
 
 type Note byte
@@ -31,6 +33,24 @@ func NewNote(num uint64) (Note, error) {
 	return Note(num), nil
 }
 
+// LookUpNote converts a note name, a pitch and an octave (e.g. "C3", "Bb4", "F#2"), to a Note.
+// The octaves are the same as the O3, O4 and so on of the octave context, so C4 is note 48.
+func LookUpNote(text string) (Note, error) {
+	i := strings.IndexAny(text, "0123456789")
+	if i <= 0 {
+		return NoNote(), fmt.Errorf("%v is not a note name, like C3", text)
+	}
+	pitch, err := LookUpPitch(text[:i])
+	if err != nil {
+		return NoNote(), fmt.Errorf("%v is not a note name, like C3", text)
+	}
+	octave, err := strconv.Atoi(text[i:])
+	if err != nil {
+		return NoNote(), fmt.Errorf("%v is not a note name, like C3", text)
+	}
+	return NewNote(uint64(octave*12 + int(pitch)))
+}
+
 func (n Note) Adjust(offset int) Note {
 	result := int(n) + offset
 	note, err := NewNote(uint64(result % 127))
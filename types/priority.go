@@ -0,0 +1,28 @@
+package types
+
+import (
+	"fmt"
+)
+
+// Priority is how much a part's notes matter when their instrument runs out of voices. An
+// instrument that steals the lowest priority note cuts off the one that matters least, so
+// e.g. a melody at priority(2) keeps sounding over a pad at the default of 0.
+type Priority struct {
+	Level int
+}
+
+func (p *Priority) String() string {
+	return fmt.Sprintf("priority(%v)", p.Level)
+}
+
+func (p *Priority) HasValue() bool {
+	return p != nil
+}
+
+func NewPriority(level int) *Priority {
+	return &Priority{Level: level}
+}
+
+func NoPriority() *Priority {
+	return nil
+}
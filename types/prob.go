@@ -21,11 +21,11 @@ func NewProb(beat, strength, percent int) *Prob {
 	}
 }
 
-func (p *Prob) Play(notesOut []Note, h *Harmony, r *Rhythm, counter uint64, step uint64, length uint64, ppq int) {
+func (p *Prob) Play(notesOut []Note, h *Harmony, r *Rhythm, rnd *rand.Rand, counter uint64, step uint64, length uint64, ppq int) {
 	beat, strength := r.Pulse(step, ppq)
 	if p.beat == 0 || beat == p.beat {
 		if p.strength == 0 || strength == p.strength {
-			chance := rand.Intn(100)
+			chance := rnd.Intn(100)
 			//fmt.Printf("chance: %v\n", chance)
 			if chance <= p.percent {
 				if h.Chord.HasValue() {
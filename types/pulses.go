@@ -0,0 +1,48 @@
+package types
+
+// pulsePattern is a pattern of hits (1) and rests (0) spread evenly over the length of a part,
+// e.g. [1 0 0 1 0 0 1 0] over a bar of 16 steps hits on steps 0, 6 and 12.
+type pulsePattern []int
+
+// Where the given slot of the pattern starts, in steps of a part of the given length.
+func (p pulsePattern) start(i int, length uint64) uint64 {
+	return uint64(i) * length / uint64(len(p))
+}
+
+// Which slot of the pattern the given step of a part is in.
+func (p pulsePattern) at(step uint64, length uint64) int {
+	return int(step * uint64(len(p)) / length)
+}
+
+// hit tells if the pattern strikes a note right at the given step.
+func (p pulsePattern) hit(step uint64, length uint64) bool {
+	if step >= length || len(p) == 0 {
+		return false
+	}
+	i := p.at(step, length)
+	return p[i] == 1 && p.start(i, length) == step
+}
+
+// noteLength holds a note struck at the given step until the next hit, or the end of the part.
+func (p pulsePattern) noteLength(step uint64, length uint64) uint64 {
+	if step >= length || len(p) == 0 {
+		return 1
+	}
+	end := length
+	for i := p.at(step, length) + 1; i < len(p); i++ {
+		if p[i] == 1 {
+			end = p.start(i, length)
+			break
+		}
+	}
+	return end - step
+}
+
+// playNotes plays the chord, or the pitch if there's no chord.
+func playNotes(notesOut []Note, h *Harmony) {
+	if h.Chord.HasValue() {
+		h.Chord.Play(notesOut, h)
+	} else {
+		notesOut[0] = h.Pitch.At(h.Octave)
+	}
+}
@@ -1,5 +1,9 @@
 package types
 
+import (
+	"math/rand"
+)
+
 type Rest int
 
 func (r Rest) HasValue() bool {
@@ -19,6 +23,6 @@ func NewRest(value string) Rest {
 }
 
 // A Rest doesn't sound, by definition.
-func (r Rest) Play(notes []Note, h *Harmony, rh *Rhythm, counter uint64, step uint64, length uint64, ppq int) {
+func (r Rest) Play(notes []Note, h *Harmony, rh *Rhythm, rnd *rand.Rand, counter uint64, step uint64, length uint64, ppq int) {
 	// TODO: Turn off previous notes here.
 }
@@ -1,10 +1,17 @@
 package types
 
+import (
+	"math/rand"
+)
+
 // Rhythmic/expressive context.
 type Rhythm struct {
+	Accent      *Accent
 	Dynamics    *Dynamics
+	Gate        *Gate
 	Humanize    *Humanize
 	Meter       *Meter
+	Swing       *Swing
 	defaultsSet bool
 }
 
@@ -13,15 +20,24 @@ type Rhythm struct {
 // only be called just before playing the part.
 func (r *Rhythm) SetDefaults() {
 	if !r.defaultsSet {
+		if !r.Accent.HasValue() {
+			r.Accent = DefaultAccent()
+		}
 		if !r.Dynamics.HasValue() {
 			r.Dynamics = DefaultDynamics()
 		}
+		if !r.Gate.HasValue() {
+			r.Gate = DefaultGate()
+		}
 		if !r.Humanize.HasValue() {
 			r.Humanize = DefaultHumanize()
 		}
 		if !r.Meter.HasValue() {
 			r.Meter = DefaultMeter()
 		}
+		if !r.Swing.HasValue() {
+			r.Swing = DefaultSwing()
+		}
 		r.defaultsSet = true
 	}
 }
@@ -34,7 +50,6 @@ func (r *Rhythm) SetDefaults() {
 // e.g. throughout a bar of 4/4 this will return (omitting 32nd notes and 0s for space):
 // number:   1  1  1  1  2  2  2  2  3  3  3  3  4  4  4  4
 // strength: 1  16 8  16 4  16 8  16 2  16 8  16 4  16 8  16
-//
 func (r *Rhythm) Pulse(step uint64, ppq int) (int, int) {
 	stepsPerBeat := uint64((ppq * 4) / r.Meter.Value)
 	beat := int((step / stepsPerBeat) + 1)
@@ -80,3 +95,46 @@ func (r *Rhythm) Pulse(step uint64, ppq int) (int, int) {
 	   }
 	*/
 }
+
+// NoteLength gets how long a note struck at the given step lasts, in steps, before the gate is
+// applied: the length of the pulse it's struck on, e.g. a beat for a note on the beat, or the
+// whole part for a note on the downbeat. It never runs past the end of the part.
+func (r *Rhythm) NoteLength(step uint64, length uint64, ppq int) uint64 {
+	_, strength := r.Pulse(step, ppq)
+	stepsPerBeat := uint64((ppq * 4) / r.Meter.Value)
+
+	var n uint64
+	switch {
+	case strength == 1:
+		n = length
+	case strength == 2:
+		n = stepsPerBeat * uint64(r.Meter.Beats/2)
+	case strength >= 4:
+		n = stepsPerBeat / uint64(strength/4)
+	}
+	if n == 0 {
+		n = 1 // No recognized beat division.
+	}
+	if step >= length {
+		return 1
+	}
+	if step+n > length {
+		n = length - step
+	}
+	return n
+}
+
+// Velocity gets the velocity to strike a note with at the given step: the center of the
+// dynamics, accented by how strong a beat the step is on, then humanized.
+func (r *Rhythm) Velocity(rnd *rand.Rand, step uint64, ppq int) int {
+	if r.Dynamics.Center == 0 {
+		return 0 // Silence stays silent, however it's accented.
+	}
+	_, strength := r.Pulse(step, ppq)
+	return r.Dynamics.Humanize(rnd, r.Dynamics.Center+r.Accent.Amount(strength))
+}
+
+// SwingDelay gets how many steps late to play a note struck at the given step.
+func (r *Rhythm) SwingDelay(step uint64, ppq int) uint64 {
+	return r.Swing.Delay(step, uint64((ppq*4)/r.Meter.Value))
+}
@@ -1,7 +1,9 @@
 package types
 
 import (
+	"github.com/edemond/abstract/msg"
 	"fmt"
+	"math/rand"
 	"testing"
 )
 
@@ -168,3 +170,190 @@ func Test54Pulse(t *testing.T) {
 	expectPulse(r, t, 18, ppq, 5, 8)
 	expectPulse(r, t, 19, ppq, 5, 16)
 }
+
+func TestVelocityAccent(t *testing.T) {
+	accent, err := NewAccent(20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Rhythm{
+		Accent:   accent,
+		Dynamics: NewDynamics(80),
+		Meter:    &Meter{Beats: 4, Value: 4},
+	}
+	ppq := 4
+	expected := map[uint64]int{
+		0: 100, // downbeat
+		8: 95,  // half bar
+		4: 90,  // beat
+		2: 85,  // eighth
+		1: 80,  // sixteenth
+	}
+	for step, velocity := range expected {
+		if v := r.Velocity(rand.New(rand.NewSource(1)), step, ppq); v != velocity {
+			t.Errorf("expected velocity %v at step %v, got %v", velocity, step, v)
+		}
+	}
+}
+
+func TestVelocityAccentSeq(t *testing.T) {
+	accent, err := NewAccent(20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewSimplePart().Rhythm
+	r.Accent = accent
+	r.Dynamics = NewDynamics(80)
+	// [C D E F] accent(20) dynamics(80): each slot starts its own steps from 0, but only
+	// the downbeat gets the whole accent.
+	seq := seqOf(r, newSimplePartWithPitch(1), newSimplePartWithPitch(2), newSimplePartWithPitch(3), newSimplePartWithPitch(4))
+	played := noteOns(t, seq, 64)
+	expected := map[uint64]byte{
+		0:   100, // downbeat
+		64:  90,  // beat
+		128: 95,  // half bar
+		192: 90,  // beat
+	}
+	if len(played) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, played)
+	}
+	for step, velocity := range expected {
+		if played[step] != velocity {
+			t.Errorf("expected velocity %v at step %v, got %v", velocity, step, played[step])
+		}
+	}
+}
+
+func TestVelocityHumanize(t *testing.T) {
+	r := &Rhythm{
+		Accent:   DefaultAccent(),
+		Dynamics: &Dynamics{Center: 120, Human: 20},
+		Meter:    &Meter{Beats: 4, Value: 4},
+	}
+	rnd := rand.New(rand.NewSource(1))
+	lower, higher := false, false
+	for i := 0; i < 100; i++ {
+		v := r.Velocity(rnd, 0, 4)
+		if v < 100 || v > 127 {
+			t.Fatalf("expected velocities from 100-127, got %v", v)
+		}
+		lower = lower || v < 120
+		higher = higher || v > 120
+	}
+	if !lower || !higher {
+		t.Errorf("expected velocities both sides of the center")
+	}
+
+	r.Dynamics.Center = 0
+	if v := r.Velocity(rnd, 0, 4); v != 0 {
+		t.Errorf("expected dynamics(0) to stay silent, got %v", v)
+	}
+}
+
+func TestSwingDelay(t *testing.T) {
+	r := &Rhythm{
+		Meter: &Meter{Beats: 4, Value: 4},
+	}
+	ppq := 64
+	tests := []struct {
+		percent, level int
+		step, delay    uint64
+	}{
+		{50, 8, 32, 0},  // straight
+		{66, 8, 0, 0},   // the beat stays put
+		{66, 8, 32, 10}, // the off-beat eighth lands 66% of the way through the beat
+		{66, 8, 96, 10}, // on every beat
+		{66, 8, 16, 5},  // the sixteenth before it stretches with it
+		{66, 8, 48, 5},  // and the one after
+		{75, 8, 32, 16}, // a dotted-eighth shuffle
+		{60, 16, 16, 3}, // swung sixteenths
+		{60, 16, 32, 0}, // leave the eighths alone
+		{75, 4, 64, 32}, // swung quarters
+		{66, 32, 4, 1},  // a thirty-second note is only 8 steps long
+	}
+	for _, test := range tests {
+		swing, err := NewSwing(test.percent, test.level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Swing = swing
+		if delay := r.SwingDelay(test.step, ppq); delay != test.delay {
+			t.Errorf("%v: expected step %v to be %v late, got %v", swing, test.step, test.delay, delay)
+		}
+	}
+}
+
+// noteOns plays the part through once, and gets the velocity of the note struck at each step.
+func noteOns(t *testing.T, part Part, ppq int) map[uint64]byte {
+	t.Helper()
+	buf, err := msg.NewBuffer(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	played := map[uint64]byte{}
+	for step := uint64(0); step < part.Length(ppq); step++ {
+		part.Play(buf, rand.New(rand.NewSource(1)), ppq, step)
+		next := buf.Next()
+		for i := 0; i < buf.NextLength(); i++ {
+			if next[i].IsNoteOn() {
+				played[step] = next[i].MidiMessage.Data2
+			}
+		}
+		buf.Flip()
+	}
+	return played
+}
+
+// seqOf puts parts in a seq in a bar of 4/4, with the given rhythm.
+func seqOf(r *Rhythm, parts ...Part) *Seq {
+	for _, part := range parts {
+		part.(*SimplePart).Rhythm = r
+	}
+	seq := NewSeqPart()
+	seq.SetParts(parts)
+	seq.SetParent(newSimplePartWithPitch(1))
+	seq.SetScale(1)
+	return seq
+}
+
+func TestSwingSeq(t *testing.T) {
+	swing, err := NewSwing(66, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewSimplePart().Rhythm
+	r.Swing = swing
+	parts := []Part{}
+	for i := uint64(1); i <= 8; i++ {
+		parts = append(parts, newSimplePartWithPitch(i))
+	}
+	// [C D E F G A B C] swing(66): each slot starts its own steps from 0, but the
+	// off-beat eighths still land 66% of the way through the beat.
+	played := noteOns(t, seqOf(r, parts...), 64)
+	expected := []uint64{0, 42, 64, 106, 128, 170, 192, 234}
+	if len(played) != len(expected) {
+		t.Fatalf("expected notes at %v, got %v", expected, played)
+	}
+	for _, step := range expected {
+		if _, ok := played[step]; !ok {
+			t.Fatalf("expected notes at %v, got %v", expected, played)
+		}
+	}
+}
+
+func TestSwingNeedsSteps(t *testing.T) {
+	r := &Rhythm{
+		Meter: &Meter{Beats: 4, Value: 4},
+	}
+	swing, err := NewSwing(66, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Swing = swing
+	// At 4 PPQ there's no room to swing thirty-seconds.
+	for step := uint64(0); step < 16; step++ {
+		if delay := r.SwingDelay(step, 4); delay != 0 {
+			t.Errorf("expected no swing at step %v, got %v", step, delay)
+		}
+	}
+}
@@ -4,15 +4,16 @@ import (
 	"edemond/abstract/msg"
 	"edemond/abstract/util"
 	"fmt"
+	"math/rand"
 	"strings"
 )
 
 type Seq struct {
 	parts      []Part
 	partRanges []partRange // [start, end] pairs for each part, in order.
-	printed    bool
 	length     uint64
-	scale      int // Scaling factor.
+	scale      int  // Scaling factor.
+	parent     Part // The part the seq was found in, which it takes its length from.
 }
 
 func NewSeqPart() *Seq {
@@ -25,8 +26,15 @@ func NewSeqPart() *Seq {
 	return s
 }
 
+// SetScale scales the seq down, e.g. to fit in a slot of another seq. Its own parts
+// are scaled down along with it.
 func (s *Seq) SetScale(scale int) {
 	s.scale = scale
+	for _, part := range s.parts {
+		if p, ok := part.(Scalable); ok {
+			p.SetScale(scale * len(s.parts))
+		}
+	}
 }
 
 func (s *Seq) SetParts(parts []Part) {
@@ -51,7 +59,6 @@ func (p partRange) end() int   { return p[1] }
 // This hinges on a length based on a given ppq, and thus
 // cannot be used until after semantic analysis.
 func getPartRanges(parts []Part, length int) []partRange {
-	fmt.Printf("getPartRanges: length %v\n", length)
 	// Distribute the parts evenly over the length using Bjorklund,
 	// otherwise we'll end up with all the parts stacked at the
 	// start and with a gap somewhere.
@@ -112,9 +119,7 @@ func (s *Seq) String() string {
 func (s *Seq) at(step uint64, ppq int) (Part, uint64) {
 	// TODO: Bjorklund during playback isn't ideal.
 	if s.partRanges == nil {
-		ln := int(s.Length(ppq))
-		fmt.Printf("length: %v, ppq: %v, parent: %v\n", ln, ppq, s.parent)
-		s.partRanges = getPartRanges(s.parts, ln)
+		s.partRanges = getPartRanges(s.parts, int(s.Length(ppq)))
 	}
 
 	for index, rng := range s.partRanges {
@@ -126,7 +131,13 @@ func (s *Seq) at(step uint64, ppq int) (Part, uint64) {
 }
 
 // A Seq is a Part. TODO: rename, lol
-func (s *Seq) Play(buf msg.Buffer, ppq int, step uint64) {
+func (s *Seq) Play(buf msg.Buffer, rnd *rand.Rand, ppq int, step uint64) {
+	s.playInBar(buf, rnd, ppq, step, step)
+}
+
+// playInBar plays the part in the slot for step, which still counts the bar from where the
+// seq does.
+func (s *Seq) playInBar(buf msg.Buffer, rnd *rand.Rand, ppq int, step uint64, bar uint64) {
 	length := s.Length(ppq)
 	if length == 0 {
 		return
@@ -137,21 +148,9 @@ func (s *Seq) Play(buf msg.Buffer, ppq int, step uint64) {
 	// this enables neat stuff like drag triplets: 4/4 [a b c]
 	step = step % length
 
-	// TODO: This is going to fail or at least behave weirdly for small enough ppq.
-	ppq = ppq / len(s.parts) // Scale ppq down to compress the subparts into one measure.
+	// The parts were scaled down to fit their slots (see SetScale), so they play at the same ppq.
 	part, start := s.at(step, ppq)
-
-	if !s.printed {
-		fmt.Println("----------------------------------")
-		for i, pr := range s.partRanges {
-			part := s.parts[i]
-			fmt.Printf("%v, start: %v, end: %v\n", part, pr[0], pr[1])
-		}
-		s.printed = true
-	}
-
-	fmt.Printf("%v: %v\n", step, part)
-	part.Play(buf, ppq, step-start)
+	playInBar(part, buf, rnd, ppq, step-start, bar)
 }
 
 // A seq part is exactly as long as the simple part in which it was found.
@@ -160,7 +159,7 @@ func (s *Seq) Play(buf msg.Buffer, ppq int, step uint64) {
 // not a simple part!
 func (s *Seq) Length(ppq int) uint64 {
 	if s.length == 0 {
-		s.length = s.parent.Length(ppq) / s.scale
+		s.length = s.parent.Length(ppq) / uint64(s.scale)
 	}
 	return s.length
 }
@@ -15,10 +15,9 @@ func TestBasicSeqPart(t *testing.T) {
 	s1 := newSimplePartWithPitch(2)
 	s2 := newSimplePartWithPitch(3)
 
-	seq := NewSeqPart(
-		[]Part{s1, s2},
-		parent,
-	)
+	seq := NewSeqPart()
+	seq.SetParts([]Part{s1, s2})
+	seq.SetParent(parent)
 
 	ppq := 16
 	p1, _ := seq.at(uint64(ppq*0), ppq) // First beat
@@ -1,8 +1,11 @@
 package types
 
 import (
+	"github.com/edemond/midi"
 	"edemond/abstract/msg"
 	"fmt"
+	"math/rand"
+	"time"
 )
 
 const BUFFER_SIZE = 128
@@ -22,14 +25,25 @@ type SimplePart struct {
 	Rhythm         *Rhythm
 	Instrument     *Instrument
 	Interpretation Interpretation
+	Priority       *Priority
+	Messages       []midi.Message // Sent at the start of the part, e.g. pc() and cc().
+	Ties           int            // How many slots after this one in a seq its notes are held through.
 	scale          int
 	// bookkeeping
 	id int
 	// playback data
 	// TODO: remove this from the parts. playback should be separate
 	playing []Note
+	swung   []swungNote
 	length  uint64
 	counter uint64
+	arpHits uint64 // How many notes the arp has played in this pass through the part.
+}
+
+// A note held back by swing, and the step of the part it goes out on.
+type swungNote struct {
+	step uint64
+	msg  msg.Message
 }
 
 // NewSimplePart creates a new blank SimplePart.
@@ -37,11 +51,15 @@ func NewSimplePart() *SimplePart {
 	SIMPLE_PART_ID += 1
 	return &SimplePart{
 		Rhythm: &Rhythm{
+			Accent:   NoAccent(),
 			Dynamics: NoDynamics(),
+			Gate:     NoGate(),
 			Humanize: NoHumanize(),
 			Meter:    NoMeter(),
+			Swing:    NoSwing(),
 		},
 		Harmony: &Harmony{
+			Arp:     NoArp(),
 			Chord:   NoChord(),
 			Octave:  NoOctave(),
 			Pitch:   NoPitch(),
@@ -50,6 +68,7 @@ func NewSimplePart() *SimplePart {
 		},
 		Instrument:     NoInstrument(),
 		Interpretation: NewBlock(), // TODO: Interpretation? lol pls. Voicing + seqs take care of this!
+		Priority:       NoPriority(),
 		playing:        makeNoteBuffer(BUFFER_SIZE),
 		id:             SIMPLE_PART_ID,
 		scale:          1,
@@ -61,11 +80,15 @@ func (s *SimplePart) Copy() *SimplePart {
 	SIMPLE_PART_ID += 1
 	return &SimplePart{
 		Rhythm: &Rhythm{
+			Accent:   s.Rhythm.Accent,
 			Dynamics: s.Rhythm.Dynamics,
+			Gate:     s.Rhythm.Gate,
 			Humanize: s.Rhythm.Humanize,
 			Meter:    s.Rhythm.Meter,
+			Swing:    s.Rhythm.Swing,
 		},
 		Harmony: &Harmony{
+			Arp:     s.Harmony.Arp,
 			Chord:   s.Harmony.Chord,
 			Octave:  s.Harmony.Octave,
 			Pitch:   s.Harmony.Pitch,
@@ -74,13 +97,22 @@ func (s *SimplePart) Copy() *SimplePart {
 		},
 		Instrument:     s.Instrument,
 		Interpretation: s.Interpretation, // TODO: Interpretation? lol pls. Voicing + seqs take care of this!
+		Priority:       s.Priority,
+		Messages:       s.Messages,
+		Ties:           s.Ties,
 		playing:        makeNoteBuffer(BUFFER_SIZE),
 		id:             SIMPLE_PART_ID,
 		scale:          s.scale,
 	}
 }
 
-func (s *SimplePart) Play(buf msg.Buffer, ppq int, step uint64) {
+func (s *SimplePart) Play(buf msg.Buffer, rnd *rand.Rand, ppq int, step uint64) {
+	s.playInBar(buf, rnd, ppq, step, step)
+}
+
+// playInBar plays the part at its own step, accenting and swinging it by where that falls
+// in the bar.
+func (s *SimplePart) playInBar(buf msg.Buffer, rnd *rand.Rand, ppq int, step uint64, bar uint64) {
 
 	// TODO: This needs to be an exemplar of the open/closed principle. It's the
 	// Harmonic and Rhythmic contexts together (and later Timbral!) that determine
@@ -95,33 +127,109 @@ func (s *SimplePart) Play(buf msg.Buffer, ppq int, step uint64) {
 
 	length := s.Length(ppq)
 
-	// Have the Interpretation update the note buffer.
-	s.Interpretation.Play(s.playing, s.Harmony, s.Rhythm, s.counter, step, length, ppq)
+	// Messages go out before any notes, so e.g. a program change applies to the first note.
+	if step == 0 {
+		sendMessages(buf, s.Messages, s.Instrument)
+	}
+
+	// Send the notes that swing held back until now.
+	swung := s.swung[:0]
+	for i := range s.swung {
+		if s.swung[i].step <= step {
+			m := s.swung[i].msg
+			buf.Add(&m)
+		} else {
+			swung = append(swung, s.swung[i])
+		}
+	}
+	s.swung = swung
+
+	// Have the Interpretation update the note buffer. An arp plays a note at each of its hits,
+	// or keeps its own time if there's no Interpretation.
+	if arp := s.ownArp(); arp != nil {
+		arp.Play(s.playing, s.Harmony, s.Rhythm, rnd, step, length, ppq)
+	} else {
+		s.Interpretation.Play(s.playing, s.Harmony, s.Rhythm, rnd, s.counter, step, length, ppq)
+		if s.Harmony.Arp.HasValue() {
+			if step == 0 {
+				s.arpHits = 0
+			}
+			if s.Harmony.Arp.Pick(s.playing, s.Harmony, rnd, s.arpHits) {
+				s.arpHits++
+			}
+		}
+	}
 
 	// Write all of the buffered notes out to the main message buffer.
 	for i := 0; i < len(s.playing); i++ {
 		note := s.playing[i]
 		if note.HasValue() {
-			human := 0
+			var human time.Duration
 			if s.Rhythm.Humanize.HasValue() {
-				human = s.Rhythm.Humanize.TimeOffset()
+				human = s.Rhythm.Humanize.TimeOffset(rnd)
 			}
 
 			var m msg.Message
 			m.MidiMessage.Command = 0x9 // note on
 			m.MidiMessage.Channel = s.Instrument.Channel
 			m.MidiMessage.Data1 = byte(note)
-			m.MidiMessage.Data2 = byte(s.Rhythm.Dynamics.Center) // TODO: humanize
+			m.MidiMessage.Data2 = byte(s.Rhythm.Velocity(rnd, bar, ppq))
 			m.Instrument = s.Instrument.ID
+			m.Voices = &s.Instrument.Voices
+			if s.Priority.HasValue() {
+				m.Priority = s.Priority.Level
+			}
 			m.HumanizeTime = human
-			buf.Add(&m)
+			m.Length = s.noteLength(step, length, ppq)
 			s.playing[i] = NoNote()
+
+			// Swing plays it late, but still within the part, and ending when it would have.
+			delay := s.Rhythm.SwingDelay(bar, ppq)
+			if step+delay >= length {
+				delay = length - 1 - step
+			}
+			if delay == 0 {
+				buf.Add(&m)
+				continue
+			}
+			if m.Length > delay {
+				m.Length -= delay
+			} else {
+				m.Length = 1
+			}
+			s.swung = append(s.swung, swungNote{step + delay, m})
 		}
 	}
 
 	s.counter++
 }
 
+// noteLength is how long a note struck at the given step sounds: to the end of its pulse
+// (or as long as the interpretation says), held through any tied slots after this part if it
+// lasts to the end, then gated.
+func (s *SimplePart) noteLength(step uint64, length uint64, ppq int) uint64 {
+	var n uint64
+	if arp := s.ownArp(); arp != nil {
+		n = arp.NoteLength(s.Rhythm, step, length, ppq)
+	} else if nl, ok := s.Interpretation.(NoteLengther); ok {
+		n = nl.NoteLength(s.counter, step, length, ppq)
+	} else {
+		n = s.Rhythm.NoteLength(step, length, ppq)
+	}
+	if step+n >= length {
+		n += uint64(s.Ties) * length
+	}
+	return s.Rhythm.Gate.Apply(n)
+}
+
+// ownArp gets the part's arp if it keeps its own time, with no Interpretation to play it.
+func (s *SimplePart) ownArp() *Arp {
+	if s.Harmony.Arp.HasValue() && !s.Interpretation.HasValue() {
+		return s.Harmony.Arp
+	}
+	return nil
+}
+
 func (s *SimplePart) SetScale(scale int) {
 	s.scale = scale
 }
@@ -140,7 +248,7 @@ func (s *SimplePart) Length(ppq int) uint64 {
 		// TODO: okay, simple part needs to know about its parent now.
 		// the length should be the parent length / s.scale, and only if there's
 		// no parent, that's where we take meter into account.
-		s.length = s.Rhythm.Meter.Length(ppq) / s.scale
+		s.length = s.Rhythm.Meter.Length(ppq) / uint64(s.scale)
 	}
 	return s.length
 }
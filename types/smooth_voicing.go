@@ -0,0 +1,164 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+)
+
+// How much a pair of voices moving in parallel fifths counts against a voicing, in half steps
+// of movement. It's enough to take a voicing that moves further instead, but not a lot further.
+const parallelFifthCost = 12
+
+// SmoothVoicing picks the notes of each chord as it's played, keeping them in a range and
+// moving as little as it can from the chord before, without parallel fifths. It remembers the
+// last chord it voiced, so the parts sharing one (e.g. through a default, a let, or a seq) are
+// voiced as one line of chords. Parts that play at the same time want one each.
+type SmoothVoicing struct {
+	Low, High Note
+	last      []Note // The notes of the last chord voiced, lowest first.
+}
+
+// NewSmoothVoicing creates a smooth voicing in the given range, which has to span at least
+// an octave so every pitch has somewhere to go.
+func NewSmoothVoicing(low Note, high Note) (*SmoothVoicing, error) {
+	if int(high)-int(low) < 11 {
+		return nil, fmt.Errorf("a smooth voicing's range has to span at least an octave (got %v to %v)", low, high)
+	}
+	return &SmoothVoicing{Low: low, High: high}, nil
+}
+
+func (v *SmoothVoicing) String() string {
+	return fmt.Sprintf("smooth voicing(%v to %v)", v.Low, v.High)
+}
+
+func (v *SmoothVoicing) HasValue() bool {
+	return v != nil
+}
+
+// Voice picks the notes for the given pitches, one each, in the same order. The range says
+// where they go, not the octave.
+func (v *SmoothVoicing) Voice(pitches []Pitch, _ Octave) []Note {
+	return v.voice(pitches, false)
+}
+
+// VoiceOverBass is Voice, but with the first pitch kept as the lowest note.
+func (v *SmoothVoicing) VoiceOverBass(pitches []Pitch) []Note {
+	return v.voice(pitches, true)
+}
+
+func (v *SmoothVoicing) voice(pitches []Pitch, bass bool) []Note {
+	// Each pitch once, in the octaves that are in range.
+	unique := make([]Pitch, 0, len(pitches))
+	seen := map[Pitch]bool{}
+	for _, p := range pitches {
+		if !seen[p] {
+			seen[p] = true
+			unique = append(unique, p)
+		}
+	}
+	if len(unique) == 0 {
+		return []Note{}
+	}
+
+	var best []Note
+	bestCost := -1
+	notes := make([]Note, len(unique))
+	var try func(i int)
+	try = func(i int) {
+		if i == len(unique) {
+			if bass && !lowestIsFirst(notes) {
+				return
+			}
+			if cost := v.cost(notes); bestCost < 0 || cost < bestCost {
+				bestCost = cost
+				best = append(best[:0], notes...)
+			}
+			return
+		}
+		first := int(v.Low) + ((int(unique[i])-int(v.Low))%12+12)%12
+		for n := first; n <= int(v.High); n += 12 {
+			notes[i] = Note(n)
+			try(i + 1)
+		}
+	}
+	try(0)
+	if best == nil {
+		// There's no room in the range for the bass under everything else.
+		return v.voice(pitches, false)
+	}
+
+	v.last = append(v.last[:0], best...)
+	sort.Slice(v.last, func(i, j int) bool { return v.last[i] < v.last[j] })
+	return best
+}
+
+func lowestIsFirst(notes []Note) bool {
+	for _, n := range notes[1:] {
+		if n < notes[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// cost is how far the voices move from the last chord, plus the cost of any parallel fifths.
+// The first chord has nothing to move from, so it's kept close to the middle of the range.
+func (v *SmoothVoicing) cost(notes []Note) int {
+	if len(v.last) == 0 {
+		middle := (int(v.Low) + int(v.High)) / 2
+		cost := 0
+		for _, n := range notes {
+			cost += abs(int(n) - middle)
+		}
+		return cost
+	}
+
+	next := append([]Note{}, notes...)
+	sort.Slice(next, func(i, j int) bool { return next[i] < next[j] })
+
+	// Each voice of the new chord comes from a voice of the last one: the one in the same place
+	// if they have as many notes, or the nearest one if they don't.
+	from := make([]Note, len(next))
+	for i, n := range next {
+		if len(next) == len(v.last) {
+			from[i] = v.last[i]
+			continue
+		}
+		from[i] = v.last[0]
+		for _, prev := range v.last {
+			if abs(int(n)-int(prev)) < abs(int(n)-int(from[i])) {
+				from[i] = prev
+			}
+		}
+	}
+
+	cost := 0
+	for i, n := range next {
+		cost += abs(int(n) - int(from[i]))
+	}
+	for i := range next {
+		for j := i + 1; j < len(next); j++ {
+			if isParallelFifth(from[i], from[j], next[i], next[j]) {
+				cost += parallelFifthCost
+			}
+		}
+	}
+	return cost
+}
+
+// isParallelFifth checks if two voices moving from a1 and a2 to b1 and b2 are a fifth (or a
+// fifth and octaves) apart before and after, moving the same way.
+func isParallelFifth(a1, a2, b1, b2 Note) bool {
+	before, after := int(a2)-int(a1), int(b2)-int(b1)
+	if before <= 0 || after <= 0 || before%12 != 7 || after%12 != 7 {
+		return false
+	}
+	return a1 != b1 && (b1 > a1) == (b2 > a2)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
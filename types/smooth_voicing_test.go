@@ -0,0 +1,92 @@
+package types
+
+import (
+	"testing"
+)
+
+func smooth(t *testing.T, low, high Note) *SmoothVoicing {
+	v, err := NewSmoothVoicing(low, high)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func pitchSet(ps ...int) []Pitch {
+	out := make([]Pitch, len(ps))
+	for i, p := range ps {
+		out[i] = Pitch(p)
+	}
+	return out
+}
+
+func TestSmoothVoicingStaysInRange(t *testing.T) {
+	v := smooth(t, 36, 60)
+	for _, chord := range [][]Pitch{pitchSet(0, 4, 7), pitchSet(5, 9, 0), pitchSet(7, 11, 2, 5), pitchSet(0, 4, 7, 11, 2)} {
+		for _, n := range v.Voice(chord, 4) {
+			if n < 36 || n > 60 {
+				t.Fatalf("expected %v to be voiced from 36 to 60, got %v", chord, n)
+			}
+		}
+	}
+}
+
+func TestSmoothVoicingMovesLittle(t *testing.T) {
+	v := smooth(t, 36, 60)
+	// C E G, close around the middle of the range: G C E.
+	expectNotes(t, v.Voice(pitchSet(0, 4, 7), 4), 48, 52, 43)
+	// F A C: the C stays put, G goes up to A, and E to F.
+	expectNotes(t, v.Voice(pitchSet(5, 9, 0), 4), 53, 45, 48)
+	// G B D F from A C F: every note is a step at most from one before.
+	next := v.Voice(pitchSet(7, 11, 2, 5), 4)
+	for _, n := range next {
+		nearest := 127
+		for _, prev := range []Note{45, 48, 53} {
+			if d := abs(int(n) - int(prev)); d < nearest {
+				nearest = d
+			}
+		}
+		if nearest > 2 {
+			t.Fatalf("expected G7 to move a step at most from A C F, got %v", next)
+		}
+	}
+}
+
+func TestSmoothVoicingAvoidsParallelFifths(t *testing.T) {
+	v := smooth(t, 36, 60)
+	v.last = []Note{48, 55} // C and G, a fifth apart.
+	// D and A could both go up a step, but that's parallel fifths.
+	next := v.Voice(pitchSet(2, 9), 4)
+	if isParallelFifth(48, 55, minNote(next), maxNote(next)) {
+		t.Fatalf("expected no parallel fifths from C G to D A, got %v", next)
+	}
+}
+
+func TestSmoothVoicingKeepsTheBass(t *testing.T) {
+	v := smooth(t, 36, 60)
+	v.Voice(pitchSet(0, 4, 7), 4)
+	notes := v.VoiceOverBass(pitchSet(4, 7, 0)) // C/E
+	if notes[0] != minNote(notes) {
+		t.Fatalf("expected E on the bottom, got %v", notes)
+	}
+}
+
+func minNote(notes []Note) Note {
+	min := notes[0]
+	for _, n := range notes {
+		if n < min {
+			min = n
+		}
+	}
+	return min
+}
+
+func maxNote(notes []Note) Note {
+	max := notes[0]
+	for _, n := range notes {
+		if n > max {
+			max = n
+		}
+	}
+	return max
+}
@@ -0,0 +1,65 @@
+package types
+
+import (
+	"fmt"
+)
+
+// Swing plays the off-beats of a subdivision late, e.g. swing(66) for a triplet feel on
+// eighth notes, or swing(58, 16) for a lazy sixteenth-note groove. The percent is how far
+// through each pair of notes the second one lands, as on a drum machine: 50 is straight,
+// 66 is a triplet feel, and 75 is a dotted-eighth shuffle.
+type Swing struct {
+	Percent int
+	Level   int // Pulse strength of the notes that swing (see Rhythm.Pulse), e.g. 8 for eighths.
+}
+
+func (s *Swing) String() string {
+	return fmt.Sprintf("swing(%v, %v)", s.Percent, s.Level)
+}
+
+func (s *Swing) HasValue() bool {
+	return s != nil
+}
+
+func NewSwing(percent int, level int) (*Swing, error) {
+	if percent < 50 || percent > 75 {
+		return nil, fmt.Errorf("swing must be from 50-75 percent (got %v)", percent)
+	}
+	switch level {
+	case 4, 8, 16, 32:
+	default:
+		return nil, fmt.Errorf("swing can be on quarters (4), eighths (8), sixteenths (16) or thirty-seconds (32), not %v", level)
+	}
+	return &Swing{Percent: percent, Level: level}, nil
+}
+
+func NoSwing() *Swing {
+	return nil
+}
+
+// Straight eighths unless asked otherwise.
+func DefaultSwing() *Swing {
+	return &Swing{Percent: 50, Level: 8}
+}
+
+// Delay gets how many steps late to play a note struck at the given step, with the given
+// number of steps to a beat. Each pair of notes at the swing's level is stretched so the
+// second lands Percent of the way through the pair, and the steps in between stretch with it.
+func (s *Swing) Delay(step uint64, stepsPerBeat uint64) uint64 {
+	div := uint64(s.Level / 4) // Notes to the beat at the swing's level.
+	if s.Percent == 50 || stepsPerBeat%div != 0 || stepsPerBeat/div < 2 {
+		return 0 // Straight, or too few steps to swing at this level.
+	}
+	sub := stepsPerBeat / div
+	pair := 2 * sub
+	pos := pair * uint64(s.Percent) / 100 // Where the second note of the pair lands.
+
+	x := step % pair
+	var swung uint64
+	if x < sub {
+		swung = x * pos / sub
+	} else {
+		swung = pos + (x-sub)*(pair-pos)/sub
+	}
+	return swung - x
+}
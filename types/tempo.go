@@ -0,0 +1,149 @@
+package types
+
+import (
+	"github.com/edemond/abstract/msg"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// TempoPart changes the tempo where it appears. Like a message part, it takes up no time.
+// "bpm 90" in a block jumps straight to the new tempo; accel(from, to) and rit(from, to)
+// ramp from one tempo to the other over the part after them, or over the whole of the
+// compound part they're in. Either way, the new tempo lasts until the end of the block
+// it's in, and then the tempo goes back to what it was before the block.
+type TempoPart struct {
+	Name     string // bpm, accel or rit.
+	From, To int
+}
+
+// NewTempoPart makes a ramp, e.g. accel(100, 140) or rit(120, 80).
+func NewTempoPart(name string, from int, to int) (*TempoPart, error) {
+	if from <= 0 || to <= 0 {
+		return nil, fmt.Errorf("%v tempos must be above 0 bpm (got %v, %v)", name, from, to)
+	}
+	if name == "accel" && to <= from {
+		return nil, fmt.Errorf("accel has to speed up (got %v to %v); use rit to slow down", from, to)
+	}
+	if name == "rit" && to >= from {
+		return nil, fmt.Errorf("rit has to slow down (got %v to %v); use accel to speed up", from, to)
+	}
+	return &TempoPart{Name: name, From: from, To: to}, nil
+}
+
+// NewBPMPart makes a jump to a new tempo, as from a bpm statement.
+func NewBPMPart(bpm int) *TempoPart {
+	return &TempoPart{Name: "bpm", From: bpm, To: bpm}
+}
+
+// The tempo is followed by the drivers, through a TempoMap; there's nothing to play.
+func (p *TempoPart) Play(buf msg.Buffer, rnd *rand.Rand, ppq int, step uint64) {}
+
+func (p *TempoPart) Length(ppq int) uint64 {
+	return 0
+}
+
+func (p *TempoPart) String() string {
+	if p.Name == "bpm" {
+		return fmt.Sprintf("bpm %v", p.To)
+	}
+	return fmt.Sprintf("%v(%v, %v)", p.Name, p.From, p.To)
+}
+
+func (p *TempoPart) HasValue() bool {
+	return p != nil
+}
+
+// TempoMap says how fast each step of a piece goes, and when it falls, following the tempo
+// parts in it. It's worked out once, before the piece is played. Tempo changes inside a seq,
+// or in the repeats of a short part looping in a compound part, aren't followed.
+type TempoMap struct {
+	ppq   int
+	base  float64         // The tempo where nothing else is said, e.g. a piece with no length.
+	bpm   []float64       // The tempo at each step.
+	times []time.Duration // When each step falls, from the start; one more than there are steps.
+}
+
+// NewTempoMap works out the tempo of the piece from the given root part, starting at the given bpm.
+func NewTempoMap(part Part, bpm int, ppq int) *TempoMap {
+	length := part.Length(ppq)
+	t := &TempoMap{
+		ppq:  ppq,
+		base: float64(bpm),
+		bpm:  make([]float64, length),
+	}
+	t.fill(0, length, &TempoPart{From: bpm, To: bpm}, 0)
+	t.walk(part, 0)
+
+	t.times = make([]time.Duration, length+1)
+	elapsed := 0.0 // Kept as a float so that rounding doesn't add up over a long piece.
+	for i, bpm := range t.bpm {
+		elapsed += float64(time.Minute) / (bpm * float64(ppq))
+		t.times[i+1] = time.Duration(math.Round(elapsed))
+	}
+	return t
+}
+
+// walk fills in the tempo changes in a part, which starts at the given step.
+func (t *TempoMap) walk(part Part, start uint64) {
+	switch p := part.(type) {
+	case *BlockPart:
+		end := start + p.Length(t.ppq)
+		at := start
+		for i, child := range p.parts {
+			if tempo, ok := child.(*TempoPart); ok {
+				// A ramp goes over the next part that takes up any time.
+				var span uint64
+				for _, next := range p.parts[i+1:] {
+					if span = next.Length(t.ppq); span > 0 {
+						break
+					}
+				}
+				t.fill(at, end, tempo, span)
+				continue
+			}
+			t.walk(child, at)
+			at += child.Length(t.ppq)
+		}
+	case *CompoundPart:
+		end := start + p.Length(t.ppq)
+		for _, child := range p.parts {
+			if tempo, ok := child.(*TempoPart); ok {
+				t.fill(start, end, tempo, end-start)
+				continue
+			}
+			t.walk(child, start)
+		}
+	}
+}
+
+// fill sets the tempo from start to end, ramping over the first span steps.
+func (t *TempoMap) fill(start uint64, end uint64, tempo *TempoPart, span uint64) {
+	from, to := float64(tempo.From), float64(tempo.To)
+	for step := start; step < end; step++ {
+		if i := step - start; i < span {
+			t.bpm[step] = from + (to-from)*float64(i)/float64(span)
+		} else {
+			t.bpm[step] = to
+		}
+	}
+}
+
+// BPM gets the tempo at the given step. Past the end, the piece loops.
+func (t *TempoMap) BPM(step uint64) float64 {
+	if len(t.bpm) == 0 {
+		return t.base
+	}
+	return t.bpm[step%uint64(len(t.bpm))]
+}
+
+// Time gets when the given step falls, from the start of the piece. Past the end, the piece
+// loops, and the step falls that many times through.
+func (t *TempoMap) Time(step uint64) time.Duration {
+	length := uint64(len(t.bpm))
+	if length == 0 {
+		return time.Duration(float64(step) * float64(time.Minute) / (t.base * float64(t.ppq)))
+	}
+	return time.Duration(step/length)*t.times[length] + t.times[step%length]
+}
@@ -0,0 +1,90 @@
+package types
+
+import (
+	"github.com/edemond/abstract/msg"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// beats is a part that plays nothing for the given number of beats.
+type beats int
+
+func (b beats) Play(buf msg.Buffer, rnd *rand.Rand, ppq int, step uint64) {}
+func (b beats) Length(ppq int) uint64                                     { return uint64(int(b) * ppq) }
+func (b beats) HasValue() bool                                            { return true }
+func (b beats) String() string                                            { return "beats" }
+
+func block(parts ...Part) *BlockPart {
+	b := NewBlockPart()
+	for _, p := range parts {
+		b.Add(p)
+	}
+	return b
+}
+
+func expectBPMs(t *testing.T, tempo *TempoMap, expected []float64) {
+	t.Helper()
+	for step, bpm := range expected {
+		if got := tempo.BPM(uint64(step)); got != bpm {
+			t.Errorf("expected step %v to be at %v bpm, got %v", step, bpm, got)
+		}
+	}
+}
+
+func TestTempoMapRamp(t *testing.T) {
+	accel, err := NewTempoPart("accel", 60, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The ramp goes over the part after it, and the tempo stays there to the end of the block.
+	tempo := NewTempoMap(block(beats(1), accel, beats(4), beats(1)), 120, 1)
+	expectBPMs(t, tempo, []float64{120, 60, 70, 80, 90, 100})
+}
+
+func TestTempoMapGoesBackAfterBlock(t *testing.T) {
+	bridge := block(NewBPMPart(60), beats(2))
+	tempo := NewTempoMap(block(beats(1), bridge, beats(1)), 120, 1)
+	expectBPMs(t, tempo, []float64{120, 60, 60, 120})
+}
+
+func TestTempoMapCompoundRamp(t *testing.T) {
+	rit, err := NewTempoPart("rit", 120, 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compound := NewCompoundPart()
+	compound.Add(rit)
+	compound.Add(beats(4))
+	compound.Add(beats(1))
+	tempo := NewTempoMap(compound, 100, 1)
+	expectBPMs(t, tempo, []float64{120, 110, 100, 90})
+}
+
+func TestTempoMapTime(t *testing.T) {
+	tempo := NewTempoMap(block(beats(2), NewBPMPart(120), beats(2)), 60, 1)
+	cases := map[uint64]time.Duration{
+		0: 0,
+		2: 2 * time.Second,
+		3: 2500 * time.Millisecond,
+		4: 3 * time.Second,
+		6: 5 * time.Second, // Looped back around.
+	}
+	for step, expected := range cases {
+		if got := tempo.Time(step); got != expected {
+			t.Errorf("expected step %v to fall at %v, got %v", step, expected, got)
+		}
+	}
+}
+
+func TestNewTempoPart(t *testing.T) {
+	if _, err := NewTempoPart("accel", 120, 100); err == nil {
+		t.Error("expected an accel that slows down to be an error")
+	}
+	if _, err := NewTempoPart("rit", 100, 120); err == nil {
+		t.Error("expected a rit that speeds up to be an error")
+	}
+	if _, err := NewTempoPart("rit", 100, 0); err == nil {
+		t.Error("expected a tempo of 0 to be an error")
+	}
+}
@@ -0,0 +1,21 @@
+package types
+
+// A Tie holds the notes from the slot before it in a sequence through its own slot,
+// e.g. [C - - E] holds the C for three slots.
+type Tie int
+
+func (t Tie) HasValue() bool {
+	return true
+}
+
+func (t Tie) String() string {
+	return "-"
+}
+
+func IsTie(value string) bool {
+	return value == "-"
+}
+
+func NewTie() Tie {
+	return Tie(0)
+}
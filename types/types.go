@@ -1,8 +1,10 @@
 package types
 
 import (
+	"github.com/edemond/midi"
 	"edemond/abstract/msg"
 	"fmt"
+	"math/rand"
 )
 
 // A Part is a compiled ast.Expression. We have four types right now:
@@ -10,9 +12,11 @@ import (
 // - Compound part: Two or more parts played simultaneously.
 // - Block part: Two or more parts played sequentially, each part filling out one meter.
 // - Seq part: Two or more parts played sequentially, with all parts condensed into one meter.
+// All randomness during playback comes from the rnd passed to Play, so that a piece
+// played with the same seed plays the same way every time.
 type Part interface {
 	Value
-	Play(buf msg.Buffer, ppq int, step uint64)
+	Play(buf msg.Buffer, rnd *rand.Rand, ppq int, step uint64)
 	Length(ppq int) uint64
 }
 
@@ -23,7 +27,33 @@ type Part interface {
 type Interpretation interface {
 	Value
 	// TODO: This method signature is waxing large
-	Play(notes []Note, h *Harmony, r *Rhythm, counter uint64, step uint64, length uint64, ppq int)
+	Play(notes []Note, h *Harmony, r *Rhythm, rnd *rand.Rand, counter uint64, step uint64, length uint64, ppq int)
+}
+
+// An Interpretation that knows how long the notes it plays should sound, rather than
+// leaving that to the pulse of the meter.
+type NoteLengther interface {
+	NoteLength(counter uint64, step uint64, length uint64, ppq int) uint64
+}
+
+// A part that can be scaled down to fit in a slot of a seq.
+type Scalable interface {
+	SetScale(scale int)
+}
+
+// A part that can be told where in the bar it's playing, for what goes by the beat
+// (swing, accents.) A part in a slot of a seq counts its own steps from 0.
+type barPlayer interface {
+	playInBar(buf msg.Buffer, rnd *rand.Rand, ppq int, step uint64, bar uint64)
+}
+
+// playInBar plays the part at the given step, at the given step of the bar if it cares.
+func playInBar(part Part, buf msg.Buffer, rnd *rand.Rand, ppq int, step uint64, bar uint64) {
+	if p, ok := part.(barPlayer); ok {
+		p.playInBar(buf, rnd, ppq, step, bar)
+		return
+	}
+	part.Play(buf, rnd, ppq, step)
 }
 
 // Any type of value in the language. Use Go type assertions to figure out what (sorry.)
@@ -32,6 +62,12 @@ type Value interface {
 	String() string
 }
 
+// A value that sends a MIDI message at the start of the part it appears in, like pc() or cc().
+type MIDIMessage interface {
+	Value
+	Message() midi.Message
+}
+
 // MIDI program change message.
 type ProgramChange struct {
 	Channel uint8
@@ -46,6 +82,14 @@ func (pc *ProgramChange) String() string {
 	return fmt.Sprintf("pc(%v, %v)", pc.Channel, pc.Program)
 }
 
+func (pc *ProgramChange) Message() midi.Message {
+	return midi.Message{
+		Command: 0xC,
+		Channel: pc.Channel,
+		Data1:   pc.Program,
+	}
+}
+
 // MIDI controller change message.
 type ControllerChange struct {
 	Channel    uint8
@@ -60,3 +104,12 @@ func (cc *ControllerChange) HasValue() bool {
 func (cc *ControllerChange) String() string {
 	return fmt.Sprintf("cc(%v, %v, %v)", cc.Channel, cc.Controller, cc.Value)
 }
+
+func (cc *ControllerChange) Message() midi.Message {
+	return midi.Message{
+		Command: 0xB,
+		Channel: cc.Channel,
+		Data1:   cc.Controller,
+		Data2:   cc.Value,
+	}
+}
@@ -6,7 +6,9 @@ import (
 
 // how do we want to represent this?
 // should we have a type that's relative to an octave??
-// 		well that's a hint right there...maybe it consists of an octave and a map of notes to a list of offsets
+//
+//	well that's a hint right there...maybe it consists of an octave and a map of notes to a list of offsets
+//
 // wait is it even possible to talk about a voicing without a chord? or at least a number of notes??
 // cause um. you need to know how many notes there are in order to map them to the octaves they're active in
 // AND...should it be sensitive to which notes are just in there for color? like cminadd2, what if you get like eight Ds?
@@ -15,8 +17,7 @@ import (
 type Voicing interface {
 	Value
 	//Voice(Pitch, *Chord) []Note
-	Voice([]Pitch) []Note
-	IsSounding(octave uint, note int) bool
+	Voice(pitches []Pitch, octave Octave) []Note // Octave-relative voicings start from the octave; others ignore it.
 }
 
 // Each byte is an octave; each bit is if that note in that octave.
@@ -54,8 +55,8 @@ func (v BitmapVoicing) IsSounding(octave uint, note int) bool {
 }
 
 // Voice applies a Voicing to a set of pitches to produce a concrete set of notes.
-//func (v BitmapVoicing) Voice(key Pitch, chord *Chord) []Note {
-func (v BitmapVoicing) Voice(pitches []Pitch) []Note {
+// func (v BitmapVoicing) Voice(key Pitch, chord *Chord) []Note {
+func (v BitmapVoicing) Voice(pitches []Pitch, _ Octave) []Note {
 	notes := make([]Note, 0) // TODO: Agh. Allocation during playback. Fix soon.
 	//pitches := chord.In(key)
 
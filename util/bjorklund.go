@@ -25,10 +25,14 @@ func findFirstPulse(steps []int) int {
 	return -1
 }
 
-// this should be something like bjork(2, 5)
+// Bjorklund spreads the pulses as evenly as possible over the steps, e.g. Bjorklund(3, 8)
+// gives [1 0 0 1 0 0 1 0]. Pulses are clamped to [0, steps].
 func Bjorklund(pulses, steps int) []int {
-	if pulses > int(steps/2) {
-		steps, pulses = pulses, steps
+	if pulses > steps {
+		pulses = steps
+	}
+	if pulses <= 0 {
+		return make([]int, steps) // All rests.
 	}
 
 	// TODO: We should add some kind of optimization for
@@ -22,4 +22,15 @@ func TestRotate(t *testing.T) {
 
 func TestBjorklund(t *testing.T) {
 	expect(t, Bjorklund(3, 8), []int{1, 0, 0, 1, 0, 0, 1, 0})
+	expect(t, Bjorklund(2, 5), []int{1, 0, 1, 0, 0})
+}
+
+func TestBjorklundMorePulsesThanRests(t *testing.T) {
+	expect(t, Bjorklund(5, 8), []int{1, 0, 1, 1, 0, 1, 1, 0})
+	expect(t, Bjorklund(3, 4), []int{1, 1, 1, 0})
+	expect(t, Bjorklund(4, 4), []int{1, 1, 1, 1})
+}
+
+func TestBjorklundNoPulses(t *testing.T) {
+	expect(t, Bjorklund(0, 4), []int{0, 0, 0, 0})
 }
@@ -8,15 +8,21 @@ if exists("b:current_syntax")
 endif
 
 " Keywords
-syn keyword abstractKeyword let default
+syn keyword abstractKeyword let default import
 syn keyword abstractKeyword poly match cutoff
+syn keyword abstractKeyword oldest quietest lowest none
+syn keyword abstractKeyword smooth
+syn keyword abstractKeyword up down updown random played
 syn keyword abstractKeyword bpm ppq
-syn keyword abstractKeyword chord dynamics instrument meter note pitch prob scale voicing 
+syn keyword abstractKeyword accel accent arp bjork cc chord dynamics gate instrument mel meter note pc pitch priority prob range rhythm rit scale swing voicing 
 
 " Scales
 syn keyword abstractBuiltIn major minor 
 syn keyword abstractBuiltIn ionian dorian phrygian lydian mixolydian aeolian locrian
 
+" Voicings
+syn keyword abstractBuiltIn close open drop2 drop3 shell spread rootless
+
 " Pitches
 syn keyword abstractBuiltIn C C\# C\#\# Cb Cbb 
 syn keyword abstractBuiltIn D D\# D\#\# Db Dbb 
@@ -26,6 +32,7 @@ syn keyword abstractBuiltIn G G\# C\#\# Gb Gbb
 syn keyword abstractBuiltIn A A\# C\#\# Ab Abb 
 syn keyword abstractBuiltIn B B\# C\#\# Bb Bbb 
 syn keyword abstractBuiltIn ppp pp p mp mf f ff fff
+syn keyword abstractBuiltIn staccato tenuto legato
 
 " Chords
 syn keyword abstractBuiltIn I i II ii III iii IV iv V v VI vi VII vii